@@ -5,20 +5,28 @@
 package handler
 
 import (
+	"bytes"
+	"context"
 	"embed"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
 	"log"
 	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/GhanshyamJha05/WEB_SCRAPPER_Using-GO/pkg/scraper"
+	"github.com/GhanshyamJha05/WEB_SCRAPPER_Using-GO/pkg/web"
 )
 
-//go:embed templates/index.html
+//go:embed templates/index.html templates/error.html
 var templateFS embed.FS
 
 // --- types ---
@@ -31,22 +39,49 @@ type scrapingSite struct {
 }
 
 type pageData struct {
-	URL         string
-	Selector    string
-	Results     []scraper.ScrapeResult
-	Duration    time.Duration
-	Error       string
-	Recommended []scrapingSite
-	Visited     []string
+	URL             string
+	Selector        string
+	Results         []scraper.ScrapeResult
+	Tables          []scraper.Table   // set instead of Results in tableMode
+	Meta            *scraper.MetaTags // set instead of Results for mode=meta
+	ScriptValues    []string          // set instead of Results for mode=scriptjson, api, microdata and forms, each value, item or form as indented JSON
+	CountOnly       bool              // countOnly=true: only MatchCount is shown
+	Duration        time.Duration
+	Error           string
+	Attr            string               // first requested attribute, shown under each result
+	Scraped         bool                 // a scrape actually ran (vs. form not yet submitted / rejected input)
+	MatchCount      int                  // len(Results) once Scraped, before the render limit
+	Hidden          int                  // results left out of Results by the render limit
+	Cached          bool                 // every page was unchanged (304) and results came from the cache
+	Reused          bool                 // a repeat of a scrape within SCRAPER_DEBOUNCE, answered with its result
+	Skipped         []string             // URLs that timed out and were left out
+	StoppedReason   string               // why a multi-page scrape stopped early, see scraper.Options.MaxPages
+	ParseWarnings   []string             // "url: warning" for pages that parsed to little or nothing, see scraper.PageInfo.Warning
+	FixedSelector   string               // with autoFix, the variant of Selector that matched on the first page it was needed for
+	Timings         []scraper.PageTiming // per-page fetch phases, for the stats block
+	PageTitle       string               // <title> of the first page scraped, for the stats block
+	PageDescription string               // its meta description
+	CanonicalURL    string               // its <link rel="canonical">, when it declares one
+	BytesDownloaded int64                // response body bytes read for this scrape
+	Recommended     []scrapingSite
+	Visited         []visitedEntry // most recent first
 }
 
 // --- state (shared across warm lambda invocations) ---
 
 var (
 	tmpl             *template.Template
+	errTmpl          = template.Must(template.ParseFS(templateFS, "templates/error.html"))
 	cli              *scraper.Client
 	mu               sync.Mutex
-	visited          []string
+	visited          []visitedEntry // oldest first
+	historySize      = web.HistorySizeFromEnv()
+	historyCanon     = web.HistoryCanonicalFromEnv() // list visited pages under their canonical URL
+	renderLimit      = web.RenderLimitFromEnv()      // results rendered into the page; 0 renders all
+	renderWarn       = web.RenderWarnFromEnv()       // template renders slower than this are logged
+	apiSecret        = apiSecretFromEnv()            // nil leaves the API open
+	debounce         = newDebouncer()                // nil unless SCRAPER_DEBOUNCE is set
+	defaultFormat    = web.DefaultFormatFromEnv()    // format of requests naming none; "" serves the page
 	recommendedSites = []scrapingSite{
 		{URL: "https://news.ycombinator.com", Tag: "Tech News", Selector: ".titleline > a", Example: "Hacker News headlines"},
 		{URL: "https://www.reddit.com/r/golang/", Tag: "Golang", Selector: "h3._eYtD2XCVieq6emjKBH3m", Example: "Reddit post titles"},
@@ -54,6 +89,14 @@ var (
 	}
 )
 
+// Build info for /version, set at link time with
+// -ldflags "-X github.com/GhanshyamJha05/WEB_SCRAPPER_Using-GO/api.version=...".
+var (
+	version = "dev"
+	commit  = "dev"
+	date    = "dev"
+)
+
 func init() {
 	funcMap := template.FuncMap{
 		"add": func(a, b int) int { return a + b },
@@ -63,31 +106,112 @@ func init() {
 	if err != nil {
 		log.Fatalf("failed to parse template: %v", err)
 	}
-	cli = scraper.NewClient(scraper.DefaultConfig())
+	// A custom UI in SCRAPER_TEMPLATE_DIR replaces the embedded one when it parses.
+	if dir := os.Getenv(web.EnvTemplateDir); dir != "" {
+		custom, err := template.New("index.html").Funcs(funcMap).ParseFiles(filepath.Join(dir, "index.html"))
+		if err != nil {
+			log.Printf("%s: %v; using the embedded template", web.EnvTemplateDir, err)
+		} else {
+			tmpl = custom
+		}
+	}
+	cli = scraper.NewClient(scraper.ConfigFromEnv())
 }
 
 // --- visited URL helpers ---
 
+type visitedEntry struct {
+	URL         string
+	LastVisited time.Time
+}
+
+// Downloaded formats BytesDownloaded for the stats block, e.g. "1.4 MB".
+func (d pageData) Downloaded() string {
+	return formatBytes(d.BytesDownloaded)
+}
+
+// formatBytes renders n in B, KB or MB (powers of 1024).
+func formatBytes(n int64) string {
+	switch {
+	case n < 1<<10:
+		return fmt.Sprintf("%d B", n)
+	case n < 1<<20:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	default:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	}
+}
+
+// Ago formats LastVisited relative to now, e.g. "2 min ago".
+func (v visitedEntry) Ago() string {
+	d := time.Since(v.LastVisited)
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%d min ago", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%d h ago", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%d d ago", int(d.Hours()/24))
+	}
+}
+
 func addToVisited(url string) {
 	mu.Lock()
 	defer mu.Unlock()
-	for i, u := range visited {
-		if u == url {
+	entry := visitedEntry{URL: url, LastVisited: time.Now()}
+	for i, v := range visited {
+		if v.URL == url {
 			visited = append(visited[:i], visited[i+1:]...)
-			visited = append(visited, url)
+			visited = append(visited, entry)
 			return
 		}
 	}
-	visited = append(visited, url)
-	if len(visited) > 10 {
-		visited = visited[1:]
+	visited = append(visited, entry)
+	if over := len(visited) - historySize; over > 0 {
+		visited = visited[over:] // drop the oldest, keep the most recent
+	}
+}
+
+// canonicalizeVisited replaces the visited URL of each page declaring a
+// different canonical URL with that one, so tracking-parameter and mirror
+// URLs of one page collapse into a single entry.
+func canonicalizeVisited(pages []scraper.PageInfo) {
+	for _, p := range pages {
+		if p.Canonical == "" || p.Canonical == p.URL {
+			continue
+		}
+		mu.Lock()
+		visited = slices.DeleteFunc(visited, func(v visitedEntry) bool { return v.URL == p.URL })
+		mu.Unlock()
+		addToVisited(p.Canonical)
+	}
+}
+
+// clearVisitedHandler handles POST /visited/clear: it empties the
+// recent-history list of this instance and redirects back to the form.
+// Cross-site requests are refused.
+func clearVisitedHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !sameOrigin(r) {
+		http.Error(w, "Cross-site request refused", http.StatusForbidden)
+		return
 	}
+	mu.Lock()
+	visited = nil
+	mu.Unlock()
+	http.Redirect(w, r, "/", http.StatusSeeOther)
 }
 
-func getVisited() []string {
+func getVisited() []visitedEntry {
 	mu.Lock()
 	defer mu.Unlock()
-	copied := make([]string, len(visited))
+	copied := make([]visitedEntry, len(visited))
 	copy(copied, visited)
 	for i, j := 0, len(copied)-1; i < j; i, j = i+1, j-1 {
 		copied[i], copied[j] = copied[j], copied[i]
@@ -95,12 +219,62 @@ func getVisited() []string {
 	return copied
 }
 
+// --- recommended site helpers ---
+
+// recommendedSelector returns the selector of the recommended site on the same
+// domain as pageURL, so every path on a known site inherits its selector.
+func recommendedSelector(pageURL string) string {
+	host := scraper.Host(pageURL)
+	if host == "" {
+		return ""
+	}
+	for _, site := range recommendedSites {
+		if scraper.Host(site.URL) == host {
+			return site.Selector
+		}
+	}
+	return ""
+}
+
 // --- Vercel entrypoint ---
 
+// route is one endpoint besides the index page, matched by path suffix so
+// /api/bulk-scrape and /bulk-scrape both work.
+type route struct {
+	suffix string
+	signed bool // an API endpoint: needs a SignatureHeader when SCRAPER_API_SECRET is set
+	serve  http.HandlerFunc
+}
+
+// routes lists every endpoint besides the index page, first match wins.
+// Both Handler and the signature check use it.
+var routes = []route{
+	{suffix: "/bulk-scrape", signed: true, serve: bulkScrapeHandler},
+	{suffix: "/batch", signed: true, serve: batchHandler},
+	{suffix: "/suggest", signed: true, serve: suggestHandler},
+	{suffix: "/check", signed: true, serve: checkHandler},
+	{suffix: "/visited/clear", serve: clearVisitedHandler},
+	{suffix: "/version", serve: versionHandler},
+}
+
+// findRoute returns the entry of routes for path, or false for the index
+// page.
+func findRoute(path string) (route, bool) {
+	for _, rt := range routes {
+		if strings.HasSuffix(path, rt.suffix) {
+			return rt, true
+		}
+	}
+	return route{}, false
+}
+
 // Handler is the exported function Vercel calls for every request.
 func Handler(w http.ResponseWriter, r *http.Request) {
-	if r.URL.Path == "/api/bulk-scrape" || strings.HasSuffix(r.URL.Path, "/bulk-scrape") {
-		bulkScrapeHandler(w, r)
+	if apiSecret != nil && isAPIRequest(r) && !authorize(w, r, apiSecret) {
+		return
+	}
+	if rt, ok := findRoute(r.URL.Path); ok {
+		rt.serve(w, r)
 		return
 	}
 	indexHandler(w, r)
@@ -108,14 +282,25 @@ func Handler(w http.ResponseWriter, r *http.Request) {
 
 // --- route handlers ---
 
+func versionHandler(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, map[string]string{"version": version, "commit": commit, "build_date": date})
+}
+
 func indexHandler(w http.ResponseWriter, r *http.Request) {
 	data := pageData{
 		Recommended: recommendedSites,
 		Visited:     getVisited(),
 	}
 
-	rawURL := r.URL.Query().Get("url")
-	selector := r.URL.Query().Get("selector")
+	q := r.URL.Query()
+	rawURL := q.Get("url")
+	selector := q.Get("selector")
+	format := scraper.RequestFormat(r, defaultFormat) // the format parameter, else the Accept header
+	copyJSON := q.Get("copy") == "json"               // UI "Copy as JSON" button
+	w.Header().Add("Vary", "Accept")
+	if copyJSON {
+		format = "json"
+	}
 
 	if rawURL != "" {
 		data.URL = rawURL
@@ -123,34 +308,125 @@ func indexHandler(w http.ResponseWriter, r *http.Request) {
 		urls := scraper.ParseURLs(rawURL)
 
 		if len(urls) == 0 {
-			data.Error = "Please provide at least one valid URL."
-			render(w, data)
+			fail(w, format, data, "Please provide at least one valid URL.")
 			return
 		}
 		if len(urls) > cli.MaxURLs() {
-			data.Error = fmt.Sprintf("Too many URLs. Maximum allowed per request is %d.", cli.MaxURLs())
-			render(w, data)
+			fail(w, format, data, fmt.Sprintf("Too many URLs. Maximum allowed per request is %d.", cli.MaxURLs()))
+			return
+		}
+		if err := checkURLs(r.Context(), urls); err != nil {
+			fail(w, format, data, web.Capitalize(err.Error()))
+			return
+		}
+		opts, err := scraper.ParseOptions(q)
+		if err == nil {
+			err = cli.CheckOptions(opts)
+		}
+		if err != nil {
+			fail(w, format, data, err.Error())
 			return
 		}
+		if len(opts.Attrs) > 0 {
+			data.Attr = opts.Attrs[0]
+		}
 		for _, u := range urls {
 			addToVisited(u)
 		}
 
+		if format == "raw" {
+			writeRaw(w, r, opts, urls)
+			return
+		}
+
+		if opts.Mode != "" {
+			if len(urls) != 1 {
+				fail(w, format, data, "mode="+opts.Mode+" accepts exactly one URL.")
+				return
+			}
+			if opts.Mode == scraper.ModeMeta {
+				if msg := web.JSONOnly("mode=meta", format); msg != "" {
+					fail(w, format, data, msg)
+					return
+				}
+				renderMeta(w, r, format, data, opts, urls[0])
+				return
+			}
+			scrape, c := web.ValueModes[opts.Mode], cli.WithOptions(opts)
+			renderValues(w, r, format, data, opts.Mode, func() (any, []any, error) {
+				return scrape(r.Context(), c, opts, urls[0], selector)
+			})
+			return
+		}
+
+		if opts.Sitemap {
+			if len(urls) != 1 {
+				fail(w, format, data, "sitemap=true accepts exactly one URL.")
+				return
+			}
+			found, err := cli.WithOptions(opts).Sitemap(r.Context(), urls[0])
+			if err != nil {
+				if format != "" {
+					http.Error(w, "Sitemap failed: "+err.Error(), http.StatusBadGateway)
+					return
+				}
+				data.Error = err.Error()
+				renderError(w, r, data, []error{err})
+				return
+			}
+			if selector == "" {
+				renderSitemap(w, format, data, opts, urls[0], found)
+				return
+			}
+			// Scrape the listed pages, as many as one request may cover.
+			urls = found[:min(len(found), cli.MaxURLs())]
+			if err := checkURLs(r.Context(), urls); err != nil {
+				fail(w, format, data, web.Capitalize(err.Error()))
+				return
+			}
+		}
+
 		// Auto-fill selector from recommended sites if not provided.
 		if selector == "" {
-			for _, site := range recommendedSites {
-				if site.URL == urls[0] {
-					selector = site.Selector
-					data.Selector = selector
-					break
-				}
+			selector = recommendedSelector(urls[0])
+			data.Selector = selector
+		}
+
+		if selector != "" && opts.TableMode != "" {
+			if msg := web.JSONOnly("tableMode", format); msg != "" {
+				fail(w, format, data, msg)
+				return
+			}
+			renderTables(w, r, format, data, opts, urls, selector)
+			return
+		}
+
+		if selector != "" && opts.CountOnly {
+			if msg := web.JSONOnly("countOnly", format); msg != "" {
+				fail(w, format, data, msg)
+				return
 			}
+			renderCount(w, r, format, data, opts, urls, selector)
+			return
+		}
+
+		if selector != "" && format == "jsonl" {
+			writeJSONL(w, opts, urls, selector)
+			return
 		}
 
 		if selector != "" {
 			start := time.Now()
-			results, errs := cli.ScrapeWithWorkerPool(urls, selector)
+			scoped := cli.WithOptions(opts)
+			run, reused, err := scrapeAll(r, scoped, urls, selector)
+			if err != nil {
+				return // the client left while waiting for a repeat's result
+			}
+			results, errs := run.Results, run.Errs
 			data.Duration = time.Since(start).Round(time.Millisecond)
+			if historyCanon {
+				canonicalizeVisited(run.Pages)
+			}
 			if len(errs) > 0 {
 				msgs := make([]string, 0, len(errs))
 				for _, e := range errs {
@@ -158,9 +434,67 @@ func indexHandler(w http.ResponseWriter, r *http.Request) {
 				}
 				data.Error = fmt.Sprintf("Completed with %d error(s): %s", len(errs), strings.Join(msgs, " | "))
 			}
+			if len(errs) > 0 && len(errs) == run.Attempted && format != "md" && format != "csv" && format != "json" {
+				renderError(w, r, data, errs)
+				return
+			}
+			if format == "md" {
+				w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+				if err := scraper.WriteMarkdown(w, results, errs); err != nil {
+					log.Printf("markdown write error: %v", err)
+				}
+				return
+			}
+			if format == "csv" {
+				w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+				if err := scraper.WriteCSV(w, results, opts); err != nil {
+					log.Printf("csv write error: %v", err)
+				}
+				return
+			}
+			if format == "json" {
+				if copyJSON {
+					w.Header().Set("Content-Disposition", "inline")
+				}
+				out := scraper.NewScrapeOutput(urls, selector, scoped.Workers(), results, errs)
+				out.Meta.Cached = run.Cached
+				out.Meta.Reused = reused
+				out.Meta.Skipped = run.Skipped
+				out.Meta.StoppedReason = run.StoppedReason
+				out.Records = run.Records
+				out.Meta.Timings = run.Timings
+				out.Meta.Pages = run.Pages
+				out.Meta.BytesDownloaded = run.BytesDownloaded
+				out.Truncate(cli.MaxOutputBytes())
+				out.SetNextCursor(opts.Offset, run.Count)
+				writeJSON(w, out)
+				return
+			}
 			data.Results = results
+			data.Cached = run.Cached
+			data.Reused = reused
+			data.Skipped = run.Skipped
+			data.StoppedReason = run.StoppedReason
+			for _, p := range run.Pages {
+				if p.Warning != "" {
+					data.ParseWarnings = append(data.ParseWarnings, p.URL+": "+p.Warning)
+				}
+				if data.FixedSelector == "" {
+					data.FixedSelector = p.FixedSelector
+				}
+			}
+			data.Timings = run.Timings
+			if len(run.Pages) > 0 {
+				data.PageTitle = run.Pages[0].Title
+				data.PageDescription = run.Pages[0].Description
+				data.CanonicalURL = run.Pages[0].Canonical
+			}
+			data.BytesDownloaded = run.BytesDownloaded
+			data.Scraped = true
+			data.MatchCount = len(results)
 		} else {
-			data.Error = "Please provide a CSS selector."
+			fail(w, format, data, "Please provide a CSS selector.")
+			return
 		}
 	}
 
@@ -199,6 +533,10 @@ func bulkScrapeHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, fmt.Sprintf("Maximum %d URLs allowed per request", cli.MaxURLs()), http.StatusBadRequest)
 		return
 	}
+	if err := checkURLs(r.Context(), urls); err != nil {
+		http.Error(w, web.Capitalize(err.Error()), http.StatusForbidden)
+		return
+	}
 
 	for _, u := range urls {
 		addToVisited(u)
@@ -211,9 +549,438 @@ func bulkScrapeHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func batchHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var items []scraper.BatchItem
+	if err := json.NewDecoder(r.Body).Decode(&items); err != nil {
+		http.Error(w, "Invalid JSON payload", http.StatusBadRequest)
+		return
+	}
+
+	if len(items) == 0 {
+		http.Error(w, "At least one item is required", http.StatusBadRequest)
+		return
+	}
+	if len(items) > cli.MaxURLs() {
+		http.Error(w, fmt.Sprintf("Maximum %d items allowed per request", cli.MaxURLs()), http.StatusBadRequest)
+		return
+	}
+	for i := range items {
+		items[i].URL = strings.TrimSpace(items[i].URL)
+		items[i].Selector = strings.TrimSpace(items[i].Selector)
+		if items[i].URL == "" || items[i].Selector == "" {
+			http.Error(w, fmt.Sprintf("Item %d needs both url and selector", i), http.StatusBadRequest)
+			return
+		}
+		if err := checkURLs(r.Context(), []string{items[i].URL}); err != nil {
+			http.Error(w, web.Capitalize(err.Error()), http.StatusForbidden)
+			return
+		}
+	}
+
+	for _, it := range items {
+		addToVisited(it.URL)
+	}
+
+	resp := cli.RunBatch(items)
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+// writeRaw sends the unparsed body of a single page back with its original
+// Content-Type, for inspecting markup when a selector misbehaves.
+func writeRaw(w http.ResponseWriter, r *http.Request, opts scraper.Options, urls []string) {
+	if len(urls) != 1 {
+		http.Error(w, "format=raw accepts exactly one URL", http.StatusBadRequest)
+		return
+	}
+	page, err := cli.WithOptions(opts).FetchRaw(r.Context(), urls[0])
+	if err != nil {
+		http.Error(w, "Failed to fetch page: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	contentType := page.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", contentType)
+	// Third-party markup is served from our origin, so sandbox it to keep
+	// its scripts away from this site's cookies and storage.
+	w.Header().Set("Content-Security-Policy", "sandbox")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if _, err := w.Write(page.Body); err != nil {
+		log.Printf("raw write error: %v", err)
+	}
+}
+
+func suggestHandler(w http.ResponseWriter, r *http.Request) {
+	pageURL := strings.TrimSpace(r.URL.Query().Get("url"))
+	if pageURL == "" {
+		http.Error(w, "url is required", http.StatusBadRequest)
+		return
+	}
+	if err := checkURLs(r.Context(), []string{pageURL}); err != nil {
+		http.Error(w, web.Capitalize(err.Error()), http.StatusForbidden)
+		return
+	}
+
+	suggestions, err := cli.SuggestSelectors(r.Context(), pageURL, 10)
+	if err != nil {
+		http.Error(w, "Failed to fetch page: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	writeJSON(w, scraper.SuggestOutput{URL: pageURL, Suggestions: suggestions})
+}
+
+func checkHandler(w http.ResponseWriter, r *http.Request) {
+	pageURL := strings.TrimSpace(r.URL.Query().Get("url"))
+	if pageURL == "" {
+		http.Error(w, "url is required", http.StatusBadRequest)
+		return
+	}
+	if err := checkURLs(r.Context(), []string{pageURL}); err != nil {
+		http.Error(w, web.Capitalize(err.Error()), http.StatusForbidden)
+		return
+	}
+	writeJSON(w, cli.Check(r.Context(), pageURL))
+}
+
+// writeJSONL streams format=jsonl results: one JSON object per line, flushed
+// as each page finishes.
+func writeJSONL(w http.ResponseWriter, opts scraper.Options, urls []string, selector string) {
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if err := scraper.WriteJSONL(w, cli.WithOptions(opts).ScrapeStreamed(urls, selector), opts); err != nil {
+		log.Printf("jsonl write error: %v", err)
+	}
+}
+
+// renderTables serves a tableMode scrape: each table matched by selector is
+// returned as a header plus rows of cell text instead of flat results, or
+// with tableMode=objects as rows keyed by header text.
+func renderTables(w http.ResponseWriter, r *http.Request, format string, data pageData, opts scraper.Options, urls []string, selector string) {
+	if len(urls) != 1 {
+		fail(w, format, data, "tableMode accepts exactly one URL.")
+		return
+	}
+
+	start := time.Now()
+	tables, err := cli.WithOptions(opts).ScrapeTables(r.Context(), urls[0], selector)
+	data.Duration = time.Since(start).Round(time.Millisecond)
+	if err != nil {
+		if format == "json" {
+			http.Error(w, "Scrape failed: "+err.Error(), http.StatusBadGateway)
+			return
+		}
+		data.Error = err.Error()
+		renderError(w, r, data, []error{err})
+		return
+	}
+
+	if format == "json" {
+		if opts.TableMode == scraper.TableObjects {
+			for i := range tables {
+				tables[i].Rows = nil // the same cells are in Objects
+			}
+		}
+		writeJSON(w, scraper.TableOutput{URL: urls[0], Selector: selector, Tables: tables})
+		return
+	}
+	data.Tables = tables
+	data.Scraped = true
+	data.MatchCount = len(tables)
+	render(w, data)
+}
+
+// renderSitemap serves sitemap=true without a selector: the page URLs
+// listed by the sitemap, as scraper.SitemapOutput JSON or as one result
+// per URL.
+func renderSitemap(w http.ResponseWriter, format string, data pageData, opts scraper.Options, sitemapURL string, urls []string) {
+	if format == "json" {
+		writeJSON(w, scraper.SitemapOutput{URL: sitemapURL, URLs: urls, Total: len(urls)})
+		return
+	}
+	results := make([]scraper.ScrapeResult, len(urls))
+	for i, u := range urls {
+		results[i] = scraper.ScrapeResult{Title: u, Link: u}
+	}
+	switch format {
+	case "jsonl":
+		fail(w, format, data, "sitemap=true without a selector does not support format=jsonl.")
+	case "md":
+		w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+		if err := scraper.WriteMarkdown(w, results, nil); err != nil {
+			log.Printf("markdown write error: %v", err)
+		}
+	case "csv":
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		if err := scraper.WriteCSV(w, results, opts); err != nil {
+			log.Printf("csv write error: %v", err)
+		}
+	default:
+		data.Results = results
+		data.Scraped = true
+		data.MatchCount = len(results)
+		render(w, data)
+	}
+}
+
+// renderMeta serves a mode=meta scrape: the page's OpenGraph and Twitter
+// card tags instead of selector matches.
+func renderMeta(w http.ResponseWriter, r *http.Request, format string, data pageData, opts scraper.Options, pageURL string) {
+	start := time.Now()
+	meta, err := cli.WithOptions(opts).ScrapeMetaTags(r.Context(), pageURL)
+	data.Duration = time.Since(start).Round(time.Millisecond)
+	if err != nil {
+		if format == "json" {
+			http.Error(w, "Scrape failed: "+err.Error(), http.StatusBadGateway)
+			return
+		}
+		data.Error = err.Error()
+		renderError(w, r, data, []error{err})
+		return
+	}
+
+	if format == "json" {
+		writeJSON(w, meta)
+		return
+	}
+	data.Meta = &meta
+	data.Scraped = true
+	data.MatchCount = len(meta.OpenGraph) + len(meta.Twitter)
+	render(w, data)
+}
+
+// renderValues serves a scrape in one of web.ValueModes, name: fetch's
+// output as JSON for format=json, else its values as indented JSON blocks
+// on the page.
+func renderValues(w http.ResponseWriter, r *http.Request, format string, data pageData, name string, fetch func() (any, []any, error)) {
+	if msg := web.JSONOnly("mode="+name, format); msg != "" {
+		fail(w, format, data, msg)
+		return
+	}
+
+	start := time.Now()
+	out, values, err := fetch()
+	data.Duration = time.Since(start).Round(time.Millisecond)
+	if err != nil {
+		if format == "json" {
+			http.Error(w, "Scrape failed: "+err.Error(), http.StatusBadGateway)
+			return
+		}
+		data.Error = err.Error()
+		renderError(w, r, data, []error{err})
+		return
+	}
+
+	if format == "json" {
+		writeJSON(w, out)
+		return
+	}
+	data.ScriptValues = web.IndentValues(values)
+	data.Scraped = true
+	data.MatchCount = len(data.ScriptValues)
+	render(w, data)
+}
+
+// renderCount serves countOnly=true: the number of matches, as
+// scraper.CountOutput JSON or in the page's match-count pill.
+func renderCount(w http.ResponseWriter, r *http.Request, format string, data pageData, opts scraper.Options, urls []string, selector string) {
+	start := time.Now()
+	run := cli.WithOptions(opts).ScrapeAll(urls, selector)
+	data.Duration = time.Since(start).Round(time.Millisecond)
+	if format == "json" {
+		writeJSON(w, scraper.NewCountOutput(urls, selector, run))
+		return
+	}
+	if len(run.Errs) > 0 {
+		msgs := make([]string, 0, len(run.Errs))
+		for _, e := range run.Errs {
+			msgs = append(msgs, e.Error())
+		}
+		data.Error = fmt.Sprintf("Completed with %d error(s): %s", len(run.Errs), strings.Join(msgs, " | "))
+	}
+	if len(run.Errs) == len(urls) {
+		renderError(w, r, data, run.Errs)
+		return
+	}
+	data.CountOnly = true
+	data.Scraped = true
+	data.MatchCount = run.Count
+	data.StoppedReason = run.StoppedReason
+	render(w, data)
+}
+
+// checkURLs applies the client's HostPolicy to every URL before any request
+// is made, so a rejected host gets a clear error instead of a failed fetch.
+// Handlers showing the error pass it through web.Capitalize.
+func checkURLs(ctx context.Context, urls []string) error {
+	for _, u := range urls {
+		if err := cli.CheckURL(ctx, u); err != nil {
+			return fmt.Errorf("refusing to scrape %s: %w", u, err)
+		}
+	}
+	return nil
+}
+
+// fail reports an input error: as a plain-text 400 for the json, jsonl and
+// md formats, or inside the rendered page otherwise.
+func fail(w http.ResponseWriter, format string, data pageData, msg string) {
+	if format == "json" || format == "jsonl" || format == "md" || format == "csv" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+	data.Error = msg
+	render(w, data)
+}
+
+// writeJSON encodes v as the response body.
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+// executeTemplate renders t into a buffer and only then writes it with
+// status, so an error halfway through the template becomes a clean 500
+// instead of half a page. A render still running after renderWarn
+// (SCRAPER_RENDER_WARN) is logged, and so is how long it finally took.
+func executeTemplate(w http.ResponseWriter, t *template.Template, data any, status int) error {
+	start := time.Now()
+	watchdog := time.AfterFunc(renderWarn, func() {
+		log.Printf("template %s: still rendering after %v", t.Name(), renderWarn)
+	})
+	var buf bytes.Buffer
+	err := t.Execute(&buf, data)
+	if !watchdog.Stop() {
+		log.Printf("template %s: rendered in %v", t.Name(), time.Since(start).Round(time.Millisecond))
+	}
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	_, _ = buf.WriteTo(w) // a write error means the client went away
+	return nil
+}
+
+// render executes the index template, cutting Results down to the render
+// limit first.
 func render(w http.ResponseWriter, data pageData) {
-	if err := tmpl.Execute(w, data); err != nil {
+	if renderLimit > 0 && len(data.Results) > renderLimit {
+		data.Hidden = len(data.Results) - renderLimit
+		data.Results = data.Results[:renderLimit]
+	}
+	if err := executeTemplate(w, tmpl, data, http.StatusOK); err != nil {
 		log.Printf("template error: %v", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 	}
 }
+
+// errorPage is the template context for templates/error.html.
+type errorPage struct {
+	URL        string
+	Selector   string
+	Kind       string   // scraper.ErrorInfo.Kind of the first error
+	Suggestion string   // scraper.ErrorInfo.Suggestion of the first error
+	Errors     []string // every error, one per failed URL
+	RetryURL   string   // the same request again
+}
+
+// renderError shows a scrape in which every URL failed on the error
+// template, with a 502.
+func renderError(w http.ResponseWriter, r *http.Request, data pageData, errs []error) {
+	info := scraper.ExplainError(errs[0])
+	q := r.URL.Query()
+	q.Del("queueId")
+	q.Del("loginPass") // never echo a password or token into the page
+	q.Del("header")
+	page := errorPage{URL: data.URL, Selector: data.Selector, Kind: info.Kind, Suggestion: info.Suggestion, RetryURL: r.URL.Path + "?" + q.Encode()}
+	for _, e := range errs {
+		page.Errors = append(page.Errors, e.Error())
+	}
+	if err := executeTemplate(w, errTmpl, page, http.StatusBadGateway); err != nil {
+		log.Printf("error template: %v", err)
+		http.Error(w, "Scrape failed: "+page.Errors[0], http.StatusBadGateway)
+	}
+}
+
+// sameOrigin reports whether r comes from a page of this site, going by
+// Sec-Fetch-Site or else Origin. Requests with neither are allowed: there is
+// no session cookie for a forged request to ride on.
+func sameOrigin(r *http.Request) bool {
+	if site := r.Header.Get("Sec-Fetch-Site"); site != "" {
+		return site == "same-origin" || site == "none"
+	}
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && u.Host == r.Host
+}
+
+// isAPIRequest reports whether r is for the API rather than the HTML UI: a
+// signed entry of routes, or any page requested with a format, by parameter
+// or Accept header, or by SCRAPER_DEFAULT_FORMAT. Only those need a
+// signature when an API secret is set.
+func isAPIRequest(r *http.Request) bool {
+	if rt, ok := findRoute(r.URL.Path); ok && rt.signed {
+		return true
+	}
+	return scraper.RequestFormat(r, defaultFormat) != "" || r.URL.Query().Get("copy") == "json"
+}
+
+// authorize verifies r's scraper.SignatureHeader, answering 401 (413 for a
+// body too large to check) when it fails.
+func authorize(w http.ResponseWriter, r *http.Request, secret []byte) bool {
+	err := scraper.VerifyRequest(secret, r)
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, scraper.ErrResponseTooLarge):
+		http.Error(w, "Request body too large to verify", http.StatusRequestEntityTooLarge)
+	default:
+		http.Error(w, "Unauthorized: "+err.Error(), http.StatusUnauthorized)
+	}
+	return false
+}
+
+// newDebouncer returns the web.Debouncer for SCRAPER_DEBOUNCE, or nil when
+// it is unset.
+func newDebouncer() *web.Debouncer {
+	if d := web.DebounceFromEnv(); d > 0 {
+		return web.NewDebouncer(d)
+	}
+	return nil
+}
+
+// scrapeAll is c.ScrapeAll, except that with SCRAPER_DEBOUNCE set a repeat
+// of a client's scrape, arriving while it runs or within the window after,
+// gets its result instead of fetching the pages again. The second result
+// reports whether the run was reused; the error is the request's, when the
+// client left while waiting for a repeat's result.
+func scrapeAll(r *http.Request, c *scraper.Client, urls []string, selector string) (scraper.ScrapeRun, bool, error) {
+	if debounce == nil {
+		return c.ScrapeAll(urls, selector), false, nil
+	}
+	return debounce.Do(r.Context(), web.DebounceKey(r), func() scraper.ScrapeRun {
+		return c.ScrapeAll(urls, selector)
+	})
+}
+
+// apiSecretFromEnv returns SCRAPER_API_SECRET, or nil when it is unset.
+func apiSecretFromEnv() []byte {
+	if v := os.Getenv(web.EnvAPISecret); v != "" {
+		return []byte(v)
+	}
+	return nil
+}
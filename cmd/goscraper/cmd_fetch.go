@@ -45,6 +45,7 @@ func runFetch(_ *cobra.Command, _ []string) error {
 
 	cfg := scraper.DefaultConfig()
 	cfg.WorkerCount = fetchWorkers
+	applyProxies(&cfg)
 	cli := scraper.NewClient(cfg)
 
 	// Single URL goes through the same ScrapeStreamed pipeline as run —
@@ -60,6 +61,9 @@ func runFetch(_ *cobra.Command, _ []string) error {
 			results = append(results, r.Items...)
 		}
 		ui.Progress(1, 1, r.URL, len(r.Items), r.DurationMs, r.Err)
+		if r.Proxy != "" {
+			ui.Detail("via " + r.Proxy)
+		}
 	}
 	elapsed := time.Since(start).Seconds()
 
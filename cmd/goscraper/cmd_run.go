@@ -22,6 +22,7 @@ var (
 	runSelector string
 	runWorkers  int
 	runOutput   string
+	runDelay    time.Duration
 )
 
 func init() {
@@ -29,6 +30,7 @@ func init() {
 	runCmd.Flags().StringVar(&runSelector, "selector", "", "CSS selector to extract (required)")
 	runCmd.Flags().IntVar(&runWorkers, "workers", 6, "number of concurrent workers")
 	runCmd.Flags().StringVar(&runOutput, "output", "", "write results to this JSON file (default: stdout)")
+	runCmd.Flags().DurationVar(&runDelay, "crawl-delay", scraper.DefaultConfig().CrawlDelay, "minimum gap between requests to the same host (0 disables)")
 
 	_ = runCmd.MarkFlagRequired("file")
 	_ = runCmd.MarkFlagRequired("selector")
@@ -55,6 +57,8 @@ func runRun(_ *cobra.Command, _ []string) error {
 
 	cfg := scraper.DefaultConfig()
 	cfg.WorkerCount = runWorkers
+	cfg.CrawlDelay = runDelay
+	applyProxies(&cfg)
 	cli := scraper.NewClient(cfg)
 
 	// All URLs are submitted to the pool at once — true concurrent scraping.
@@ -73,6 +77,9 @@ func runRun(_ *cobra.Command, _ []string) error {
 			allResults = append(allResults, r.Items...)
 		}
 		ui.Progress(n, total, r.URL, len(r.Items), r.DurationMs, r.Err)
+		if r.Proxy != "" {
+			ui.Detail("via " + r.Proxy)
+		}
 	}
 	elapsed := time.Since(start).Seconds()
 
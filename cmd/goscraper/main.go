@@ -33,13 +33,17 @@ Use "goscraper [command] --help" for more information.`,
 }
 
 var (
-	flagVerbose bool
-	flagQuiet   bool
+	flagVerbose  bool
+	flagQuiet    bool
+	flagProxies  string
+	flagRotation string
 )
 
 func init() {
 	rootCmd.PersistentFlags().BoolVarP(&flagVerbose, "verbose", "v", false, "show detailed output including per-URL timing")
 	rootCmd.PersistentFlags().BoolVarP(&flagQuiet, "quiet", "q", false, "suppress all output except errors and saved path")
+	rootCmd.PersistentFlags().StringVar(&flagProxies, "proxies", "", "comma-separated proxy URLs to rotate through (default: $"+scraper.EnvProxies+")")
+	rootCmd.PersistentFlags().StringVar(&flagRotation, "proxy-rotation", scraper.ProxyRoundRobin, "how to pick a proxy per request: roundrobin or random")
 
 	rootCmd.PersistentPreRun = func(cmd *cobra.Command, args []string) {
 		switch {
@@ -92,6 +96,22 @@ func readURLs(path string) ([]string, error) {
 	return urls, sc.Err()
 }
 
+// applyProxies sets cfg's proxies from --proxies, else SCRAPER_PROXIES.
+func applyProxies(cfg *scraper.Config) {
+	raw := flagProxies
+	if raw == "" {
+		raw = os.Getenv(scraper.EnvProxies)
+	}
+	proxies, err := scraper.ParseProxies(raw)
+	if err != nil {
+		ui.Fatal(err.Error())
+	}
+	if flagRotation != scraper.ProxyRoundRobin && flagRotation != scraper.ProxyRandom {
+		ui.Fatal("unknown --proxy-rotation " + flagRotation + ": use roundrobin or random")
+	}
+	cfg.Proxies, cfg.ProxyRotation = proxies, flagRotation
+}
+
 // writeOutput writes a ScrapeOutput as indented JSON to a file or stdout.
 func writeOutput(path string, out scraper.ScrapeOutput) error {
 	if path != "" {
@@ -4,7 +4,10 @@ go 1.23.0
 
 require (
 	github.com/PuerkitoBio/goquery v1.10.3
+	github.com/gorilla/websocket v1.5.3
 	github.com/prometheus/client_golang v1.20.5
+	github.com/spf13/cobra v1.10.2
+	golang.org/x/net v0.39.0
 )
 
 require (
@@ -17,9 +20,8 @@ require (
 	github.com/prometheus/client_model v0.6.1 // indirect
 	github.com/prometheus/common v0.55.0 // indirect
 	github.com/prometheus/procfs v0.15.1 // indirect
-	github.com/spf13/cobra v1.10.2 // indirect
 	github.com/spf13/pflag v1.0.9 // indirect
-	golang.org/x/net v0.39.0 // indirect
 	golang.org/x/sys v0.32.0 // indirect
+	golang.org/x/text v0.24.0 // indirect
 	google.golang.org/protobuf v1.34.2 // indirect
 )
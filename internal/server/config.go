@@ -0,0 +1,177 @@
+package server
+
+import (
+	"context"
+	"html/template"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/GhanshyamJha05/WEB_SCRAPPER_Using-GO/pkg/scraper"
+	"github.com/GhanshyamJha05/WEB_SCRAPPER_Using-GO/pkg/web"
+)
+
+// Config is everything needed to run the web server. main builds one from
+// flags and the environment; embedders can fill it in directly.
+type Config struct {
+	Addr          string             // listen address; defaults to ":8080"
+	Template      *template.Template // index page, executed with PageData
+	ErrorTemplate *template.Template // failed scrapes, executed with ErrorPage; nil shows errors inline
+	Scraper       scraper.Config     // passed to scraper.NewClient
+	Recommended   []ScrapingSite     // UI presets; nil uses RecommendedSites
+	HistorySize   int                // visited URLs kept; 0 uses web.DefaultHistorySize
+	Build         BuildInfo          // served by /version
+
+	// HistoryCanonical lists a scraped page in the visited history under its
+	// <link rel="canonical"> URL, when it declares one, instead of the URL
+	// it was requested with.
+	HistoryCanonical bool
+
+	// RenderLimit caps the results rendered into the index page, so a huge
+	// page can't freeze the browser; a notice points to the exports, which
+	// still carry every result. 0 uses web.DefaultRenderLimit, a
+	// negative value renders all.
+	RenderLimit int
+
+	// RenderWarn is how long rendering a page may take before it is logged
+	// as slow; 0 uses web.DefaultRenderWarn. Pages are always rendered
+	// in full before anything is sent, so a template error is a clean 500.
+	RenderWarn time.Duration
+
+	// Debounce, when positive, answers a client repeating a scrape (same
+	// query, from the same address) within this window with the result of
+	// the first one instead of fetching the pages again, so a double submit
+	// or a burst of reloads hits the targets once. 0 disables it.
+	Debounce time.Duration
+
+	// DefaultFormat is the format ("json", "jsonl", "csv" or "md") of a
+	// scrape requested without a format parameter or an Accept header
+	// preferring one, e.g. by curl; "" serves the HTML page. Browsers, which
+	// prefer text/html, always get the page. See scraper.RequestFormat.
+	DefaultFormat string
+
+	// WarmInterval, when positive, re-scrapes the recommended sites in the
+	// background at this interval so /dashboard is always served from cache.
+	// The warmer stops when the server is shut down.
+	WarmInterval time.Duration
+
+	// DashboardConcurrency caps how many recommended sites /dashboard scrapes
+	// at once and DashboardTimeout bounds each one, so a slow site shows as
+	// timed out instead of holding up the page. 0 uses
+	// DefaultDashboardConcurrency and DefaultDashboardTimeout.
+	DashboardConcurrency int
+	DashboardTimeout     time.Duration
+
+	// SnapshotFile, when set, is where /api/diff keeps its snapshots, so
+	// change feeds polled with since survive restarts. It is read at start
+	// and rewritten after every diff.
+	SnapshotFile string
+
+	// JobsFile, when set, is where the job definitions of POST /jobs are
+	// kept, so scheduled scrapes survive restarts. It is read at start and
+	// rewritten whenever a job is added or deleted; results are not saved.
+	JobsFile string
+
+	// MaxConcurrent, when positive, caps the scrapes (GET / with a url,
+	// bulk-scrape and batch) running at once. Up to QueueSize more wait
+	// for a slot and can poll GET /queue/{id}; beyond that they get 503.
+	MaxConcurrent int
+	QueueSize     int
+
+	// APISecret, when set, requires API requests (/api/* and any format=)
+	// to carry a valid scraper.SignatureHeader; others get 401. The HTML UI
+	// itself stays open.
+	APISecret string
+
+	// ReadTimeout, WriteTimeout and IdleTimeout are set on the http.Server
+	// so stalled clients can't hold connections open forever. 0 uses the
+	// Default*Timeout below; a negative value disables the timeout.
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	IdleTimeout  time.Duration
+}
+
+// Default http.Server timeouts. WriteTimeout covers the whole response, so
+// it leaves room for a queued multi-URL scrape with retries.
+const (
+	DefaultReadTimeout  = 15 * time.Second
+	DefaultWriteTimeout = 3 * time.Minute
+	DefaultIdleTimeout  = 2 * time.Minute
+)
+
+// timeout resolves a Config timeout: 0 is def, negative is none.
+func timeout(d, def time.Duration) time.Duration {
+	switch {
+	case d == 0:
+		return def
+	case d < 0:
+		return 0
+	}
+	return d
+}
+
+// NewServer returns an http.Server that serves the UI and API for cfg.
+// Each server owns its Handler, so several can run in one process.
+func NewServer(cfg Config) *http.Server {
+	if cfg.Addr == "" {
+		cfg.Addr = ":8080"
+	}
+	h := New(cfg.Template, scraper.NewClient(cfg.Scraper))
+	if cfg.Recommended != nil {
+		h.recommended = cfg.Recommended
+	}
+	if cfg.HistorySize > 0 {
+		h.historySize = cfg.HistorySize
+	}
+	h.historyCanon = cfg.HistoryCanonical
+	if cfg.RenderWarn > 0 {
+		h.renderWarn = cfg.RenderWarn
+	}
+	if cfg.RenderLimit != 0 {
+		h.renderLimit = max(cfg.RenderLimit, 0)
+	}
+	if cfg.Debounce > 0 {
+		h.debounce = web.NewDebouncer(cfg.Debounce)
+	}
+	h.defaultFmt = cfg.DefaultFormat
+	if cfg.DashboardConcurrency > 0 {
+		h.dashWorkers = cfg.DashboardConcurrency
+	}
+	if cfg.DashboardTimeout > 0 {
+		h.dashTimeout = cfg.DashboardTimeout
+	}
+	h.build = cfg.Build
+	h.errTmpl = cfg.ErrorTemplate
+	if cfg.APISecret != "" {
+		h.secret = []byte(cfg.APISecret)
+	}
+	if cfg.SnapshotFile != "" {
+		h.snapFile = cfg.SnapshotFile
+		if err := h.loadSnapshots(cfg.SnapshotFile); err != nil {
+			log.Printf("%s: %v; starting with no snapshots", cfg.SnapshotFile, err)
+		}
+	}
+	if cfg.JobsFile != "" {
+		h.jobs.file = cfg.JobsFile
+		if err := h.loadJobs(); err != nil {
+			log.Printf("%s: %v; starting with no jobs", cfg.JobsFile, err)
+		}
+	}
+	if cfg.MaxConcurrent > 0 {
+		h.queue = newScrapeQueue(cfg.MaxConcurrent, cfg.QueueSize)
+	}
+	srv := &http.Server{
+		Addr:         cfg.Addr,
+		Handler:      h,
+		ReadTimeout:  timeout(cfg.ReadTimeout, DefaultReadTimeout),
+		WriteTimeout: timeout(cfg.WriteTimeout, DefaultWriteTimeout),
+		IdleTimeout:  timeout(cfg.IdleTimeout, DefaultIdleTimeout),
+	}
+	srv.RegisterOnShutdown(h.jobs.stop)
+	if cfg.WarmInterval > 0 {
+		ctx, cancel := context.WithCancel(context.Background())
+		srv.RegisterOnShutdown(cancel)
+		go h.warmDashboard(ctx, cfg.WarmInterval)
+	}
+	return srv
+}
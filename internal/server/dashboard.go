@@ -0,0 +1,144 @@
+package server
+
+import (
+	"context"
+	"embed"
+	"html/template"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/GhanshyamJha05/WEB_SCRAPPER_Using-GO/pkg/scraper"
+)
+
+//go:embed dashboard.html
+var dashboardFS embed.FS
+
+var dashboardTmpl = template.Must(template.ParseFS(dashboardFS, "dashboard.html"))
+
+const (
+	dashboardTTL  = time.Minute // how long a dashboard run is served before rescraping
+	dashboardTopN = 5           // results shown per site
+)
+
+// Defaults for Config.DashboardConcurrency and Config.DashboardTimeout. The
+// dashboard is a background fan-out, so it takes few workers.
+const (
+	DefaultDashboardConcurrency = 3
+	DefaultDashboardTimeout     = 10 * time.Second
+)
+
+// DashboardSection is one recommended site on the dashboard.
+type DashboardSection struct {
+	Site       ScrapingSite           `json:"site"`
+	Results    []scraper.ScrapeResult `json:"results"` // first dashboardTopN matches
+	Total      int                    `json:"total"`   // matches before trimming
+	DurationMs int64                  `json:"duration_ms"`
+	Timing     *scraper.Timing        `json:"timing,omitempty"` // fetch phases; nil when the site failed
+	Error      string                 `json:"error,omitempty"`
+	TimedOut   bool                   `json:"timed_out,omitempty"` // the site hit Config.DashboardTimeout
+}
+
+// DashboardData is the template context (and JSON body) for GET /dashboard.
+type DashboardData struct {
+	ScrapedAt time.Time          `json:"scraped_at"`
+	Cached    bool               `json:"cached"`    // served from the last run without rescraping
+	InFlight  int64              `json:"in_flight"` // scrapes running on the server as the page was served
+	Sections  []DashboardSection `json:"sections"`
+}
+
+// dashboardCache holds the last dashboard run. mu is held for the whole
+// refresh so concurrent reloads wait for one scrape instead of starting more.
+type dashboardCache struct {
+	mu   sync.Mutex
+	data DashboardData
+}
+
+// Dashboard handles GET /dashboard: the top results of every recommended site,
+// scraped Config.DashboardConcurrency at a time and cached for dashboardTTL.
+// A failing or timed-out site shows its error in its own section.
+// format=json returns DashboardData.
+func (h *Handler) Dashboard(w http.ResponseWriter, r *http.Request) {
+	data := h.dashboardData()
+	data.InFlight = h.inFlight.Load()
+	if scraper.RequestFormat(r, h.defaultFmt) == "json" {
+		writeJSON(w, data)
+		return
+	}
+	if err := executeTemplate(w, dashboardTmpl, data, http.StatusOK, h.renderWarn); err != nil {
+		log.Printf("dashboard template error: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// dashboardData returns the cached run, rescraping once it is older than dashboardTTL.
+func (h *Handler) dashboardData() DashboardData {
+	h.dashboard.mu.Lock()
+	defer h.dashboard.mu.Unlock()
+
+	if d := h.dashboard.data; !d.ScrapedAt.IsZero() && time.Since(d.ScrapedAt) < dashboardTTL {
+		d.Cached = true
+		return d
+	}
+	return h.scrapeDashboard()
+}
+
+// warmDashboard re-scrapes the recommended sites now and then every interval
+// until ctx is done, so /dashboard is served from a fresh cache. Each refresh
+// also revalidates the client's page cache for those sites.
+func (h *Handler) warmDashboard(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		h.dashboard.mu.Lock()
+		start := time.Now()
+		data := h.scrapeDashboard()
+		h.dashboard.mu.Unlock()
+
+		failed := 0
+		for _, sec := range data.Sections {
+			if sec.Error != "" {
+				failed++
+			}
+		}
+		log.Printf("cache warmer: refreshed %d sites in %s, %d failed", len(data.Sections), time.Since(start).Round(time.Millisecond), failed)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// scrapeDashboard scrapes every recommended site through a worker pool of
+// h.dashWorkers, each site within h.dashTimeout, and stores the run.
+// The caller must hold h.dashboard.mu.
+func (h *Handler) scrapeDashboard() DashboardData {
+	defer h.countScrape()()
+	items := make([]scraper.BatchItem, len(h.recommended))
+	for i, site := range h.recommended {
+		items[i] = scraper.BatchItem{URL: site.URL, Selector: site.Selector}
+	}
+	run := h.cli.WithLimits(h.dashWorkers, h.dashTimeout).RunBatch(items)
+
+	data := DashboardData{ScrapedAt: time.Now().UTC(), Sections: make([]DashboardSection, len(items))}
+	for i, res := range run.Results {
+		sec := DashboardSection{
+			Site:       h.recommended[i],
+			Results:    res.Results,
+			Total:      len(res.Results),
+			DurationMs: res.DurationMs,
+			Timing:     res.Timing,
+			Error:      res.Error,
+			TimedOut:   res.TimedOut,
+		}
+		if len(sec.Results) > dashboardTopN {
+			sec.Results = sec.Results[:dashboardTopN]
+		}
+		data.Sections[i] = sec
+	}
+	h.dashboard.data = data
+	return data
+}
@@ -0,0 +1,20 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/GhanshyamJha05/WEB_SCRAPPER_Using-GO/pkg/scraper"
+	"github.com/GhanshyamJha05/WEB_SCRAPPER_Using-GO/pkg/web"
+)
+
+// scrapeAll is cli.ScrapeAll, debounced per client when Config.Debounce is
+// set. The second result reports whether the run was reused; the error is
+// the request's, when the client left while waiting for a repeat's result.
+func (h *Handler) scrapeAll(r *http.Request, cli *scraper.Client, urls []string, selector string) (scraper.ScrapeRun, bool, error) {
+	if h.debounce == nil {
+		return cli.ScrapeAll(urls, selector), false, nil
+	}
+	return h.debounce.Do(r.Context(), web.DebounceKey(r), func() scraper.ScrapeRun {
+		return cli.ScrapeAll(urls, selector)
+	})
+}
@@ -0,0 +1,234 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/GhanshyamJha05/WEB_SCRAPPER_Using-GO/pkg/scraper"
+	"github.com/GhanshyamJha05/WEB_SCRAPPER_Using-GO/pkg/web"
+)
+
+// maxSnapshots bounds how many scrapes (url, selector and options) /api/diff
+// remembers.
+// The pair whose last run is oldest is evicted when a new pair arrives at
+// the limit.
+const maxSnapshots = 100
+
+// maxSnapshotRuns bounds the runs kept per pair for the since parameter.
+// A since older than all of them finds no baseline.
+const maxSnapshotRuns = 20
+
+// snapshot is one successful run of a scrape, see snapshotKey.
+type snapshot struct {
+	Results []scraper.ScrapeResult `json:"results"`
+	At      time.Time              `json:"at"`
+}
+
+// DiffResponse is the JSON body for GET /api/diff.
+type DiffResponse struct {
+	URL        string     `json:"url"`
+	Selector   string     `json:"selector"`
+	ScrapedAt  time.Time  `json:"scraped_at"`
+	Since      *time.Time `json:"since,omitempty"`       // the since parameter, when given
+	PreviousAt *time.Time `json:"previous_at,omitempty"` // the run compared against; nil when there is none
+	FirstRun   bool       `json:"first_run"`             // no earlier snapshot: every result counts as added
+	Total      int        `json:"total"`                 // results in the current run
+	scraper.ResultDiff
+}
+
+// Diff handles GET /api/diff?url=&selector=[&since=]. It scrapes the page,
+// compares the results with the previous run of the same scrape (url,
+// selector and options, see snapshotKey), and stores the new run for next
+// time. With since, an RFC 3339 time, the
+// comparison is against the newest run at or before it instead, so a poller
+// passing its last scraped_at gets everything added since then even if
+// other clients ran the diff in between.
+func (h *Handler) Diff(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	pageURL := strings.TrimSpace(q.Get("url"))
+	selector := strings.TrimSpace(q.Get("selector"))
+	if pageURL == "" {
+		http.Error(w, "url is required", http.StatusBadRequest)
+		return
+	}
+	if selector == "" {
+		selector = h.recommendedSelector(pageURL)
+	}
+	if selector == "" {
+		http.Error(w, "CSS selector is required", http.StatusBadRequest)
+		return
+	}
+	opts, err := scraper.ParseOptions(q)
+	if err == nil {
+		err = h.cli.CheckOptions(opts)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var since time.Time
+	if s := strings.TrimSpace(q.Get("since")); s != "" {
+		if since, err = time.Parse(time.RFC3339, s); err != nil {
+			http.Error(w, "since must be an RFC 3339 time such as 2026-04-17T12:00:00Z", http.StatusBadRequest)
+			return
+		}
+	}
+	if err := h.checkURLs(r.Context(), []string{pageURL}); err != nil {
+		http.Error(w, web.Capitalize(err.Error()), http.StatusForbidden)
+		return
+	}
+
+	h.addToVisited(pageURL)
+
+	results, errs := h.cli.WithOptions(opts).ScrapeWithWorkerPool([]string{pageURL}, selector)
+	if len(errs) > 0 {
+		// Keep the old baseline: a failed fetch is not "everything was removed".
+		http.Error(w, "Scrape failed: "+errs[0].Error(), http.StatusBadGateway)
+		return
+	}
+
+	now := time.Now().UTC()
+	history := h.addSnapshot(snapshotKey(pageURL, selector, q), snapshot{Results: results, At: now})
+	if h.snapFile != "" {
+		h.saveSnapshots()
+	}
+
+	resp := DiffResponse{
+		URL:       pageURL,
+		Selector:  selector,
+		ScrapedAt: now,
+		FirstRun:  len(history) == 0,
+		Total:     len(results),
+	}
+	if !since.IsZero() {
+		resp.Since = &since
+	}
+	prev, ok := baseline(history, since)
+	if ok {
+		resp.PreviousAt = &prev.At
+	}
+	resp.ResultDiff = scraper.Diff(prev.Results, results)
+	writeJSON(w, resp)
+}
+
+// snapshotKey identifies the runs of a diff by url, selector and the other
+// query parameters, so a scrape with different attrs or a filter is never
+// compared with another's results. Parameters that don't change what is
+// scraped are left out, and the rest are hashed so login passwords and
+// header values stay out of Config.SnapshotFile. Without options the key is
+// url and selector alone.
+func snapshotKey(pageURL, selector string, q url.Values) string {
+	opts := url.Values{}
+	for k, vs := range q {
+		switch k {
+		case "url", "selector", "since", "format", "copy":
+			continue
+		}
+		for _, v := range vs {
+			if v = strings.TrimSpace(v); v != "" {
+				opts.Add(k, v)
+			}
+		}
+	}
+	key := pageURL + "\x00" + selector
+	if len(opts) == 0 {
+		return key
+	}
+	sum := sha256.Sum256([]byte(opts.Encode())) // Encode sorts by name
+	return key + "\x00" + hex.EncodeToString(sum[:8])
+}
+
+// baseline picks the run to compare against from history, oldest first:
+// the newest run at or before since, or the last run when since is zero.
+func baseline(history []snapshot, since time.Time) (snapshot, bool) {
+	for i := len(history) - 1; i >= 0; i-- {
+		if since.IsZero() || !history[i].At.After(since) {
+			return history[i], true
+		}
+	}
+	return snapshot{}, false
+}
+
+// addSnapshot appends next to the runs stored under key and returns the
+// runs before it, oldest first.
+func (h *Handler) addSnapshot(key string, next snapshot) []snapshot {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.snapshots == nil {
+		h.snapshots = make(map[string][]snapshot)
+	}
+
+	prev, ok := h.snapshots[key]
+	if !ok && len(h.snapshots) >= maxSnapshots {
+		var oldest string
+		var oldestAt time.Time
+		for k, runs := range h.snapshots {
+			if at := runs[len(runs)-1].At; oldest == "" || at.Before(oldestAt) {
+				oldest, oldestAt = k, at
+			}
+		}
+		delete(h.snapshots, oldest)
+	}
+	// Appending never overwrites the runs returned to earlier callers.
+	runs := append(prev, next)
+	if len(runs) > maxSnapshotRuns {
+		runs = runs[len(runs)-maxSnapshotRuns:]
+	}
+	h.snapshots[key] = runs
+	return runs[:len(runs)-1]
+}
+
+// loadSnapshots reads the snapshots saved in path. A missing file is an
+// empty store.
+func (h *Handler) loadSnapshots(path string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var stored map[string][]snapshot
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return err
+	}
+	for key, runs := range stored {
+		if len(runs) == 0 {
+			delete(stored, key)
+		}
+	}
+	h.mu.Lock()
+	h.snapshots = stored
+	h.mu.Unlock()
+	return nil
+}
+
+// saveSnapshots writes every snapshot to h.snapFile, through a temporary
+// file so a crash never leaves it half written. Saves are serialized and each
+// writes the store as it is then, so the file ends up with the latest state.
+func (h *Handler) saveSnapshots() {
+	h.saveMu.Lock()
+	defer h.saveMu.Unlock()
+
+	h.mu.Lock()
+	data, err := json.Marshal(h.snapshots)
+	h.mu.Unlock()
+	if err == nil {
+		tmp := h.snapFile + ".tmp"
+		if err = os.WriteFile(tmp, data, 0600); err == nil {
+			err = os.Rename(tmp, h.snapFile)
+		}
+	}
+	if err != nil {
+		log.Printf("saving snapshots: %v", err)
+	}
+}
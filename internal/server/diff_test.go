@@ -0,0 +1,54 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestDiffKeyedByOptions(t *testing.T) {
+	site, _ := jobSite(t)
+	h := newTestHandler(jobsConfig())
+	defer h.jobs.stop()
+
+	diff := func(extra string) DiffResponse {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", "/api/diff?selector=a&url="+url.QueryEscape(site.URL)+extra, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("diff%s: status %d: %s", extra, rec.Code, rec.Body)
+		}
+		var resp DiffResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	for _, tt := range []struct {
+		extra string
+		first bool
+	}{
+		{"", true},
+		{"&format=json", false},      // format doesn't change the scrape
+		{"&attrs=href", true},        // options start their own history
+		{"&attrs=href&lang=", false}, // blank parameters are ignored
+		{"&lang=fr&attrs=href", true},
+		{"&attrs=href&lang=fr", false}, // order doesn't matter
+	} {
+		if got := diff(tt.extra).FirstRun; got != tt.first {
+			t.Errorf("diff%s: first_run %v, want %v", tt.extra, got, tt.first)
+		}
+	}
+
+	// Options the client refuses are refused here too, as by Index.
+	for _, extra := range []string{"&insecure=true", "&render=true"} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", "/api/diff?selector=a&url="+url.QueryEscape(site.URL)+extra, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("diff%s: status %d, want 400", extra, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package server
+
+import (
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/GhanshyamJha05/WEB_SCRAPPER_Using-GO/pkg/scraper"
+	"github.com/GhanshyamJha05/WEB_SCRAPPER_Using-GO/pkg/web"
+)
+
+// Environment variables only the standalone server reads, on top of those
+// in pkg/web and scraper.ConfigFromEnv.
+const (
+	EnvWarmInterval = "SCRAPER_WARM_INTERVAL"         // Config.WarmInterval
+	EnvDashWorkers  = "SCRAPER_DASHBOARD_CONCURRENCY" // Config.DashboardConcurrency
+	EnvDashTimeout  = "SCRAPER_DASHBOARD_TIMEOUT"     // Config.DashboardTimeout, a Go duration
+	EnvMaxScrapes   = "SCRAPER_MAX_CONCURRENT"        // Config.MaxConcurrent; "0" is unlimited
+	EnvQueueSize    = "SCRAPER_QUEUE_SIZE"            // Config.QueueSize; defaults to DefaultQueueSize
+	EnvSnapshotFile = "SCRAPER_SNAPSHOT_FILE"         // Config.SnapshotFile
+	EnvJobsFile     = "SCRAPER_JOBS_FILE"             // Config.JobsFile
+	EnvReadTimeout  = "SCRAPER_READ_TIMEOUT"          // Config.ReadTimeout, a Go duration; "0" disables
+	EnvWriteTimeout = "SCRAPER_WRITE_TIMEOUT"         // Config.WriteTimeout, a Go duration; "0" disables
+	EnvIdleTimeout  = "SCRAPER_IDLE_TIMEOUT"          // Config.IdleTimeout, a Go duration; "0" disables
+)
+
+// DefaultQueueSize is how many requests wait for a scrape slot when
+// SCRAPER_QUEUE_SIZE is unset.
+const DefaultQueueSize = 20
+
+// ConfigFromEnv returns the Config of the standalone server from the
+// SCRAPER_* environment variables: Scraper from scraper.ConfigFromEnv, the
+// UI settings from pkg/web and the rest from the Env* variables above.
+// Unset or malformed values keep the default. Addr, the templates and
+// Build are left for the caller.
+func ConfigFromEnv() Config {
+	cfg := Config{
+		Scraper:          scraper.ConfigFromEnv(),
+		HistorySize:      web.HistorySizeFromEnv(),
+		HistoryCanonical: web.HistoryCanonicalFromEnv(),
+		RenderLimit:      web.RenderLimitFromEnv(),
+		RenderWarn:       web.RenderWarnFromEnv(),
+		Debounce:         web.DebounceFromEnv(),
+		DefaultFormat:    web.DefaultFormatFromEnv(),
+		APISecret:        os.Getenv(web.EnvAPISecret),
+		SnapshotFile:     os.Getenv(EnvSnapshotFile),
+		JobsFile:         os.Getenv(EnvJobsFile),
+		QueueSize:        DefaultQueueSize,
+		ReadTimeout:      envTimeout(EnvReadTimeout),
+		WriteTimeout:     envTimeout(EnvWriteTimeout),
+		IdleTimeout:      envTimeout(EnvIdleTimeout),
+	}
+	if cfg.RenderLimit == 0 {
+		cfg.RenderLimit = -1 // the variable's "render all" is Config's negative
+	}
+	if d, err := time.ParseDuration(os.Getenv(EnvWarmInterval)); err == nil && d > 0 {
+		cfg.WarmInterval = d
+	}
+	if n, err := strconv.Atoi(os.Getenv(EnvDashWorkers)); err == nil && n > 0 {
+		cfg.DashboardConcurrency = n
+	}
+	if d, err := time.ParseDuration(os.Getenv(EnvDashTimeout)); err == nil && d > 0 {
+		cfg.DashboardTimeout = d
+	}
+	if n, err := strconv.Atoi(os.Getenv(EnvMaxScrapes)); err == nil && n > 0 {
+		cfg.MaxConcurrent = n
+	}
+	if n, err := strconv.Atoi(os.Getenv(EnvQueueSize)); err == nil && n >= 0 {
+		cfg.QueueSize = n
+	}
+	return cfg
+}
+
+// envTimeout reads an http.Server timeout variable in the convention of
+// Config: 0 when unset or malformed (the default), negative for "0" (none).
+func envTimeout(name string) time.Duration {
+	d, err := time.ParseDuration(os.Getenv(name))
+	switch {
+	case err != nil || d < 0:
+		return 0
+	case d == 0:
+		return -1
+	}
+	return d
+}
@@ -0,0 +1,320 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/GhanshyamJha05/WEB_SCRAPPER_Using-GO/pkg/scraper"
+	"github.com/GhanshyamJha05/WEB_SCRAPPER_Using-GO/pkg/web"
+)
+
+// minJobInterval is the shortest schedule POST /jobs accepts, so a job
+// can't hammer a site.
+const minJobInterval = time.Minute
+
+// maxJobs bounds how many scheduled jobs the server keeps.
+const maxJobs = 50
+
+// Job is a scrape the server repeats on a schedule, defined with POST /jobs.
+type Job struct {
+	ID       string    `json:"id"`
+	URL      string    `json:"url"`
+	Selector string    `json:"selector"`
+	Interval string    `json:"interval"` // a Go duration such as "15m"
+	Created  time.Time `json:"created"`
+}
+
+// JobResults is the JSON body for GET /jobs/{id}/results: the latest run
+// of a job. Results is empty until the first run finishes.
+type JobResults struct {
+	Job
+	Runs       int                    `json:"runs"`                  // runs finished so far
+	LastRun    *time.Time             `json:"last_run,omitempty"`    // nil until the first run finishes
+	NextRun    time.Time              `json:"next_run"`              // when the job runs again
+	DurationMs int64                  `json:"duration_ms,omitempty"` // time the last run took
+	Error      string                 `json:"error,omitempty"`       // why the last run failed
+	Results    []scraper.ScrapeResult `json:"results"`
+}
+
+// scheduledJob is a Job with its schedule and the state of its last run,
+// guarded by jobRegistry.mu.
+type scheduledJob struct {
+	Job
+	every  time.Duration
+	cancel context.CancelFunc // stops the job's goroutine
+	latest JobResults
+}
+
+// jobRegistry holds the scheduled jobs, each run by its own goroutine
+// until the job is deleted or the registry stopped.
+type jobRegistry struct {
+	ctx    context.Context // canceled by stop
+	stop   context.CancelFunc
+	file   string // Config.JobsFile; "" keeps jobs in memory only
+	mu     sync.Mutex
+	jobs   map[string]*scheduledJob
+	saveMu sync.Mutex // serializes writes of file
+}
+
+func newJobRegistry() *jobRegistry {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &jobRegistry{ctx: ctx, stop: cancel, jobs: map[string]*scheduledJob{}}
+}
+
+// Jobs handles the job registry: POST /jobs defines a job from a JSON
+// {url, selector, interval} and answers 201 with it, GET /jobs lists the
+// jobs, GET /jobs/{id}/results returns the latest run of one as JobResults
+// and DELETE /jobs/{id} stops and removes it. Jobs run at once and then
+// every interval, at least a minute; they stop when the server shuts down.
+func (h *Handler) Jobs(w http.ResponseWriter, r *http.Request) {
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/jobs"), "/")
+	id, sub, _ := strings.Cut(rest, "/")
+	switch {
+	case id == "" && r.Method == http.MethodPost:
+		h.createJob(w, r)
+	case id == "" && r.Method == http.MethodGet:
+		writeJSON(w, h.jobs.list())
+	case id != "" && sub == "results" && r.Method == http.MethodGet:
+		res, ok := h.jobs.results(id)
+		if !ok {
+			http.Error(w, "Unknown job "+id, http.StatusNotFound)
+			return
+		}
+		writeJSON(w, res)
+	case id != "" && sub == "" && r.Method == http.MethodDelete:
+		if !h.jobs.remove(id) {
+			http.Error(w, "Unknown job "+id, http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case id != "" && sub != "" && sub != "results":
+		http.NotFound(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// createJob handles POST /jobs.
+func (h *Handler) createJob(w http.ResponseWriter, r *http.Request) {
+	var j Job
+	if err := json.NewDecoder(r.Body).Decode(&j); err != nil {
+		http.Error(w, "Invalid JSON payload", http.StatusBadRequest)
+		return
+	}
+	j.URL = strings.TrimSpace(j.URL)
+	j.Selector = strings.TrimSpace(j.Selector)
+	if j.URL == "" {
+		http.Error(w, "url is required", http.StatusBadRequest)
+		return
+	}
+	if j.Selector == "" {
+		j.Selector = h.recommendedSelector(j.URL)
+	}
+	if j.Selector == "" {
+		http.Error(w, "CSS selector is required", http.StatusBadRequest)
+		return
+	}
+	every, err := parseJobInterval(j.Interval)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := h.checkURLs(r.Context(), []string{j.URL}); err != nil {
+		http.Error(w, web.Capitalize(err.Error()), http.StatusForbidden)
+		return
+	}
+
+	b := make([]byte, 8)
+	rand.Read(b)
+	j.ID = hex.EncodeToString(b)
+	j.Created = time.Now().UTC()
+	if err := h.jobs.add(h, j, every); err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	h.jobs.save()
+	body, err := json.Marshal(j)
+	if err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/jobs/"+j.ID+"/results")
+	w.WriteHeader(http.StatusCreated)
+	w.Write(append(body, '\n'))
+}
+
+// parseJobInterval parses a Job's interval, which must be at least
+// minJobInterval.
+func parseJobInterval(raw string) (time.Duration, error) {
+	if strings.TrimSpace(raw) == "" {
+		return 0, errors.New("interval is required, e.g. \"15m\"")
+	}
+	d, err := time.ParseDuration(strings.TrimSpace(raw))
+	if err != nil {
+		return 0, fmt.Errorf("invalid interval %q: use a Go duration such as \"15m\"", raw)
+	}
+	if d < minJobInterval {
+		return 0, fmt.Errorf("interval %s is too short: the minimum is %s", d, minJobInterval)
+	}
+	return d, nil
+}
+
+// add registers j and starts its goroutine. It fails once maxJobs are
+// registered.
+func (reg *jobRegistry) add(h *Handler, j Job, every time.Duration) error {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	if len(reg.jobs) >= maxJobs {
+		return fmt.Errorf("too many jobs: the limit is %d", maxJobs)
+	}
+	ctx, cancel := context.WithCancel(reg.ctx)
+	sj := &scheduledJob{Job: j, every: every, cancel: cancel}
+	sj.latest = JobResults{Job: j, NextRun: time.Now().UTC(), Results: []scraper.ScrapeResult{}}
+	reg.jobs[j.ID] = sj
+	go h.runJob(ctx, sj)
+	return nil
+}
+
+// remove stops and deletes the job id, reporting whether it existed.
+func (reg *jobRegistry) remove(id string) bool {
+	reg.mu.Lock()
+	sj, ok := reg.jobs[id]
+	if ok {
+		sj.cancel()
+		delete(reg.jobs, id)
+	}
+	reg.mu.Unlock()
+	if ok {
+		reg.save()
+	}
+	return ok
+}
+
+// list returns every job, oldest first.
+func (reg *jobRegistry) list() []Job {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	jobs := make([]Job, 0, len(reg.jobs))
+	for _, sj := range reg.jobs {
+		jobs = append(jobs, sj.Job)
+	}
+	slices.SortFunc(jobs, func(a, b Job) int { return a.Created.Compare(b.Created) })
+	return jobs
+}
+
+// results returns the latest run of the job id.
+func (reg *jobRegistry) results(id string) (JobResults, bool) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	sj, ok := reg.jobs[id]
+	if !ok {
+		return JobResults{}, false
+	}
+	return sj.latest, true
+}
+
+// runJob scrapes sj at once and then on every tick of its interval, until
+// ctx is canceled. A scrape in progress is abandoned then.
+func (h *Handler) runJob(ctx context.Context, sj *scheduledJob) {
+	ticker := time.NewTicker(sj.every)
+	defer ticker.Stop()
+	for {
+		start := time.Now()
+		done := h.countScrape()
+		p := <-h.cli.ScrapeStreamedContext(ctx, []string{sj.URL}, sj.Selector)
+		done()
+		if ctx.Err() != nil {
+			return
+		}
+
+		h.jobs.mu.Lock()
+		res := &sj.latest
+		res.Runs++
+		finished := time.Now().UTC()
+		res.LastRun = &finished
+		res.NextRun = start.Add(sj.every).UTC()
+		res.DurationMs = time.Since(start).Milliseconds()
+		res.Error = ""
+		if p.Err != nil {
+			res.Error = p.Err.Error()
+			log.Printf("job %s: %s: %v", sj.ID, sj.URL, p.Err)
+		} else {
+			res.Results = p.Items
+			if res.Results == nil {
+				res.Results = []scraper.ScrapeResult{}
+			}
+		}
+		h.jobs.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// loadJobs reads the jobs saved in reg.file and starts them. A missing
+// file is an empty registry; a job whose interval no longer parses, or
+// whose URL the host policy now refuses, is skipped with a log line.
+func (h *Handler) loadJobs() error {
+	data, err := os.ReadFile(h.jobs.file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var stored []Job
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return err
+	}
+	for _, j := range stored {
+		every, err := parseJobInterval(j.Interval)
+		if err == nil {
+			err = h.checkURLs(context.Background(), []string{j.URL})
+		}
+		if err == nil {
+			err = h.jobs.add(h, j, every)
+		}
+		if err != nil {
+			log.Printf("%s: job %s: %v; skipped", h.jobs.file, j.ID, err)
+		}
+	}
+	return nil
+}
+
+// save writes every job definition to reg.file, through a temporary file
+// so a crash never leaves it half written. Results are not saved: a
+// restarted job runs again at once.
+func (reg *jobRegistry) save() {
+	if reg.file == "" {
+		return
+	}
+	reg.saveMu.Lock()
+	defer reg.saveMu.Unlock()
+
+	data, err := json.Marshal(reg.list())
+	if err == nil {
+		tmp := reg.file + ".tmp"
+		if err = os.WriteFile(tmp, data, 0600); err == nil {
+			err = os.Rename(tmp, reg.file)
+		}
+	}
+	if err != nil {
+		log.Printf("saving jobs: %v", err)
+	}
+}
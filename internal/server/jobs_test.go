@@ -0,0 +1,184 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"html/template"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/GhanshyamJha05/WEB_SCRAPPER_Using-GO/pkg/scraper"
+)
+
+// jobSite serves one link and counts the page fetches, robots.txt aside.
+func jobSite(t *testing.T) (*httptest.Server, *atomic.Int32) {
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			http.NotFound(w, r)
+			return
+		}
+		hits.Add(1)
+		_, _ = io.WriteString(w, `<a class="item" href="/a">Alpha</a>`)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &hits
+}
+
+// jobsConfig is the scraper config of the job tests, without the crawl
+// delay so runs are quick.
+func jobsConfig() scraper.Config {
+	cfg := scraper.DefaultConfig()
+	cfg.CrawlDelay = 0
+	return cfg
+}
+
+// waitRuns waits until the job id has finished at least n runs and returns
+// its latest results.
+func waitRuns(t *testing.T, h *Handler, id string, n int) JobResults {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		res, ok := h.jobs.results(id)
+		if !ok {
+			t.Fatalf("job %s is not registered", id)
+		}
+		if res.Runs >= n {
+			return res
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job %s: %d runs after 5s, want %d", id, res.Runs, n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func postJob(h http.Handler, body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("POST", "/jobs", strings.NewReader(body)))
+	return rec
+}
+
+func TestJobsAPI(t *testing.T) {
+	site, _ := jobSite(t)
+	h := newTestHandler(jobsConfig())
+	defer h.jobs.stop()
+
+	for _, body := range []string{
+		`{`,
+		`{"selector":"a","interval":"1m"}`,
+		`{"url":"` + site.URL + `","selector":"a"}`,
+		`{"url":"` + site.URL + `","selector":"a","interval":"30s"}`,
+		`{"url":"` + site.URL + `","selector":"a","interval":"soon"}`,
+	} {
+		if rec := postJob(h, body); rec.Code != http.StatusBadRequest {
+			t.Errorf("POST %s: status %d, want 400", body, rec.Code)
+		}
+	}
+
+	rec := postJob(h, `{"url":"`+site.URL+`","selector":".item","interval":"1m"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("POST: status %d, want 201: %s", rec.Code, rec.Body)
+	}
+	var j Job
+	if err := json.Unmarshal(rec.Body.Bytes(), &j); err != nil || j.ID == "" {
+		t.Fatalf("POST body %q: %v", rec.Body, err)
+	}
+	if loc := rec.Header().Get("Location"); loc != "/jobs/"+j.ID+"/results" {
+		t.Errorf("Location = %q", loc)
+	}
+
+	waitRuns(t, h, j.ID, 1)
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/jobs/"+j.ID+"/results", nil))
+	var res JobResults
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatalf("results %q: %v", rec.Body, err)
+	}
+	if res.Runs != 1 || res.Error != "" || len(res.Results) != 1 || res.Results[0].Title != "Alpha" {
+		t.Errorf("results = %+v, want one run finding Alpha", res)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/jobs", nil))
+	var jobs []Job
+	if err := json.Unmarshal(rec.Body.Bytes(), &jobs); err != nil || len(jobs) != 1 || jobs[0].ID != j.ID {
+		t.Errorf("GET /jobs = %s, %v; want the one job", rec.Body, err)
+	}
+
+	for _, want := range []int{http.StatusNoContent, http.StatusNotFound} {
+		rec = httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("DELETE", "/jobs/"+j.ID, nil))
+		if rec.Code != want {
+			t.Errorf("DELETE: status %d, want %d", rec.Code, want)
+		}
+	}
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/jobs/"+j.ID+"/results", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("results after DELETE: status %d, want 404", rec.Code)
+	}
+}
+
+func TestJobTickerAndStop(t *testing.T) {
+	site, hits := jobSite(t)
+	h := newTestHandler(jobsConfig())
+
+	if err := h.jobs.add(h, Job{ID: "tick", URL: site.URL, Selector: "a"}, 30*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	waitRuns(t, h, "tick", 3)
+
+	// Once the registry is stopped, as on server shutdown, no run starts.
+	h.jobs.stop()
+	time.Sleep(50 * time.Millisecond)
+	n := hits.Load()
+	time.Sleep(150 * time.Millisecond)
+	if got := hits.Load(); got != n {
+		t.Errorf("%d fetches after stop, want none", got-n)
+	}
+}
+
+func TestJobsFile(t *testing.T) {
+	site, _ := jobSite(t)
+	file := filepath.Join(t.TempDir(), "jobs.json")
+	tmpl := template.Must(template.New("index.html").Parse("ok"))
+
+	srv := NewServer(Config{Template: tmpl, Scraper: jobsConfig(), JobsFile: file})
+	rec := postJob(srv.Handler, `{"url":"`+site.URL+`","selector":"a","interval":"1h"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("POST: status %d, want 201: %s", rec.Code, rec.Body)
+	}
+	if err := srv.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(file); err != nil {
+		t.Fatalf("jobs file not written: %v", err)
+	}
+
+	// A restarted server picks the job up again and runs it.
+	srv = NewServer(Config{Template: tmpl, Scraper: jobsConfig(), JobsFile: file})
+	h := srv.Handler.(*Handler)
+	jobs := h.jobs.list()
+	if len(jobs) != 1 || jobs[0].URL != site.URL {
+		t.Fatalf("reloaded jobs = %+v, want the saved one", jobs)
+	}
+	waitRuns(t, h, jobs[0].ID, 1)
+	srv.Shutdown(context.Background())
+
+	// A job whose host the policy now refuses is not restarted.
+	cfg := jobsConfig()
+	cfg.HostPolicy.Deny = []string{"127.0.0.1"}
+	srv = NewServer(Config{Template: tmpl, Scraper: cfg, JobsFile: file})
+	defer srv.Shutdown(context.Background())
+	if jobs := srv.Handler.(*Handler).jobs.list(); len(jobs) != 0 {
+		t.Errorf("jobs loaded despite the deny list: %+v", jobs)
+	}
+}
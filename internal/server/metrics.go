@@ -0,0 +1,21 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Metrics handles GET /metrics in the Prometheus text format: the scrapes
+// running now and, with a request queue, the requests waiting for a slot.
+func (h *Handler) Metrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	fmt.Fprintf(w, "# HELP scraper_in_flight_scrapes Scrapes running now.\n")
+	fmt.Fprintf(w, "# TYPE scraper_in_flight_scrapes gauge\n")
+	fmt.Fprintf(w, "scraper_in_flight_scrapes %d\n", h.inFlight.Load())
+	if h.queue != nil {
+		st, _ := h.queue.status("")
+		fmt.Fprintf(w, "# HELP scraper_queued_requests Scrape requests waiting for a slot.\n")
+		fmt.Fprintf(w, "# TYPE scraper_queued_requests gauge\n")
+		fmt.Fprintf(w, "scraper_queued_requests %d\n", st.Queued)
+	}
+}
@@ -0,0 +1,199 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// errQueueFull is returned by scrapeQueue.acquire when every slot is busy and
+// the waiting list is at capacity.
+var errQueueFull = errors.New("server busy: scrape queue is full, try again later")
+
+// scrapeQueue limits how many scrapes run at once. Requests over the limit
+// wait in FIFO order, up to a fixed number of them, and take a slot as soon
+// as one is released.
+type scrapeQueue struct {
+	mu      sync.Mutex
+	slots   int
+	size    int
+	active  int            // slots in use
+	running map[string]int // slots in use per id; clients may reuse an id
+	waiting []*queueTicket // first in line first
+}
+
+type queueTicket struct {
+	id    string
+	ready chan struct{} // closed when the ticket is handed a slot
+}
+
+// QueueStatus is the JSON body for GET /queue/{id}.
+type QueueStatus struct {
+	ID       string `json:"id"`
+	State    string `json:"state"`              // "queued" or "running"
+	Position int    `json:"position,omitempty"` // 1 is next in line; only while queued
+	Running  int    `json:"running"`            // scrapes in progress
+	Queued   int    `json:"queued"`             // requests waiting
+	Slots    int    `json:"slots"`              // Config.MaxConcurrent
+}
+
+func newScrapeQueue(slots, size int) *scrapeQueue {
+	return &scrapeQueue{slots: slots, size: size, running: map[string]int{}}
+}
+
+// acquire blocks until id holds a slot and returns the func that releases
+// it. It fails at once with errQueueFull, or with ctx's error if the client
+// goes away while waiting.
+func (q *scrapeQueue) acquire(ctx context.Context, id string) (func(), error) {
+	release := func() { q.release(id) }
+
+	q.mu.Lock()
+	if q.active < q.slots && len(q.waiting) == 0 {
+		q.active++
+		q.running[id]++
+		q.mu.Unlock()
+		return release, nil
+	}
+	if len(q.waiting) >= q.size {
+		q.mu.Unlock()
+		return nil, errQueueFull
+	}
+	t := &queueTicket{id: id, ready: make(chan struct{})}
+	q.waiting = append(q.waiting, t)
+	q.mu.Unlock()
+
+	select {
+	case <-t.ready:
+		return release, nil
+	case <-ctx.Done():
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		if i := slices.Index(q.waiting, t); i >= 0 {
+			q.waiting = slices.Delete(q.waiting, i, i+1)
+			return nil, ctx.Err()
+		}
+		// Handed a slot just as the client left; pass it on.
+		q.handOff(id)
+		return nil, ctx.Err()
+	}
+}
+
+func (q *scrapeQueue) release(id string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.handOff(id)
+}
+
+// handOff frees id's slot and gives it to the first waiting ticket. The
+// caller holds q.mu.
+func (q *scrapeQueue) handOff(id string) {
+	q.active--
+	if q.running[id]--; q.running[id] <= 0 {
+		delete(q.running, id)
+	}
+	if len(q.waiting) > 0 && q.active < q.slots {
+		next := q.waiting[0]
+		q.waiting = q.waiting[1:]
+		q.active++
+		q.running[next.id]++
+		close(next.ready)
+	}
+}
+
+// status reports where id is, or false when it is neither queued nor running.
+func (q *scrapeQueue) status(id string) (QueueStatus, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	st := QueueStatus{ID: id, Running: q.active, Queued: len(q.waiting), Slots: q.slots}
+	if q.running[id] > 0 {
+		st.State = "running"
+		return st, true
+	}
+	for i, t := range q.waiting {
+		if t.id == id {
+			st.State = "queued"
+			st.Position = i + 1
+			return st, true
+		}
+	}
+	return st, false
+}
+
+// queueID returns the id the client chose for this request, from the
+// queueId parameter or the X-Queue-Id header, so it can poll /queue/{id}
+// while the request waits. Without one a random id is made up; it is sent
+// back in X-Queue-Id, but only with the response, so such a request can't
+// be polled.
+func queueID(r *http.Request) string {
+	if id := r.URL.Query().Get("queueId"); id != "" {
+		return id
+	}
+	if id := r.Header.Get("X-Queue-Id"); id != "" {
+		return id
+	}
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// queued runs next once the request holds a scrape slot. Without a queue
+// (Config.MaxConcurrent is 0) it runs next straight away. A full queue gets
+// 503 with Retry-After. h.inFlight counts next while it runs.
+func (h *Handler) queued(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	if h.queue == nil {
+		h.track(w, r, next)
+		return
+	}
+	id := queueID(r)
+	w.Header().Set("X-Queue-Id", id)
+	release, err := h.queue.acquire(r.Context(), id)
+	if errors.Is(err, errQueueFull) {
+		w.Header().Set("Retry-After", "5")
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	if err != nil {
+		return // client gave up while queued
+	}
+	defer release()
+	h.track(w, r, next)
+}
+
+// track runs next counted in h.inFlight. The deferred decrement keeps the
+// count right when next panics and net/http recovers.
+func (h *Handler) track(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	defer h.countScrape()()
+	next(w, r)
+}
+
+// countScrape counts a scrape in h.inFlight until the returned func is
+// called. Scrapes that aren't requests, the dashboard warmer's and the
+// jobs', use it directly.
+func (h *Handler) countScrape() (done func()) {
+	h.inFlight.Add(1)
+	return func() { h.inFlight.Add(-1) }
+}
+
+// Queue handles GET /queue/{id}, reporting the place of a waiting request
+// as QueueStatus JSON. Unknown ids, including requests that have already
+// finished, get 404.
+func (h *Handler) Queue(w http.ResponseWriter, r *http.Request) {
+	if h.queue == nil {
+		http.Error(w, "Request queue is disabled", http.StatusNotFound)
+		return
+	}
+	id := strings.TrimPrefix(r.URL.Path, "/queue/")
+	st, ok := h.queue.status(id)
+	if !ok {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		writeJSON(w, st)
+		return
+	}
+	writeJSON(w, st)
+}
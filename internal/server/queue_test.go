@@ -0,0 +1,208 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// acquireAsync starts q.acquire(ctx, id) and returns a channel receiving its
+// release func, or nil when it failed, once it returns.
+func acquireAsync(q *scrapeQueue, ctx context.Context, id string) <-chan func() {
+	got := make(chan func(), 1)
+	go func() {
+		release, err := q.acquire(ctx, id)
+		if err != nil {
+			release = nil
+		}
+		got <- release
+	}()
+	return got
+}
+
+// waitStatus waits until id has state in q.
+func waitStatus(t *testing.T, q *scrapeQueue, id, state string) QueueStatus {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		st, ok := q.status(id)
+		if ok && st.State == state {
+			return st
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%s: status %+v (known %v), want %s", id, st, ok, state)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// mustAcquire takes a slot for id at once.
+func mustAcquire(t *testing.T, q *scrapeQueue, id string) func() {
+	t.Helper()
+	release, err := q.acquire(context.Background(), id)
+	if err != nil {
+		t.Fatalf("acquire %s: %v", id, err)
+	}
+	return release
+}
+
+func TestScrapeQueue(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, q *scrapeQueue)
+	}{
+		{"free slot", func(t *testing.T, q *scrapeQueue) {
+			release := mustAcquire(t, q, "a")
+			if st := waitStatus(t, q, "a", "running"); st.Running != 1 || st.Queued != 0 {
+				t.Errorf("status = %+v", st)
+			}
+			release()
+			if _, ok := q.status("a"); ok {
+				t.Error("released id still known")
+			}
+		}},
+		{"fifo hand-off", func(t *testing.T, q *scrapeQueue) {
+			release := mustAcquire(t, q, "a")
+			b := acquireAsync(q, context.Background(), "b")
+			waitStatus(t, q, "b", "queued")
+			c := acquireAsync(q, context.Background(), "c")
+			if st := waitStatus(t, q, "c", "queued"); st.Position != 2 {
+				t.Errorf("c position = %d, want 2", st.Position)
+			}
+
+			release()
+			releaseB := <-b
+			if releaseB == nil {
+				t.Fatal("b failed to get the freed slot")
+			}
+			if st := waitStatus(t, q, "c", "queued"); st.Position != 1 {
+				t.Errorf("c position after a = %d, want 1", st.Position)
+			}
+			releaseB()
+			if releaseC := <-c; releaseC == nil {
+				t.Fatal("c failed to get the freed slot")
+			} else {
+				releaseC()
+			}
+		}},
+		{"full", func(t *testing.T, q *scrapeQueue) {
+			defer mustAcquire(t, q, "a")()
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			for _, id := range []string{"b", "c"} {
+				acquireAsync(q, ctx, id)
+				waitStatus(t, q, id, "queued")
+			}
+			if _, err := q.acquire(context.Background(), "d"); !errors.Is(err, errQueueFull) {
+				t.Errorf("acquire over the queue size = %v, want errQueueFull", err)
+			}
+		}},
+		{"cancel while waiting", func(t *testing.T, q *scrapeQueue) {
+			release := mustAcquire(t, q, "a")
+			ctx, cancel := context.WithCancel(context.Background())
+			b := acquireAsync(q, ctx, "b")
+			waitStatus(t, q, "b", "queued")
+			cancel()
+			if <-b != nil {
+				t.Fatal("canceled waiter got a slot")
+			}
+			if _, ok := q.status("b"); ok {
+				t.Error("canceled waiter still queued")
+			}
+			release()
+			if q.active != 0 {
+				t.Errorf("active = %d after all released, want 0", q.active)
+			}
+		}},
+		{"cancel as handed a slot", func(t *testing.T, q *scrapeQueue) {
+			mustAcquire(t, q, "a") // released by hand below
+			ctx, cancel := context.WithCancel(context.Background())
+			b := acquireAsync(q, ctx, "b")
+			waitStatus(t, q, "b", "queued")
+			c := acquireAsync(q, context.Background(), "c")
+			waitStatus(t, q, "c", "queued")
+
+			// b sees its context done and waits for the lock while a's slot
+			// is handed to it: b must pass the slot on to c.
+			q.mu.Lock()
+			cancel()
+			time.Sleep(20 * time.Millisecond)
+			q.handOff("a")
+			q.mu.Unlock()
+
+			if <-b != nil {
+				t.Fatal("canceled waiter kept its slot")
+			}
+			releaseC := <-c
+			if releaseC == nil {
+				t.Fatal("c never got the slot b gave up")
+			}
+			releaseC()
+			if q.active != 0 || len(q.running) != 0 {
+				t.Errorf("active %d, running %v after all released; want none", q.active, q.running)
+			}
+		}},
+		{"reused id", func(t *testing.T, q *scrapeQueue) {
+			q.slots = 2
+			first := mustAcquire(t, q, "x")
+			second := mustAcquire(t, q, "x")
+			first()
+			if st := waitStatus(t, q, "x", "running"); st.Running != 1 {
+				t.Errorf("status after the first of x finished = %+v, want x still running", st)
+			}
+			second()
+			if _, ok := q.status("x"); ok {
+				t.Error("x still known after both requests finished")
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.run(t, newScrapeQueue(1, 2))
+		})
+	}
+}
+
+func TestQueueEndpoint(t *testing.T) {
+	h := newTestHandler(jobsConfig())
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/queue/a", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("without a queue: status %d, want 404", rec.Code)
+	}
+
+	h.queue = newScrapeQueue(1, 1)
+	defer mustAcquire(t, h.queue, "a")()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	acquireAsync(h.queue, ctx, "b")
+	waitStatus(t, h.queue, "b", "queued")
+
+	for _, tt := range []struct {
+		id     string
+		status int
+		want   QueueStatus
+	}{
+		{"a", http.StatusOK, QueueStatus{ID: "a", State: "running", Running: 1, Queued: 1, Slots: 1}},
+		{"b", http.StatusOK, QueueStatus{ID: "b", State: "queued", Position: 1, Running: 1, Queued: 1, Slots: 1}},
+		{"zz", http.StatusNotFound, QueueStatus{ID: "zz", Running: 1, Queued: 1, Slots: 1}},
+	} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", "/queue/"+tt.id, nil))
+		var got QueueStatus
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil || rec.Code != tt.status || got != tt.want {
+			t.Errorf("GET /queue/%s = %d %s, want %d %+v", tt.id, rec.Code, rec.Body, tt.status, tt.want)
+		}
+	}
+
+	// With the slot and the queue taken, a scrape gets 503 at once.
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/?url=https://example.com&selector=a", nil))
+	if rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Retry-After") == "" {
+		t.Errorf("scrape on a full queue: status %d, Retry-After %q; want 503 with Retry-After", rec.Code, rec.Header().Get("Retry-After"))
+	}
+}
@@ -3,38 +3,65 @@
 package server
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
 	"log"
 	"net/http"
+	"net/url"
+	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/GhanshyamJha05/WEB_SCRAPPER_Using-GO/pkg/scraper"
+	"github.com/GhanshyamJha05/WEB_SCRAPPER_Using-GO/pkg/web"
 )
 
 // ScrapingSite is a pre-configured site shown as a recommendation in the UI.
 type ScrapingSite struct {
-	URL      string
-	Tag      string
-	Selector string
-	Example  string
+	URL      string `json:"url"`
+	Tag      string `json:"tag"`
+	Selector string `json:"selector"`
+	Example  string `json:"example"`
 }
 
 // PageData is the template context for the index page.
 type PageData struct {
-	URL         string
-	Selector    string
-	Results     []scraper.ScrapeResult
-	Duration    time.Duration
-	Error       string
-	Recommended []ScrapingSite
-	Visited     []string
+	URL             string
+	Selector        string
+	Results         []scraper.ScrapeResult
+	Tables          []scraper.Table   // set instead of Results in tableMode
+	Meta            *scraper.MetaTags // set instead of Results for mode=meta
+	ScriptValues    []string          // set instead of Results for mode=scriptjson, api, microdata and forms, each value, item or form as indented JSON
+	CountOnly       bool              // countOnly=true: only MatchCount is shown
+	Duration        time.Duration
+	Error           string
+	Attr            string               // first requested attribute, shown under each result
+	Scraped         bool                 // a scrape actually ran (vs. form not yet submitted / rejected input)
+	MatchCount      int                  // len(Results) once Scraped, before the render limit
+	Hidden          int                  // results left out of Results by the render limit
+	Cached          bool                 // every page was unchanged (304) and results came from the cache
+	Reused          bool                 // a repeat of a scrape within Config.Debounce, answered with its result
+	Skipped         []string             // URLs that timed out and were left out
+	StoppedReason   string               // why a multi-page scrape stopped early, see scraper.Options.MaxPages
+	ParseWarnings   []string             // "url: warning" for pages that parsed to little or nothing, see scraper.PageInfo.Warning
+	FixedSelector   string               // with autoFix, the variant of Selector that matched on the first page it was needed for
+	Timings         []scraper.PageTiming // per-page fetch phases, for the stats block
+	PageTitle       string               // <title> of the first page scraped, for the stats block
+	PageDescription string               // its meta description
+	CanonicalURL    string               // its <link rel="canonical">, when it declares one
+	BytesDownloaded int64                // response body bytes read for this scrape
+	Recommended     []ScrapingSite
+	Visited         []VisitedEntry // most recent first
 }
 
-// RecommendedSites are the default suggestions shown in the UI.
+// RecommendedSites are the default suggestions shown in the UI when
+// Config.Recommended is nil.
 var RecommendedSites = []ScrapingSite{
 	{URL: "https://news.ycombinator.com", Tag: "Tech News", Selector: ".titleline > a", Example: "Hacker News headlines"},
 	{URL: "https://www.reddit.com/r/golang/", Tag: "Golang", Selector: "h3._eYtD2XCVieq6emjKBH3m", Example: "Reddit post titles"},
@@ -43,37 +70,141 @@ var RecommendedSites = []ScrapingSite{
 
 // Handler holds shared state and handles HTTP requests.
 type Handler struct {
-	tmpl    *template.Template
-	cli     *scraper.Client
-	mu      sync.Mutex
-	visited []string
+	tmpl         *template.Template
+	cli          *scraper.Client
+	recommended  []ScrapingSite
+	mu           sync.Mutex
+	visited      []VisitedEntry        // oldest first
+	historySize  int                   // cap on visited
+	historyCanon bool                  // list visited pages under their canonical URL, see Config.HistoryCanonical
+	renderLimit  int                   // results rendered into the page; 0 renders all
+	renderWarn   time.Duration         // template renders slower than this are logged
+	snapshots    map[string][]snapshot // recent runs per url+selector, oldest first, for Diff
+	snapFile     string                // Config.SnapshotFile; "" keeps snapshots in memory only
+	saveMu       sync.Mutex            // serializes writes of snapFile
+	dashboard    dashboardCache
+	dashWorkers  int                // sites scraped at once for /dashboard
+	dashTimeout  time.Duration      // deadline for one dashboard site
+	build        BuildInfo          // reported by /version
+	queue        *scrapeQueue       // nil when Config.MaxConcurrent is 0
+	inFlight     atomic.Int64       // scrapes running now, see countScrape
+	debounce     *web.Debouncer     // nil when Config.Debounce is 0
+	jobs         *jobRegistry       // scheduled scrapes, see Jobs
+	defaultFmt   string             // Config.DefaultFormat
+	errTmpl      *template.Template // failed scrapes; nil renders them inline
+	secret       []byte             // Config.APISecret; nil leaves the API open
 }
 
 // New creates a Handler with the given template and scraper client.
 func New(tmpl *template.Template, cli *scraper.Client) *Handler {
-	return &Handler{tmpl: tmpl, cli: cli}
+	return &Handler{
+		tmpl:        tmpl,
+		cli:         cli,
+		recommended: RecommendedSites,
+		historySize: web.DefaultHistorySize,
+		renderLimit: web.DefaultRenderLimit,
+		renderWarn:  web.DefaultRenderWarn,
+		dashWorkers: DefaultDashboardConcurrency,
+		dashTimeout: DefaultDashboardTimeout,
+		jobs:        newJobRegistry(),
+	}
+}
+
+// VisitedEntry is one URL in the recent-history list.
+type VisitedEntry struct {
+	URL         string
+	LastVisited time.Time
+}
+
+// Downloaded formats BytesDownloaded for the stats block, e.g. "1.4 MB".
+func (d PageData) Downloaded() string {
+	return formatBytes(d.BytesDownloaded)
+}
+
+// formatBytes renders n in B, KB or MB (powers of 1024).
+func formatBytes(n int64) string {
+	switch {
+	case n < 1<<10:
+		return fmt.Sprintf("%d B", n)
+	case n < 1<<20:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	default:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	}
+}
+
+// Ago formats LastVisited relative to now, e.g. "2 min ago".
+func (v VisitedEntry) Ago() string {
+	d := time.Since(v.LastVisited)
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%d min ago", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%d h ago", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%d d ago", int(d.Hours()/24))
+	}
 }
 
+// addToVisited records a visit, moving a revisited URL to the end with a
+// fresh timestamp so the list stays ordered by LastVisited.
 func (h *Handler) addToVisited(url string) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
-	for i, u := range h.visited {
-		if u == url {
+	entry := VisitedEntry{URL: url, LastVisited: time.Now()}
+	for i, v := range h.visited {
+		if v.URL == url {
 			h.visited = append(h.visited[:i], h.visited[i+1:]...)
-			h.visited = append(h.visited, url)
+			h.visited = append(h.visited, entry)
 			return
 		}
 	}
-	h.visited = append(h.visited, url)
-	if len(h.visited) > 10 {
-		h.visited = h.visited[1:]
+	h.visited = append(h.visited, entry)
+	if over := len(h.visited) - h.historySize; over > 0 {
+		h.visited = h.visited[over:] // drop the oldest, keep the most recent
 	}
 }
 
-func (h *Handler) getVisited() []string {
+// canonicalizeVisited replaces the visited URL of each page declaring a
+// different canonical URL with that one, so tracking-parameter and mirror
+// URLs of one page collapse into a single entry.
+func (h *Handler) canonicalizeVisited(pages []scraper.PageInfo) {
+	for _, p := range pages {
+		if p.Canonical == "" || p.Canonical == p.URL {
+			continue
+		}
+		h.mu.Lock()
+		h.visited = slices.DeleteFunc(h.visited, func(v VisitedEntry) bool { return v.URL == p.URL })
+		h.mu.Unlock()
+		h.addToVisited(p.Canonical)
+	}
+}
+
+// ClearVisited handles POST /visited/clear: it empties the recent-history
+// list and redirects back to the form. Cross-site requests are refused, so
+// another page can't wipe the list through a hidden form.
+func (h *Handler) ClearVisited(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !sameOrigin(r) {
+		http.Error(w, "Cross-site request refused", http.StatusForbidden)
+		return
+	}
+	h.mu.Lock()
+	h.visited = nil
+	h.mu.Unlock()
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+func (h *Handler) getVisited() []VisitedEntry {
 	h.mu.Lock()
 	defer h.mu.Unlock()
-	copied := make([]string, len(h.visited))
+	copied := make([]VisitedEntry, len(h.visited))
 	copy(copied, h.visited)
 	for i, j := 0, len(copied)-1; i < j; i, j = i+1, j-1 {
 		copied[i], copied[j] = copied[j], copied[i]
@@ -81,10 +212,84 @@ func (h *Handler) getVisited() []string {
 	return copied
 }
 
+// recommendedSelector returns the selector of the recommended site on the same
+// domain as pageURL, so every path on a known site inherits its selector.
+func (h *Handler) recommendedSelector(pageURL string) string {
+	host := scraper.Host(pageURL)
+	if host == "" {
+		return ""
+	}
+	for _, site := range h.recommended {
+		if scraper.Host(site.URL) == host {
+			return site.Selector
+		}
+	}
+	return ""
+}
+
+// route is one endpoint of Handler besides the index page.
+type route struct {
+	path   string // matched exactly; a path ending in "/" matches everything under it
+	suffix bool   // match any path ending in path instead, e.g. /api/bulk-scrape for "/bulk-scrape"
+	signed bool   // an API endpoint: needs a SignatureHeader when Config.APISecret is set
+	queued bool   // takes a scrape slot, see queued
+	scrape bool   // counted in h.inFlight without taking a slot, see track
+	serve  func(h *Handler, w http.ResponseWriter, r *http.Request)
+}
+
+// routes lists every endpoint besides the index page, first match wins.
+// Both ServeHTTP and the signature check use it, so a new endpoint can't be
+// routed without deciding whether it is part of the API.
+var routes = []route{
+	{path: "/bulk-scrape", suffix: true, signed: true, queued: true, serve: (*Handler).BulkScrape},
+	{path: "/batch", suffix: true, signed: true, queued: true, serve: (*Handler).Batch},
+	{path: "/suggest", suffix: true, signed: true, scrape: true, serve: (*Handler).Suggest},
+	{path: "/check", suffix: true, signed: true, scrape: true, serve: (*Handler).Check},
+	{path: "/api/diff", signed: true, scrape: true, serve: (*Handler).Diff},
+	{path: "/ws/scrape", signed: true, queued: true, serve: (*Handler).Stream},
+	{path: "/visited/clear", serve: (*Handler).ClearVisited},
+	{path: "/dashboard", serve: (*Handler).Dashboard},
+	{path: "/version", serve: (*Handler).Version},
+	{path: "/stats", signed: true, serve: (*Handler).Stats},
+	{path: "/metrics", signed: true, serve: (*Handler).Metrics},
+	{path: "/jobs", signed: true, serve: (*Handler).Jobs},
+	{path: "/jobs/", signed: true, serve: (*Handler).Jobs},
+	{path: "/queue/", signed: true, serve: (*Handler).Queue},
+}
+
+// findRoute returns the entry of routes for path, or false for the index
+// page.
+func findRoute(path string) (route, bool) {
+	for _, rt := range routes {
+		switch {
+		case rt.suffix && strings.HasSuffix(path, rt.path),
+			!rt.suffix && strings.HasSuffix(rt.path, "/") && strings.HasPrefix(path, rt.path),
+			!rt.suffix && path == rt.path:
+			return rt, true
+		}
+	}
+	return route{}, false
+}
+
 // ServeHTTP routes requests to the appropriate handler.
 func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	if r.URL.Path == "/api/bulk-scrape" || strings.HasSuffix(r.URL.Path, "/bulk-scrape") {
-		h.BulkScrape(w, r)
+	if h.secret != nil && isAPIRequest(r, h.defaultFmt) && !authorize(w, r, h.secret) {
+		return
+	}
+	if rt, ok := findRoute(r.URL.Path); ok {
+		serve := func(w http.ResponseWriter, r *http.Request) { rt.serve(h, w, r) }
+		switch {
+		case rt.queued:
+			h.queued(w, r, serve)
+		case rt.scrape:
+			h.track(w, r, serve)
+		default:
+			serve(w, r)
+		}
+		return
+	}
+	if r.URL.Query().Get("url") != "" {
+		h.queued(w, r, h.Index) // only a scrape takes a slot, not the bare form
 		return
 	}
 	h.Index(w, r)
@@ -93,12 +298,19 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 // Index handles the main scraper UI page (GET /).
 func (h *Handler) Index(w http.ResponseWriter, r *http.Request) {
 	data := PageData{
-		Recommended: RecommendedSites,
+		Recommended: h.recommended,
 		Visited:     h.getVisited(),
 	}
 
-	rawURL := r.URL.Query().Get("url")
-	selector := r.URL.Query().Get("selector")
+	q := r.URL.Query()
+	rawURL := q.Get("url")
+	selector := q.Get("selector")
+	format := scraper.RequestFormat(r, h.defaultFmt) // the format parameter, else the Accept header
+	copyJSON := q.Get("copy") == "json"              // UI "Copy as JSON" button
+	w.Header().Add("Vary", "Accept")
+	if copyJSON {
+		format = "json"
+	}
 
 	if rawURL != "" {
 		data.URL = rawURL
@@ -106,34 +318,125 @@ func (h *Handler) Index(w http.ResponseWriter, r *http.Request) {
 		urls := scraper.ParseURLs(rawURL)
 
 		if len(urls) == 0 {
-			data.Error = "Please provide at least one valid URL."
-			h.render(w, data)
+			h.fail(w, format, data, "Please provide at least one valid URL.")
 			return
 		}
 		if len(urls) > h.cli.MaxURLs() {
-			data.Error = fmt.Sprintf("Too many URLs. Maximum allowed per request is %d.", h.cli.MaxURLs())
-			h.render(w, data)
+			h.fail(w, format, data, fmt.Sprintf("Too many URLs. Maximum allowed per request is %d.", h.cli.MaxURLs()))
+			return
+		}
+		if err := h.checkURLs(r.Context(), urls); err != nil {
+			h.fail(w, format, data, web.Capitalize(err.Error()))
+			return
+		}
+		opts, err := scraper.ParseOptions(q)
+		if err == nil {
+			err = h.cli.CheckOptions(opts)
+		}
+		if err != nil {
+			h.fail(w, format, data, err.Error())
 			return
 		}
+		if len(opts.Attrs) > 0 {
+			data.Attr = opts.Attrs[0]
+		}
 		for _, u := range urls {
 			h.addToVisited(u)
 		}
 
+		if format == "raw" {
+			h.writeRaw(w, r, opts, urls)
+			return
+		}
+
+		if opts.Mode != "" {
+			if len(urls) != 1 {
+				h.fail(w, format, data, "mode="+opts.Mode+" accepts exactly one URL.")
+				return
+			}
+			if opts.Mode == scraper.ModeMeta {
+				if msg := web.JSONOnly("mode=meta", format); msg != "" {
+					h.fail(w, format, data, msg)
+					return
+				}
+				h.renderMeta(w, r, format, data, opts, urls[0])
+				return
+			}
+			scrape, c := web.ValueModes[opts.Mode], h.cli.WithOptions(opts)
+			h.renderValues(w, r, format, data, opts.Mode, func() (any, []any, error) {
+				return scrape(r.Context(), c, opts, urls[0], selector)
+			})
+			return
+		}
+
+		if opts.Sitemap {
+			if len(urls) != 1 {
+				h.fail(w, format, data, "sitemap=true accepts exactly one URL.")
+				return
+			}
+			found, err := h.cli.WithOptions(opts).Sitemap(r.Context(), urls[0])
+			if err != nil {
+				if format != "" {
+					http.Error(w, "Sitemap failed: "+err.Error(), http.StatusBadGateway)
+					return
+				}
+				data.Error = err.Error()
+				h.renderError(w, r, data, []error{err})
+				return
+			}
+			if selector == "" {
+				h.renderSitemap(w, format, data, opts, urls[0], found)
+				return
+			}
+			// Scrape the listed pages, as many as one request may cover.
+			urls = found[:min(len(found), h.cli.MaxURLs())]
+			if err := h.checkURLs(r.Context(), urls); err != nil {
+				h.fail(w, format, data, web.Capitalize(err.Error()))
+				return
+			}
+		}
+
 		// Auto-fill selector from recommended sites if not provided.
 		if selector == "" {
-			for _, site := range RecommendedSites {
-				if site.URL == urls[0] {
-					selector = site.Selector
-					data.Selector = selector
-					break
-				}
+			selector = h.recommendedSelector(urls[0])
+			data.Selector = selector
+		}
+
+		if selector != "" && opts.TableMode != "" {
+			if msg := web.JSONOnly("tableMode", format); msg != "" {
+				h.fail(w, format, data, msg)
+				return
+			}
+			h.renderTables(w, r, format, data, opts, urls, selector)
+			return
+		}
+
+		if selector != "" && opts.CountOnly {
+			if msg := web.JSONOnly("countOnly", format); msg != "" {
+				h.fail(w, format, data, msg)
+				return
 			}
+			h.renderCount(w, r, format, data, opts, urls, selector)
+			return
+		}
+
+		if selector != "" && format == "jsonl" {
+			h.writeJSONL(w, opts, urls, selector)
+			return
 		}
 
 		if selector != "" {
 			start := time.Now()
-			results, errs := h.cli.ScrapeWithWorkerPool(urls, selector)
+			scoped := h.cli.WithOptions(opts)
+			run, reused, err := h.scrapeAll(r, scoped, urls, selector)
+			if err != nil {
+				return // the client left while waiting for a repeat's result
+			}
+			results, errs := run.Results, run.Errs
 			data.Duration = time.Since(start).Round(time.Millisecond)
+			if h.historyCanon {
+				h.canonicalizeVisited(run.Pages)
+			}
 			if len(errs) > 0 {
 				msgs := make([]string, 0, len(errs))
 				for _, e := range errs {
@@ -141,9 +444,67 @@ func (h *Handler) Index(w http.ResponseWriter, r *http.Request) {
 				}
 				data.Error = fmt.Sprintf("Completed with %d error(s): %s", len(errs), strings.Join(msgs, " | "))
 			}
+			if len(errs) > 0 && len(errs) == run.Attempted && format != "md" && format != "csv" && format != "json" {
+				h.renderError(w, r, data, errs)
+				return
+			}
+			if format == "md" {
+				w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+				if err := scraper.WriteMarkdown(w, results, errs); err != nil {
+					log.Printf("markdown write error: %v", err)
+				}
+				return
+			}
+			if format == "csv" {
+				w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+				if err := scraper.WriteCSV(w, results, opts); err != nil {
+					log.Printf("csv write error: %v", err)
+				}
+				return
+			}
+			if format == "json" {
+				if copyJSON {
+					w.Header().Set("Content-Disposition", "inline")
+				}
+				out := scraper.NewScrapeOutput(urls, selector, scoped.Workers(), results, errs)
+				out.Meta.Cached = run.Cached
+				out.Meta.Reused = reused
+				out.Meta.Skipped = run.Skipped
+				out.Meta.StoppedReason = run.StoppedReason
+				out.Records = run.Records
+				out.Meta.Timings = run.Timings
+				out.Meta.Pages = run.Pages
+				out.Meta.BytesDownloaded = run.BytesDownloaded
+				out.Truncate(h.cli.MaxOutputBytes())
+				out.SetNextCursor(opts.Offset, run.Count)
+				writeJSON(w, out)
+				return
+			}
 			data.Results = results
+			data.Cached = run.Cached
+			data.Reused = reused
+			data.Skipped = run.Skipped
+			data.StoppedReason = run.StoppedReason
+			for _, p := range run.Pages {
+				if p.Warning != "" {
+					data.ParseWarnings = append(data.ParseWarnings, p.URL+": "+p.Warning)
+				}
+				if data.FixedSelector == "" {
+					data.FixedSelector = p.FixedSelector
+				}
+			}
+			data.Timings = run.Timings
+			if len(run.Pages) > 0 {
+				data.PageTitle = run.Pages[0].Title
+				data.PageDescription = run.Pages[0].Description
+				data.CanonicalURL = run.Pages[0].Canonical
+			}
+			data.BytesDownloaded = run.BytesDownloaded
+			data.Scraped = true
+			data.MatchCount = len(results)
 		} else {
-			data.Error = "Please provide a CSS selector."
+			h.fail(w, format, data, "Please provide a CSS selector.")
+			return
 		}
 	}
 
@@ -183,6 +544,10 @@ func (h *Handler) BulkScrape(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, fmt.Sprintf("Maximum %d URLs allowed per request", h.cli.MaxURLs()), http.StatusBadRequest)
 		return
 	}
+	if err := h.checkURLs(r.Context(), urls); err != nil {
+		http.Error(w, web.Capitalize(err.Error()), http.StatusForbidden)
+		return
+	}
 
 	for _, u := range urls {
 		h.addToVisited(u)
@@ -195,9 +560,424 @@ func (h *Handler) BulkScrape(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// Batch handles POST /api/batch.
+func (h *Handler) Batch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var items []scraper.BatchItem
+	if err := json.NewDecoder(r.Body).Decode(&items); err != nil {
+		http.Error(w, "Invalid JSON payload", http.StatusBadRequest)
+		return
+	}
+
+	if len(items) == 0 {
+		http.Error(w, "At least one item is required", http.StatusBadRequest)
+		return
+	}
+	if len(items) > h.cli.MaxURLs() {
+		http.Error(w, fmt.Sprintf("Maximum %d items allowed per request", h.cli.MaxURLs()), http.StatusBadRequest)
+		return
+	}
+	for i := range items {
+		items[i].URL = strings.TrimSpace(items[i].URL)
+		items[i].Selector = strings.TrimSpace(items[i].Selector)
+		if items[i].URL == "" || items[i].Selector == "" {
+			http.Error(w, fmt.Sprintf("Item %d needs both url and selector", i), http.StatusBadRequest)
+			return
+		}
+		if err := h.checkURLs(r.Context(), []string{items[i].URL}); err != nil {
+			http.Error(w, web.Capitalize(err.Error()), http.StatusForbidden)
+			return
+		}
+	}
+
+	for _, it := range items {
+		h.addToVisited(it.URL)
+	}
+
+	resp := h.cli.RunBatch(items)
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+// writeRaw sends the unparsed body of a single page back with its original
+// Content-Type, for inspecting markup when a selector misbehaves.
+func (h *Handler) writeRaw(w http.ResponseWriter, r *http.Request, opts scraper.Options, urls []string) {
+	if len(urls) != 1 {
+		http.Error(w, "format=raw accepts exactly one URL", http.StatusBadRequest)
+		return
+	}
+	page, err := h.cli.WithOptions(opts).FetchRaw(r.Context(), urls[0])
+	if err != nil {
+		http.Error(w, "Failed to fetch page: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	contentType := page.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", contentType)
+	// Third-party markup is served from our origin, so sandbox it to keep
+	// its scripts away from this site's cookies and storage.
+	w.Header().Set("Content-Security-Policy", "sandbox")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if _, err := w.Write(page.Body); err != nil {
+		log.Printf("raw write error: %v", err)
+	}
+}
+
+// Suggest handles GET /api/suggest?url=, returning candidate selectors for
+// the page ranked by how many elements they match.
+func (h *Handler) Suggest(w http.ResponseWriter, r *http.Request) {
+	pageURL := strings.TrimSpace(r.URL.Query().Get("url"))
+	if pageURL == "" {
+		http.Error(w, "url is required", http.StatusBadRequest)
+		return
+	}
+	if err := h.checkURLs(r.Context(), []string{pageURL}); err != nil {
+		http.Error(w, web.Capitalize(err.Error()), http.StatusForbidden)
+		return
+	}
+
+	suggestions, err := h.cli.SuggestSelectors(r.Context(), pageURL, 10)
+	if err != nil {
+		http.Error(w, "Failed to fetch page: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	writeJSON(w, scraper.SuggestOutput{URL: pageURL, Suggestions: suggestions})
+}
+
+// Check handles GET /api/check?url=: a quick reachability and content-type
+// probe, without downloading or parsing the page. An unreachable page is
+// reported in the body (reachable=false), not as an HTTP error.
+func (h *Handler) Check(w http.ResponseWriter, r *http.Request) {
+	pageURL := strings.TrimSpace(r.URL.Query().Get("url"))
+	if pageURL == "" {
+		http.Error(w, "url is required", http.StatusBadRequest)
+		return
+	}
+	if err := h.checkURLs(r.Context(), []string{pageURL}); err != nil {
+		http.Error(w, web.Capitalize(err.Error()), http.StatusForbidden)
+		return
+	}
+	writeJSON(w, h.cli.Check(r.Context(), pageURL))
+}
+
+// writeJSONL streams format=jsonl results: one JSON object per line, flushed
+// as each page finishes.
+func (h *Handler) writeJSONL(w http.ResponseWriter, opts scraper.Options, urls []string, selector string) {
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if err := scraper.WriteJSONL(w, h.cli.WithOptions(opts).ScrapeStreamed(urls, selector), opts); err != nil {
+		log.Printf("jsonl write error: %v", err)
+	}
+}
+
+// renderTables serves a tableMode scrape: each table matched by selector is
+// returned as a header plus rows of cell text instead of flat results, or
+// with tableMode=objects as rows keyed by header text.
+func (h *Handler) renderTables(w http.ResponseWriter, r *http.Request, format string, data PageData, opts scraper.Options, urls []string, selector string) {
+	if len(urls) != 1 {
+		h.fail(w, format, data, "tableMode accepts exactly one URL.")
+		return
+	}
+
+	start := time.Now()
+	tables, err := h.cli.WithOptions(opts).ScrapeTables(r.Context(), urls[0], selector)
+	data.Duration = time.Since(start).Round(time.Millisecond)
+	if err != nil {
+		if format == "json" {
+			http.Error(w, "Scrape failed: "+err.Error(), http.StatusBadGateway)
+			return
+		}
+		data.Error = err.Error()
+		h.renderError(w, r, data, []error{err})
+		return
+	}
+
+	if format == "json" {
+		if opts.TableMode == scraper.TableObjects {
+			for i := range tables {
+				tables[i].Rows = nil // the same cells are in Objects
+			}
+		}
+		writeJSON(w, scraper.TableOutput{URL: urls[0], Selector: selector, Tables: tables})
+		return
+	}
+	data.Tables = tables
+	data.Scraped = true
+	data.MatchCount = len(tables)
+	h.render(w, data)
+}
+
+// renderSitemap serves sitemap=true without a selector: the page URLs
+// listed by the sitemap, as scraper.SitemapOutput JSON or as one result
+// per URL.
+func (h *Handler) renderSitemap(w http.ResponseWriter, format string, data PageData, opts scraper.Options, sitemapURL string, urls []string) {
+	if format == "json" {
+		writeJSON(w, scraper.SitemapOutput{URL: sitemapURL, URLs: urls, Total: len(urls)})
+		return
+	}
+	results := make([]scraper.ScrapeResult, len(urls))
+	for i, u := range urls {
+		results[i] = scraper.ScrapeResult{Title: u, Link: u}
+	}
+	switch format {
+	case "jsonl":
+		h.fail(w, format, data, "sitemap=true without a selector does not support format=jsonl.")
+	case "md":
+		w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+		if err := scraper.WriteMarkdown(w, results, nil); err != nil {
+			log.Printf("markdown write error: %v", err)
+		}
+	case "csv":
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		if err := scraper.WriteCSV(w, results, opts); err != nil {
+			log.Printf("csv write error: %v", err)
+		}
+	default:
+		data.Results = results
+		data.Scraped = true
+		data.MatchCount = len(results)
+		h.render(w, data)
+	}
+}
+
+// renderMeta serves a mode=meta scrape: the page's OpenGraph and Twitter
+// card tags instead of selector matches.
+func (h *Handler) renderMeta(w http.ResponseWriter, r *http.Request, format string, data PageData, opts scraper.Options, pageURL string) {
+	start := time.Now()
+	meta, err := h.cli.WithOptions(opts).ScrapeMetaTags(r.Context(), pageURL)
+	data.Duration = time.Since(start).Round(time.Millisecond)
+	if err != nil {
+		if format == "json" {
+			http.Error(w, "Scrape failed: "+err.Error(), http.StatusBadGateway)
+			return
+		}
+		data.Error = err.Error()
+		h.renderError(w, r, data, []error{err})
+		return
+	}
+
+	if format == "json" {
+		writeJSON(w, meta)
+		return
+	}
+	data.Meta = &meta
+	data.Scraped = true
+	data.MatchCount = len(meta.OpenGraph) + len(meta.Twitter)
+	h.render(w, data)
+}
+
+// renderValues serves a scrape in one of web.ValueModes, name: fetch's
+// output as JSON for format=json, else its values as indented JSON blocks
+// on the page.
+func (h *Handler) renderValues(w http.ResponseWriter, r *http.Request, format string, data PageData, name string, fetch func() (any, []any, error)) {
+	if msg := web.JSONOnly("mode="+name, format); msg != "" {
+		h.fail(w, format, data, msg)
+		return
+	}
+
+	start := time.Now()
+	out, values, err := fetch()
+	data.Duration = time.Since(start).Round(time.Millisecond)
+	if err != nil {
+		if format == "json" {
+			http.Error(w, "Scrape failed: "+err.Error(), http.StatusBadGateway)
+			return
+		}
+		data.Error = err.Error()
+		h.renderError(w, r, data, []error{err})
+		return
+	}
+
+	if format == "json" {
+		writeJSON(w, out)
+		return
+	}
+	data.ScriptValues = web.IndentValues(values)
+	data.Scraped = true
+	data.MatchCount = len(data.ScriptValues)
+	h.render(w, data)
+}
+
+// renderCount serves countOnly=true: the number of matches, as
+// scraper.CountOutput JSON or in the page's match-count pill.
+func (h *Handler) renderCount(w http.ResponseWriter, r *http.Request, format string, data PageData, opts scraper.Options, urls []string, selector string) {
+	start := time.Now()
+	run := h.cli.WithOptions(opts).ScrapeAll(urls, selector)
+	data.Duration = time.Since(start).Round(time.Millisecond)
+	if format == "json" {
+		writeJSON(w, scraper.NewCountOutput(urls, selector, run))
+		return
+	}
+	if len(run.Errs) > 0 {
+		msgs := make([]string, 0, len(run.Errs))
+		for _, e := range run.Errs {
+			msgs = append(msgs, e.Error())
+		}
+		data.Error = fmt.Sprintf("Completed with %d error(s): %s", len(run.Errs), strings.Join(msgs, " | "))
+	}
+	if len(run.Errs) == len(urls) {
+		h.renderError(w, r, data, run.Errs)
+		return
+	}
+	data.CountOnly = true
+	data.Scraped = true
+	data.MatchCount = run.Count
+	data.StoppedReason = run.StoppedReason
+	h.render(w, data)
+}
+
+// checkURLs applies the client's HostPolicy to every URL before any request
+// is made, so a rejected host gets a clear error instead of a failed fetch.
+// Handlers showing the error pass it through web.Capitalize.
+func (h *Handler) checkURLs(ctx context.Context, urls []string) error {
+	for _, u := range urls {
+		if err := h.cli.CheckURL(ctx, u); err != nil {
+			return fmt.Errorf("refusing to scrape %s: %w", u, err)
+		}
+	}
+	return nil
+}
+
+// fail reports an input error: as a plain-text 400 for the json, jsonl and
+// md formats, or inside the rendered page otherwise.
+func (h *Handler) fail(w http.ResponseWriter, format string, data PageData, msg string) {
+	if format == "json" || format == "jsonl" || format == "md" || format == "csv" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+	data.Error = msg
+	h.render(w, data)
+}
+
+// writeJSON encodes v as the response body.
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+// executeTemplate renders t into a buffer and only then writes it with
+// status, so an error halfway through the template becomes a clean 500
+// instead of half a page. A render still running after warnAfter is logged,
+// and so is how long it finally took.
+func executeTemplate(w http.ResponseWriter, t *template.Template, data any, status int, warnAfter time.Duration) error {
+	start := time.Now()
+	watchdog := time.AfterFunc(warnAfter, func() {
+		log.Printf("template %s: still rendering after %v", t.Name(), warnAfter)
+	})
+	var buf bytes.Buffer
+	err := t.Execute(&buf, data)
+	if !watchdog.Stop() {
+		log.Printf("template %s: rendered in %v", t.Name(), time.Since(start).Round(time.Millisecond))
+	}
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	_, _ = buf.WriteTo(w) // a write error means the client went away
+	return nil
+}
+
+// render executes the index template, cutting Results down to the render
+// limit first.
 func (h *Handler) render(w http.ResponseWriter, data PageData) {
-	if err := h.tmpl.Execute(w, data); err != nil {
+	if h.renderLimit > 0 && len(data.Results) > h.renderLimit {
+		data.Hidden = len(data.Results) - h.renderLimit
+		data.Results = data.Results[:h.renderLimit]
+	}
+	if err := executeTemplate(w, h.tmpl, data, http.StatusOK, h.renderWarn); err != nil {
 		log.Printf("template error: %v", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 	}
 }
+
+// ErrorPage is the template context for Config.ErrorTemplate.
+type ErrorPage struct {
+	URL        string
+	Selector   string
+	Kind       string   // scraper.ErrorInfo.Kind of the first error
+	Suggestion string   // scraper.ErrorInfo.Suggestion of the first error
+	Errors     []string // every error, one per failed URL
+	RetryURL   string   // the same request again
+}
+
+// renderError shows a scrape in which every URL failed: on the error
+// template with a 502, or inline as data.Error when the server has none.
+func (h *Handler) renderError(w http.ResponseWriter, r *http.Request, data PageData, errs []error) {
+	if h.errTmpl == nil {
+		h.render(w, data)
+		return
+	}
+	info := scraper.ExplainError(errs[0])
+	page := ErrorPage{URL: data.URL, Selector: data.Selector, Kind: info.Kind, Suggestion: info.Suggestion, RetryURL: retryURL(r)}
+	for _, e := range errs {
+		page.Errors = append(page.Errors, e.Error())
+	}
+	if err := executeTemplate(w, h.errTmpl, page, http.StatusBadGateway, h.renderWarn); err != nil {
+		log.Printf("error template: %v", err)
+		http.Error(w, "Scrape failed: "+page.Errors[0], http.StatusBadGateway)
+	}
+}
+
+// retryURL is r's URL without its queue id, which belongs to this attempt,
+// and without loginPass and header, whose passwords and tokens must never
+// be echoed into a page.
+func retryURL(r *http.Request) string {
+	q := r.URL.Query()
+	q.Del("queueId")
+	q.Del("loginPass")
+	q.Del("header")
+	return r.URL.Path + "?" + q.Encode()
+}
+
+// sameOrigin reports whether r comes from a page of this site, going by
+// Sec-Fetch-Site or else Origin. Requests with neither (curl, old browsers)
+// are allowed: there is no session cookie for a forged request to ride on.
+func sameOrigin(r *http.Request) bool {
+	if site := r.Header.Get("Sec-Fetch-Site"); site != "" {
+		return site == "same-origin" || site == "none"
+	}
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && u.Host == r.Host
+}
+
+// isAPIRequest reports whether r is for the API rather than the HTML UI: a
+// signed entry of routes, or any page requested with a format, by parameter
+// or Accept header, or by a defaultFormat the request doesn't override. Only
+// those need a signature when an API secret is set.
+func isAPIRequest(r *http.Request, defaultFormat string) bool {
+	if rt, ok := findRoute(r.URL.Path); ok && rt.signed {
+		return true
+	}
+	return scraper.RequestFormat(r, defaultFormat) != "" || r.URL.Query().Get("copy") == "json"
+}
+
+// authorize verifies r's scraper.SignatureHeader, answering 401 (413 for a
+// body too large to check) when it fails.
+func authorize(w http.ResponseWriter, r *http.Request, secret []byte) bool {
+	err := scraper.VerifyRequest(secret, r)
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, scraper.ErrResponseTooLarge):
+		http.Error(w, "Request body too large to verify", http.StatusRequestEntityTooLarge)
+	default:
+		http.Error(w, "Unauthorized: "+err.Error(), http.StatusUnauthorized)
+	}
+	return false
+}
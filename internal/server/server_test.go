@@ -0,0 +1,178 @@
+package server
+
+import (
+	"encoding/json"
+	"html/template"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GhanshyamJha05/WEB_SCRAPPER_Using-GO/pkg/scraper"
+	"github.com/GhanshyamJha05/WEB_SCRAPPER_Using-GO/pkg/web"
+)
+
+// newTestHandler returns a Handler with a one-line index template, for tests
+// that don't look at the page.
+func newTestHandler(cfg scraper.Config) *Handler {
+	tmpl := template.Must(template.New("index.html").Parse("ok"))
+	return New(tmpl, scraper.NewClient(cfg))
+}
+
+func TestSignatureGate(t *testing.T) {
+	secret := []byte("s3cret")
+	h := newTestHandler(scraper.DefaultConfig())
+	h.secret = secret
+
+	tests := []struct {
+		method, target string
+		api            bool
+	}{
+		{"POST", "/api/bulk-scrape", true},
+		{"POST", "/bulk-scrape", true},
+		{"POST", "/api/batch", true},
+		{"GET", "/api/suggest?url=https://example.com", true},
+		{"GET", "/api/check?url=https://example.com", true},
+		{"GET", "/api/diff?url=https://example.com", true},
+		{"GET", "/ws/scrape?url=https://example.com", true},
+		{"GET", "/stats", true},
+		{"GET", "/metrics", true},
+		{"GET", "/queue/abc", true},
+		{"GET", "/jobs", true},
+		{"GET", "/jobs/abc/results", true},
+		{"GET", "/?url=https://example.com&format=json", true},
+		{"GET", "/?url=https://example.com&copy=json", true},
+		{"GET", "/", false},
+		{"GET", "/version", false},
+		{"GET", "/visited/clear", false},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.target, nil))
+		if got := rec.Code == http.StatusUnauthorized; got != tt.api {
+			t.Errorf("%s %s unsigned: status %d, want 401: %v", tt.method, tt.target, rec.Code, tt.api)
+		}
+	}
+
+	// Signed, the gated read-only endpoints answer as usual.
+	for _, target := range []string{"/stats", "/metrics", "/jobs"} {
+		req := httptest.NewRequest("GET", target, nil)
+		if err := scraper.SignRequest(secret, req); err != nil {
+			t.Fatal(err)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Errorf("GET %s signed: status %d, want 200", target, rec.Code)
+		}
+	}
+
+	// A signature is bound to its path and method: one captured for /stats
+	// opens nothing else.
+	signed := httptest.NewRequest("GET", "/stats", nil)
+	if err := scraper.SignRequest(secret, signed); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct{ method, target string }{{"GET", "/metrics"}, {"GET", "/jobs"}, {"DELETE", "/jobs/abc"}} {
+		req := httptest.NewRequest(tt.method, tt.target, nil)
+		req.Header = signed.Header.Clone()
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("%s %s with the /stats signature: status %d, want 401", tt.method, tt.target, rec.Code)
+		}
+	}
+}
+
+func TestValueModes(t *testing.T) {
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `<form id="f" action="/go"><input name="q"></form><form id="g"></form>`)
+	}))
+	defer site.Close()
+	tmpl := template.Must(template.New("index.html").Parse(`{{.Error}}|{{.MatchCount}}|{{range .ScriptValues}}[{{.}}]{{end}}`))
+	h := New(tmpl, scraper.NewClient(jobsConfig()))
+	defer h.jobs.stop()
+
+	get := func(query string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", "/?"+query, nil))
+		return rec
+	}
+	u := url.QueryEscape(site.URL)
+
+	rec := get("mode=forms&format=json&url=" + u)
+	var forms scraper.Forms
+	if err := json.Unmarshal(rec.Body.Bytes(), &forms); err != nil || rec.Code != http.StatusOK || len(forms.Forms) != 2 {
+		t.Errorf("format=json: %d %s, want both forms", rec.Code, rec.Body)
+	}
+	if rec := get("mode=forms&url=" + u); !strings.HasPrefix(rec.Body.String(), "|2|[{") {
+		t.Errorf("page = %q, want the two forms as JSON blocks", rec.Body)
+	}
+
+	for _, tt := range []struct{ query, want string }{
+		{"mode=forms&format=csv&url=" + u, "mode=forms does not support format=csv."},
+		{"mode=meta&format=md&url=" + u, "mode=meta does not support format=md."},
+		{"mode=microdata&format=json&url=" + url.QueryEscape(site.URL+"\n"+site.URL+"/b"), "mode=microdata accepts exactly one URL."},
+	} {
+		if rec := get(tt.query); rec.Code != http.StatusBadRequest || strings.TrimSpace(rec.Body.String()) != tt.want {
+			t.Errorf("%s: %d %q, want 400 %q", tt.query, rec.Code, rec.Body, tt.want)
+		}
+	}
+}
+
+func TestInFlight(t *testing.T) {
+	release := make(chan struct{})
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		_, _ = io.WriteString(w, `<a href="/a">a</a>`)
+	}))
+	defer site.Close()
+	h := newTestHandler(jobsConfig())
+	defer h.jobs.stop()
+
+	// Every path that fetches pages counts while it runs, not only the
+	// queued scrape endpoints.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/suggest?url="+site.URL, nil))
+	}()
+	if err := h.jobs.add(h, Job{ID: "j", URL: site.URL, Selector: "a"}, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for h.inFlight.Load() != 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("inFlight = %d while a suggest and a job run, want 2", h.inFlight.Load())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	close(release)
+	<-done
+	waitRuns(t, h, "j", 1)
+	if n := h.inFlight.Load(); n != 0 {
+		t.Errorf("inFlight = %d after both finished, want 0", n)
+	}
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv(web.EnvRenderLimit, "0")
+	t.Setenv(EnvWriteTimeout, "0")
+	t.Setenv(EnvReadTimeout, "5s")
+	t.Setenv(EnvMaxScrapes, "4")
+	cfg := ConfigFromEnv()
+	if cfg.RenderLimit >= 0 || cfg.WriteTimeout >= 0 {
+		t.Errorf("RenderLimit %d, WriteTimeout %v; want both negative for \"0\"", cfg.RenderLimit, cfg.WriteTimeout)
+	}
+	if cfg.ReadTimeout != 5*time.Second || cfg.IdleTimeout != 0 || cfg.MaxConcurrent != 4 || cfg.QueueSize != DefaultQueueSize {
+		t.Errorf("ConfigFromEnv = %+v", cfg)
+	}
+
+	t.Setenv(web.EnvRenderLimit, "")
+	if cfg := ConfigFromEnv(); cfg.RenderLimit != web.DefaultRenderLimit {
+		t.Errorf("RenderLimit unset = %d, want %d", cfg.RenderLimit, web.DefaultRenderLimit)
+	}
+}
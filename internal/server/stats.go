@@ -0,0 +1,19 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/GhanshyamJha05/WEB_SCRAPPER_Using-GO/pkg/scraper"
+)
+
+// StatsResponse is the JSON body for GET /stats.
+type StatsResponse struct {
+	URLs []scraper.URLStats `json:"urls"` // most scraped first
+}
+
+// Stats handles GET /stats with the per-URL scrape statistics of this
+// server's client: how often each URL was scraped, its average, fastest and
+// slowest duration, and its last error.
+func (h *Handler) Stats(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, StatsResponse{URLs: h.cli.Stats()})
+}
@@ -0,0 +1,137 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/GhanshyamJha05/WEB_SCRAPPER_Using-GO/pkg/scraper"
+	"github.com/GhanshyamJha05/WEB_SCRAPPER_Using-GO/pkg/web"
+	"github.com/gorilla/websocket"
+)
+
+// streamWriteWait bounds each WebSocket write, so a client that stops
+// reading can't hold a scrape open.
+const streamWriteWait = 10 * time.Second
+
+// upgrader accepts WebSocket connections from pages of this site only, see
+// sameOrigin; a cross-site page could otherwise scrape through a visitor.
+var upgrader = websocket.Upgrader{CheckOrigin: sameOrigin}
+
+// StreamEvent is one message sent by Stream: a "result" as soon as its
+// page is parsed, a "page" when a page finishes (with its error, if it
+// failed), and a final "done".
+type StreamEvent struct {
+	Type       string                `json:"type"`
+	Result     *scraper.ScrapeResult `json:"result,omitempty"`
+	URL        string                `json:"url,omitempty"`
+	Error      string                `json:"error,omitempty"`
+	Count      int                   `json:"count"`                 // results of the page, or of the whole scrape for "done"
+	Pages      int                   `json:"pages"`                 // pages finished so far
+	Total      int                   `json:"total"`                 // pages in the scrape
+	DurationMs int64                 `json:"duration_ms,omitempty"` // time spent on the page, or the scrape
+}
+
+// Stream handles GET /ws/scrape, a WebSocket carrying a scrape's progress
+// as StreamEvent JSON messages while it runs. It takes the parameters of a
+// jsonl scrape; offset and count apply across the whole stream. The client
+// only listens: closing the connection cancels the scrape, and pages still
+// being fetched are abandoned.
+func (h *Handler) Stream(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	urls := scraper.ParseURLs(q.Get("url"))
+	selector := strings.TrimSpace(q.Get("selector"))
+	if len(urls) == 0 {
+		http.Error(w, "url is required", http.StatusBadRequest)
+		return
+	}
+	if len(urls) > h.cli.MaxURLs() {
+		http.Error(w, fmt.Sprintf("Too many URLs. Maximum allowed per request is %d.", h.cli.MaxURLs()), http.StatusBadRequest)
+		return
+	}
+	if selector == "" {
+		selector = h.recommendedSelector(urls[0])
+	}
+	if selector == "" {
+		http.Error(w, "CSS selector is required", http.StatusBadRequest)
+		return
+	}
+	opts, err := scraper.ParseOptions(q)
+	if err == nil {
+		err = h.cli.CheckOptions(opts)
+	}
+	if err == nil && opts.Mode != "" {
+		err = fmt.Errorf("mode=%s can't be streamed", opts.Mode)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := h.checkURLs(r.Context(), urls); err != nil {
+		http.Error(w, web.Capitalize(err.Error()), http.StatusForbidden)
+		return
+	}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return // Upgrade has answered the request
+	}
+	defer conn.Close()
+
+	// r.Context() isn't canceled once the connection is hijacked, so the
+	// scrape gets its own, canceled when a read shows the client has gone.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_ = conn.SetReadDeadline(time.Time{}) // drop the server's ReadTimeout
+	go func() {
+		defer cancel()
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+	send := func(ev StreamEvent) {
+		if ctx.Err() != nil {
+			return
+		}
+		_ = conn.SetWriteDeadline(time.Now().Add(streamWriteWait))
+		if err := conn.WriteJSON(ev); err != nil {
+			cancel()
+		}
+	}
+
+	for _, u := range urls {
+		h.addToVisited(u)
+	}
+	start := time.Now()
+	skip, left := max(opts.Offset, 0), opts.Count
+	pages, count := 0, 0
+	for p := range h.cli.WithOptions(opts).ScrapeStreamedContext(ctx, urls, selector) {
+		pages++
+		ev := StreamEvent{Type: "page", URL: p.URL, Pages: pages, Total: len(urls), DurationMs: p.DurationMs}
+		if p.Err != nil {
+			ev.Error = p.Err.Error()
+		}
+		for _, item := range p.Items {
+			if skip > 0 {
+				skip--
+				continue
+			}
+			if opts.Count > 0 && left == 0 {
+				break
+			}
+			left--
+			ev.Count++
+			send(StreamEvent{Type: "result", Result: &item, Pages: pages - 1, Total: len(urls)})
+		}
+		count += ev.Count
+		send(ev)
+	}
+	send(StreamEvent{Type: "done", Count: count, Pages: pages, Total: len(urls), DurationMs: time.Since(start).Milliseconds()})
+	if ctx.Err() == nil {
+		_ = conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(streamWriteWait))
+	}
+}
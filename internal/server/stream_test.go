@@ -0,0 +1,152 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// dialStream opens GET /ws/scrape on srv with the query q.
+func dialStream(t *testing.T, srv *httptest.Server, q url.Values) *websocket.Conn {
+	t.Helper()
+	conn, resp, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/ws/scrape?"+q.Encode(), nil)
+	if err != nil {
+		t.Fatalf("dial: %v (response %v)", err, resp)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// readStream reads events until "done" and the close that follows it.
+func readStream(t *testing.T, conn *websocket.Conn) []StreamEvent {
+	t.Helper()
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var events []StreamEvent
+	for {
+		var ev StreamEvent
+		if err := conn.ReadJSON(&ev); err != nil {
+			if !websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+				t.Fatalf("read after %d events: %v", len(events), err)
+			}
+			return events
+		}
+		events = append(events, ev)
+	}
+}
+
+func TestStream(t *testing.T) {
+	items := map[string][]string{
+		"/one": {"One", "Two", "Three"},
+		"/two": {"Four", "Five"},
+	}
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for i, title := range items[r.URL.Path] {
+			fmt.Fprintf(w, `<a class="item" href="/%d">%s</a>`, i, title)
+		}
+	}))
+	defer site.Close()
+	srv := httptest.NewServer(newTestHandler(jobsConfig()))
+	defer srv.Close()
+
+	tests := []struct {
+		name          string
+		offset, count int
+	}{
+		{"all", 0, 0},
+		{"offset and count across pages", 2, 2},
+		{"count within the first page", 0, 1},
+		{"offset past the first page", 4, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := url.Values{
+				"url":      {site.URL + "/one\n" + site.URL + "/two"},
+				"selector": {".item"},
+				"offset":   {fmt.Sprint(tt.offset)},
+				"count":    {fmt.Sprint(tt.count)},
+			}
+			events := readStream(t, dialStream(t, srv, q))
+
+			// Each page's results come right before its "page" event, and
+			// "done" comes last.
+			var got, all []string
+			pending, pages := 0, 0
+			for i, ev := range events {
+				switch ev.Type {
+				case "result":
+					pending++
+					got = append(got, ev.Result.Title)
+				case "page":
+					pages++
+					if ev.Error != "" || ev.Count != pending || ev.Pages != pages || ev.Total != 2 {
+						t.Errorf("page event %+v after %d results, want count %d of page %d/2", ev, pending, pending, pages)
+					}
+					all = append(all, items[strings.TrimPrefix(ev.URL, site.URL)]...)
+					pending = 0
+				case "done":
+					if i != len(events)-1 {
+						t.Errorf("done is event %d of %d", i+1, len(events))
+					}
+					if ev.Count != len(got) || ev.Pages != 2 || ev.Total != 2 {
+						t.Errorf("done = %+v, want %d results of 2/2 pages", ev, len(got))
+					}
+				default:
+					t.Errorf("unexpected event %+v", ev)
+				}
+			}
+			if pending != 0 {
+				t.Errorf("%d results after the last page event", pending)
+			}
+
+			// Pages may finish in either order; offset and count apply to
+			// the results in the order they are sent.
+			want := all[min(tt.offset, len(all)):]
+			if tt.count > 0 && len(want) > tt.count {
+				want = want[:tt.count]
+			}
+			if fmt.Sprint(got) != fmt.Sprint(want) {
+				t.Errorf("results = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestStreamClientClose(t *testing.T) {
+	fetched, canceled := make(chan struct{}), make(chan struct{})
+	var once sync.Once
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			http.NotFound(w, r)
+			return
+		}
+		once.Do(func() { close(fetched) })
+		select {
+		case <-r.Context().Done():
+			close(canceled)
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer site.Close()
+	srv := httptest.NewServer(newTestHandler(jobsConfig()))
+	defer srv.Close()
+
+	conn := dialStream(t, srv, url.Values{"url": {site.URL + "/slow"}, "selector": {"a"}})
+	select {
+	case <-fetched:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the page was never fetched")
+	}
+	conn.Close()
+	select {
+	case <-canceled:
+	case <-time.After(2 * time.Second):
+		t.Fatal("closing the WebSocket didn't cancel the page fetch")
+	}
+}
@@ -0,0 +1,23 @@
+package server
+
+import "net/http"
+
+// BuildInfo identifies the running binary. main fills it from variables set
+// with -ldflags "-X main.version=... -X main.commit=... -X main.date=...".
+type BuildInfo struct {
+	Version string `json:"version"`
+	Commit  string `json:"commit"`
+	Date    string `json:"build_date"`
+}
+
+// Version handles GET /version with the BuildInfo as JSON. Fields that were
+// not set at build time read "dev".
+func (h *Handler) Version(w http.ResponseWriter, r *http.Request) {
+	info := h.build
+	for _, f := range []*string{&info.Version, &info.Commit, &info.Date} {
+		if *f == "" {
+			*f = "dev"
+		}
+	}
+	writeJSON(w, info)
+}
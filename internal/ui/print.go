@@ -69,6 +69,17 @@ func Progress(n, total int, url string, count int, durationMs int64, err error)
 	fmt.Fprintln(os.Stderr, line)
 }
 
+// Detail prints a note under the last progress line, only in verbose mode.
+//
+//	[1/3]  https://example.com    30 results  (120ms)
+//	          via http://10.0.0.5:3128
+func Detail(msg string) {
+	if Active != ModeVerbose {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "            %s\n", msg)
+}
+
 // Done prints the summary line after all URLs are processed.
 //
 //	done  55 results  1 error  (1.2s)
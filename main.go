@@ -1,23 +1,41 @@
 package main
 
 import (
+	"context"
 	"embed"
+	"errors"
+	"flag"
 	"fmt"
 	"html/template"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/GhanshyamJha05/WEB_SCRAPPER_Using-GO/internal/server"
-	"github.com/GhanshyamJha05/WEB_SCRAPPER_Using-GO/pkg/scraper"
+	"github.com/GhanshyamJha05/WEB_SCRAPPER_Using-GO/pkg/web"
 )
 
-// version is set at link time via -ldflags.
-var version = "dev"
+// Build info, set at link time via -ldflags, e.g.
+//
+//	go build -ldflags "-X main.version=v1.4.0 -X main.commit=$(git rev-parse --short HEAD) -X main.date=$(date -u +%FT%TZ)"
+var (
+	version = "dev"
+	commit  = "dev"
+	date    = "dev"
+)
 
-//go:embed api/templates/index.html
+//go:embed api/templates/index.html api/templates/error.html
 var templateFS embed.FS
 
 func main() {
+	addr := flag.String("addr", ":8080", "listen address, e.g. :9090 or 127.0.0.1:8080")
+	flag.Parse()
+
 	funcMap := template.FuncMap{
 		"add": func(a, b int) int { return a + b },
 	}
@@ -25,15 +43,47 @@ func main() {
 	if err != nil {
 		log.Fatalf("failed to parse template: %v", err)
 	}
+	if dir := os.Getenv(web.EnvTemplateDir); dir != "" {
+		custom, err := template.New("index.html").Funcs(funcMap).ParseFiles(filepath.Join(dir, "index.html"))
+		if err != nil {
+			log.Printf("%s: %v; using the embedded template", web.EnvTemplateDir, err)
+		} else {
+			tmpl = custom
+		}
+	}
 
-	cli := scraper.NewClient(scraper.DefaultConfig())
-	h := server.New(tmpl, cli)
-
-	http.Handle("/", h)
+	cfg := server.ConfigFromEnv()
+	cfg.Addr = *addr
+	cfg.Template = tmpl
+	cfg.ErrorTemplate = template.Must(template.ParseFS(templateFS, "api/templates/error.html"))
+	cfg.Build = server.BuildInfo{Version: version, Commit: commit, Date: date}
+	srv := server.NewServer(cfg)
 
-	fmt.Printf("Web Scraper %s - http://localhost:8080\n", version)
+	host := srv.Addr
+	if strings.HasPrefix(host, ":") {
+		host = "localhost" + host // ":8080" listens on all interfaces
+	}
+	fmt.Printf("Web Scraper %s - http://%s\n", version, host)
 	fmt.Println("Press Ctrl+C to stop")
-	if err := http.ListenAndServe(":8080", nil); err != nil {
+
+	// Shut down on Ctrl+C / SIGTERM so in-flight requests finish and the
+	// cache warmer and scheduled jobs stop.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	done := make(chan struct{}) // closed once Shutdown has returned
+	go func() {
+		defer close(done)
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			log.Printf("shutdown: %v", err)
+		}
+	}()
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 		log.Fatal(err)
 	}
+	// ListenAndServe returns as soon as Shutdown starts; wait for it to
+	// drain the in-flight requests before exiting.
+	<-done
 }
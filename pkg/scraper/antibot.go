@@ -0,0 +1,66 @@
+package scraper
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+)
+
+// ErrBlocked is returned when the target answered with an anti-bot
+// interstitial (Cloudflare, DDoS-Guard, ...) instead of the real page, so
+// callers don't mistake the challenge for a page their selector can't match.
+var ErrBlocked = errors.New("blocked by anti-bot protection")
+
+// sniffLen is how much of a body is searched for challenge markers.
+const sniffLen = 64 << 10
+
+// challengeTitles appear in the <title> of interstitial pages. They are safe
+// to look for in 200 responses too: real pages don't use them as titles.
+var challengeTitles = [][]byte{
+	[]byte("<title>just a moment...</title>"),
+	[]byte("<title>attention required! | cloudflare</title>"),
+	[]byte("<title>ddos-guard</title>"),
+	[]byte("checking your browser before accessing"),
+}
+
+// challengeMarkers identify challenge bodies on 403/429/503 responses. Some,
+// like Cloudflare's challenge-platform script, are also injected into normal
+// pages, so they are not used for 200 responses.
+var challengeMarkers = [][]byte{
+	[]byte("cf-browser-verification"),
+	[]byte("cf_chl_opt"),
+	[]byte("/cdn-cgi/challenge-platform/"),
+	[]byte("captcha-delivery.com"), // DataDome
+	[]byte("px-captcha"),           // PerimeterX
+}
+
+// blockedResponse reports whether a non-200 response is an anti-bot challenge.
+// It reads (and consumes) up to sniffLen bytes of the body.
+func blockedResponse(res *http.Response) bool {
+	if res.Header.Get("Cf-Mitigated") == "challenge" {
+		return true
+	}
+	switch res.StatusCode {
+	case http.StatusForbidden, http.StatusTooManyRequests, http.StatusServiceUnavailable:
+	default:
+		return false
+	}
+	head, _ := io.ReadAll(io.LimitReader(res.Body, sniffLen))
+	head = bytes.ToLower(head)
+	return containsAny(head, challengeTitles) || containsAny(head, challengeMarkers)
+}
+
+// blockedPage reports whether a 200 body is really a challenge interstitial.
+func blockedPage(body []byte) bool {
+	return containsAny(bytes.ToLower(body[:min(len(body), sniffLen)]), challengeTitles)
+}
+
+func containsAny(b []byte, needles [][]byte) bool {
+	for _, n := range needles {
+		if bytes.Contains(b, n) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,91 @@
+package scraper
+
+import (
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html/atom"
+)
+
+// repairSelector returns the first of selectorVariants that matches
+// something in doc, or "" when none does. It is only worth calling when
+// selector itself matched nothing, see Options.AutoFix.
+func repairSelector(doc *goquery.Document, selector string) string {
+	for _, v := range selectorVariants(selector) {
+		if doc.Find(v).Length() > 0 {
+			return v
+		}
+	}
+	return ""
+}
+
+// selectorVariants rewrites selector for the mistakes beginners make most,
+// most likely first:
+//
+//	.a.b   → .a .b   (meant a descendant, wrote a compound)
+//	.a .b  → .a.b    (the other way round)
+//	story  → .story  (forgot the dot; only for names that are no HTML element)
+//	story  → #story  (or the hash)
+//
+// Parts with attribute selectors, pseudo-classes or quotes are left alone.
+func selectorVariants(selector string) []string {
+	tokens := strings.Fields(selector)
+	if len(tokens) == 0 {
+		return nil
+	}
+	rewrite := func(fn func(tok string) string) string {
+		out := make([]string, len(tokens))
+		for i, tok := range tokens {
+			out[i] = tok
+			if !strings.ContainsAny(tok, `[]():"'`) {
+				out[i] = fn(tok)
+			}
+		}
+		return strings.Join(out, " ")
+	}
+
+	candidates := []string{
+		rewrite(func(tok string) string {
+			if strings.HasPrefix(tok, ".") && strings.Count(tok, ".") > 1 {
+				return strings.Join(strings.Split(tok, "."), " .")[1:]
+			}
+			return tok
+		}),
+		joinClasses(tokens),
+		rewrite(func(tok string) string { return bareName(tok, ".") }),
+		rewrite(func(tok string) string { return bareName(tok, "#") }),
+	}
+
+	seen := map[string]bool{strings.Join(tokens, " "): true}
+	var variants []string
+	for _, c := range candidates {
+		if c != "" && !seen[c] {
+			seen[c] = true
+			variants = append(variants, c)
+		}
+	}
+	return variants
+}
+
+// joinClasses turns a selector made only of single-class parts, ".a .b",
+// into the compound ".a.b"; anything else gives "".
+func joinClasses(tokens []string) string {
+	if len(tokens) < 2 {
+		return ""
+	}
+	for _, tok := range tokens {
+		if !strings.HasPrefix(tok, ".") || strings.Count(tok, ".") != 1 || !cssIdent.MatchString(tok[1:]) {
+			return ""
+		}
+	}
+	return strings.Join(tokens, "")
+}
+
+// bareName prefixes tok with prefix when it is a plain name that is not an
+// HTML element, such as "headline"; other tokens are returned unchanged.
+func bareName(tok, prefix string) string {
+	if !cssIdent.MatchString(tok) || atom.Lookup([]byte(strings.ToLower(tok))) != 0 {
+		return tok
+	}
+	return prefix + tok
+}
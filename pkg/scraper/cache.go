@@ -0,0 +1,69 @@
+package scraper
+
+import (
+	"net/http"
+	"sync"
+)
+
+// pageCache remembers the validators and body of recently fetched pages so a
+// repeat scrape can send a conditional GET and reuse the body on 304.
+// Entries are evicted oldest first once max is reached.
+type pageCache struct {
+	mu      sync.Mutex
+	max     int
+	entries map[string]cacheEntry
+	order   []string // keys, oldest first
+}
+
+type cacheEntry struct {
+	etag         string
+	lastModified string
+	page         RawPage
+}
+
+func newPageCache(max int) *pageCache {
+	return &pageCache{max: max, entries: make(map[string]cacheEntry)}
+}
+
+// lookup returns the cached entry for key and adds its validators to req.
+// The key is the page URL plus anything that changes the response, such as
+// the Accept-Language sent.
+func (pc *pageCache) lookup(key string, req *http.Request) (cacheEntry, bool) {
+	pc.mu.Lock()
+	e, ok := pc.entries[key]
+	pc.mu.Unlock()
+	if !ok {
+		return cacheEntry{}, false
+	}
+	if e.etag != "" {
+		req.Header.Set("If-None-Match", e.etag)
+	}
+	if e.lastModified != "" {
+		req.Header.Set("If-Modified-Since", e.lastModified)
+	}
+	return e, true
+}
+
+// store keeps page under key when the response carries a validator to
+// revalidate it with.
+func (pc *pageCache) store(key string, page RawPage, header http.Header) {
+	e := cacheEntry{
+		etag:         header.Get("ETag"),
+		lastModified: header.Get("Last-Modified"),
+		page:         page,
+	}
+	if e.etag == "" && e.lastModified == "" {
+		return
+	}
+
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	if _, ok := pc.entries[key]; !ok {
+		if len(pc.order) >= pc.max {
+			delete(pc.entries, pc.order[0])
+			pc.order = pc.order[1:]
+		}
+		pc.order = append(pc.order, key)
+	}
+	pc.entries[key] = e
+}
@@ -0,0 +1,67 @@
+package scraper
+
+import (
+	"context"
+	"io"
+	"net/http"
+)
+
+// CheckResult is the JSON body for GET /api/check.
+type CheckResult struct {
+	URL         string `json:"url"`
+	Reachable   bool   `json:"reachable"` // the server answered with a status below 400
+	Status      int    `json:"status,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	HTML        bool   `json:"html"` // ContentType is an HTML document
+	Error       string `json:"error,omitempty"`
+}
+
+// checkSniffLen is how much of a GET body Check reads, to let the server
+// finish the response without downloading the page.
+const checkSniffLen = 512
+
+// Check finds out whether pageURL answers and serves HTML, without parsing
+// it and without retries. It sends HEAD and falls back to a GET, reading at
+// most a few hundred bytes, when HEAD fails or is refused.
+func (c *Client) Check(ctx context.Context, pageURL string) CheckResult {
+	out := CheckResult{URL: pageURL}
+	hc, err := c.pageClient(ctx)
+	if err != nil {
+		out.Error = err.Error()
+		return out
+	}
+
+	res, err := c.checkRequest(ctx, hc, http.MethodHead, pageURL)
+	if err != nil || res.StatusCode == http.StatusMethodNotAllowed || res.StatusCode == http.StatusNotImplemented {
+		res, err = c.checkRequest(ctx, hc, http.MethodGet, pageURL)
+	}
+	if err != nil {
+		out.Error = err.Error()
+		return out
+	}
+
+	out.Status = res.StatusCode
+	out.Reachable = res.StatusCode < http.StatusBadRequest
+	out.ContentType = res.Header.Get("Content-Type")
+	out.HTML = isHTML(out.ContentType)
+	return out
+}
+
+// checkRequest sends one request for Check and closes the body, reading only
+// checkSniffLen bytes of it.
+func (c *Client) checkRequest(ctx context.Context, hc *http.Client, method, pageURL string) (*http.Response, error) {
+	req, err := c.newRequest(ctx, method, pageURL)
+	if err != nil {
+		return nil, err
+	}
+	res, err := hc.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.Header.Get("Content-Type") == "" && method == http.MethodGet {
+		head, _ := io.ReadAll(io.LimitReader(res.Body, checkSniffLen))
+		res.Header.Set("Content-Type", http.DetectContentType(head))
+	}
+	return res, nil
+}
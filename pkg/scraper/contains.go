@@ -0,0 +1,54 @@
+package scraper
+
+import (
+	"regexp"
+	"strings"
+)
+
+// containsPseudo starts a :contains() or :containsOwn() pseudo-class, which
+// keep the elements whose text holds the argument, ignoring case.
+var containsPseudo = regexp.MustCompile(`(?i)^:contains(own)?\(`)
+
+// cssStringEscaper escapes text for a double-quoted CSS string.
+var cssStringEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+// quoteContains quotes the unquoted text of every :contains() and
+// :containsOwn() in selector, so the jQuery habit of writing
+// a:contains(Download now) works: the selector engine takes an unquoted
+// argument only when it is a single word, and otherwise silently matches
+// nothing. Quoted arguments, and anything inside quotes, are left alone.
+func quoteContains(selector string) string {
+	var b strings.Builder
+	var quote byte
+	for i := 0; i < len(selector); i++ {
+		ch := selector[i]
+		switch {
+		case quote != 0:
+			if ch == '\\' && i+1 < len(selector) {
+				b.WriteByte(ch)
+				i++
+				ch = selector[i]
+			} else if ch == quote {
+				quote = 0
+			}
+		case ch == '"' || ch == '\'':
+			quote = ch
+		case ch == ':':
+			open := containsPseudo.FindString(selector[i:])
+			if open == "" {
+				break
+			}
+			rest := selector[i+len(open):]
+			end := strings.IndexByte(rest, ')')
+			arg := strings.TrimSpace(rest[:max(end, 0)])
+			if end < 0 || arg == "" || arg[0] == '"' || arg[0] == '\'' {
+				break
+			}
+			b.WriteString(open + `"` + cssStringEscaper.Replace(arg) + `")`)
+			i += len(open) + end
+			continue
+		}
+		b.WriteByte(ch)
+	}
+	return b.String()
+}
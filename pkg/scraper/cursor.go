@@ -0,0 +1,50 @@
+package scraper
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// cursorPrefix versions the cursor format, so it can change without old
+// tokens being misread.
+const cursorPrefix = "o1:"
+
+// errBadCursor is returned by ParseOptions for a cursor it didn't issue.
+var errBadCursor = errors.New("cursor is not a token from next_cursor of an earlier response")
+
+// EncodeCursor returns the opaque pagination token for the results starting
+// at offset. It holds only the position, so the server keeps no state; a
+// token is reused with the same scrape parameters it came from.
+func EncodeCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(cursorPrefix + strconv.Itoa(offset)))
+}
+
+// DecodeCursor returns the offset EncodeCursor put in token.
+func DecodeCursor(token string) (int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return 0, errBadCursor
+	}
+	s, ok := strings.CutPrefix(string(raw), cursorPrefix)
+	if !ok {
+		return 0, errBadCursor
+	}
+	offset, err := strconv.Atoi(s)
+	if err != nil || offset < 0 {
+		return 0, errBadCursor
+	}
+	return offset, nil
+}
+
+// SetNextCursor sets NextCursor when results past the ones in o are left:
+// offset is where o.Results start among total results. Call it after
+// Truncate, so the next page picks up the results it dropped.
+func (o *ScrapeOutput) SetNextCursor(offset, total int) {
+	next := min(max(offset, 0), total) + len(o.Results)
+	o.NextCursor = ""
+	if next < total {
+		o.NextCursor = EncodeCursor(next)
+	}
+}
@@ -0,0 +1,42 @@
+package scraper
+
+import "strings"
+
+// Dedup modes accepted by the dedupMode parameter. They decide when two
+// results count as the same, comparing title and link.
+const (
+	DedupExact = "exact" // identical title and link (the default)
+	DedupTrim  = "trim"  // equal once runs of whitespace are collapsed
+	DedupLower = "lower" // like trim, ignoring case as well
+)
+
+// dedupKey is what results are compared by under mode.
+func dedupKey(r ScrapeResult, mode string) string {
+	title, link := r.Title, r.Link
+	switch mode {
+	case DedupTrim:
+		title, link = strings.Join(strings.Fields(title), " "), strings.TrimSpace(link)
+	case DedupLower:
+		title, link = strings.ToLower(strings.Join(strings.Fields(title), " ")), strings.ToLower(strings.TrimSpace(link))
+	}
+	return title + "\x00" + link
+}
+
+// dedupResults drops results whose key under Options.DedupMode was already
+// seen, keeping the first. It does nothing unless Options.Dedup is set.
+func (o Options) dedupResults(results []ScrapeResult) []ScrapeResult {
+	if !o.Dedup {
+		return results
+	}
+	seen := make(map[string]bool, len(results))
+	kept := results[:0]
+	for _, r := range results {
+		key := dedupKey(r, o.DedupMode)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		kept = append(kept, r)
+	}
+	return kept
+}
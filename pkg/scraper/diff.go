@@ -0,0 +1,37 @@
+package scraper
+
+// ResultDiff lists the results that appeared or disappeared between two runs.
+type ResultDiff struct {
+	Added   []ScrapeResult `json:"added"`
+	Removed []ScrapeResult `json:"removed"`
+}
+
+// Diff compares two runs of the same scrape. Results are matched on title and
+// link, so a changed link shows up as one removal plus one addition.
+// Both slices in the returned diff are non-nil and keep their input order.
+func Diff(prev, curr []ScrapeResult) ResultDiff {
+	d := ResultDiff{Added: []ScrapeResult{}, Removed: []ScrapeResult{}}
+
+	before := make(map[diffKey]bool, len(prev))
+	for _, r := range prev {
+		before[keyOf(r)] = true
+	}
+	after := make(map[diffKey]bool, len(curr))
+	for _, r := range curr {
+		k := keyOf(r)
+		after[k] = true
+		if !before[k] {
+			d.Added = append(d.Added, r)
+		}
+	}
+	for _, r := range prev {
+		if !after[keyOf(r)] {
+			d.Removed = append(d.Removed, r)
+		}
+	}
+	return d
+}
+
+type diffKey struct{ title, link string }
+
+func keyOf(r ScrapeResult) diffKey { return diffKey{r.Title, r.Link} }
@@ -0,0 +1,184 @@
+package scraper
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Environment variables read by ConfigFromEnv.
+const (
+	EnvWorkers      = "SCRAPER_WORKERS"                 // Config.WorkerCount
+	EnvMaxWorkers   = "SCRAPER_MAX_WORKERS"             // Config.MaxWorkers
+	EnvMaxBodyBytes = "SCRAPER_MAX_BODY_BYTES"          // Config.MaxBodyBytes, in bytes
+	EnvMaxOutput    = "SCRAPER_MAX_OUTPUT_BYTES"        // Config.MaxOutputBytes, in bytes; "0" is unlimited
+	EnvMaxResBytes  = "SCRAPER_MAX_RESULT_BYTES"        // Config.MaxResultBytes, in bytes; "0" is unlimited
+	EnvMaxResults   = "SCRAPER_MAX_TOTAL_RESULTS"       // Config.MaxTotalResults; "0" is unlimited
+	EnvAllowHosts   = "SCRAPER_ALLOW_HOSTS"             // HostPolicy.Allow, comma-separated
+	EnvDenyHosts    = "SCRAPER_DENY_HOSTS"              // HostPolicy.Deny, comma-separated
+	EnvAllowPrivate = "SCRAPER_ALLOW_PRIVATE"           // "true" disables HostPolicy.BlockPrivate
+	EnvCrawlDelay   = "SCRAPER_CRAWL_DELAY"             // Config.CrawlDelay, a Go duration such as "1s"; "0" disables
+	EnvPageTimeout  = "SCRAPER_PAGE_TIMEOUT"            // Config.PageTimeout, a Go duration; "0" disables
+	EnvMaxRetries   = "SCRAPER_MAX_RETRIES"             // Config.MaxRetries; "1" turns retries off
+	EnvRetryStatus  = "SCRAPER_RETRY_STATUSES"          // Config.RetryStatuses, e.g. "429,502,503,504"
+	EnvRenderURL    = "SCRAPER_RENDER_URL"              // Config.RenderURL
+	EnvUserAgent    = "SCRAPER_USER_AGENT"              // Config.UserAgent
+	EnvAcceptLang   = "SCRAPER_ACCEPT_LANGUAGE"         // Config.AcceptLanguage; defaults to the locale in LANG
+	EnvMaxConns     = "SCRAPER_MAX_CONNS_PER_HOST"      // Config.MaxConnsPerHost; "0" is unlimited
+	EnvMaxIdleConns = "SCRAPER_MAX_IDLE_CONNS_PER_HOST" // Config.MaxIdlePerHost
+	EnvLinkAttrs    = "SCRAPER_LINK_ATTRS"              // Config.LinkAttrs, comma-separated
+	EnvAllowAttrs   = "SCRAPER_ALLOWED_ATTRS"           // Config.AllowedAttrs, comma-separated; unset allows all
+	EnvAllowInsec   = "SCRAPER_ALLOW_INSECURE"          // "true" sets Config.AllowInsecure
+	EnvParseWorkers = "SCRAPER_PARSE_WORKERS"           // Config.ParseWorkers
+	EnvHostHeaders  = "SCRAPER_HOST_HEADERS"            // Config.HostHeaders as JSON, e.g. {"example.com":{"Referer":"https://example.com/"}}
+	EnvHeadersFile  = "SCRAPER_HOST_HEADERS_FILE"       // file holding that JSON; used when SCRAPER_HOST_HEADERS is unset
+	EnvProxies      = "SCRAPER_PROXIES"                 // Config.Proxies, comma- or newline-separated, see ParseProxies
+	EnvProxyRotate  = "SCRAPER_PROXY_ROTATION"          // Config.ProxyRotation: "roundrobin" or "random"
+)
+
+// ConfigFromEnv returns DefaultConfig with any values overridden by the
+// SCRAPER_* environment variables. Unset or malformed values keep the default;
+// malformed host headers and proxies are also logged.
+//
+// Because it is meant for servers reachable by others, ConfigFromEnv blocks
+// private and loopback addresses unless SCRAPER_ALLOW_PRIVATE=true.
+func ConfigFromEnv() Config {
+	cfg := DefaultConfig()
+	cfg.RenderURL = os.Getenv(EnvRenderURL)
+	cfg.UserAgent = os.Getenv(EnvUserAgent)
+	cfg.AcceptLanguage = os.Getenv(EnvAcceptLang)
+	cfg.LinkAttrs = splitList(os.Getenv(EnvLinkAttrs))
+	cfg.AllowedAttrs = splitList(os.Getenv(EnvAllowAttrs))
+	if cfg.AcceptLanguage == "" {
+		cfg.AcceptLanguage = localeLanguage(os.Getenv("LANG"))
+	}
+	cfg.HostPolicy = HostPolicy{
+		Allow:        splitList(os.Getenv(EnvAllowHosts)),
+		Deny:         splitList(os.Getenv(EnvDenyHosts)),
+		BlockPrivate: !envBool(EnvAllowPrivate),
+	}
+	cfg.AllowInsecure = envBool(EnvAllowInsec)
+	if n, ok := envInt64(EnvWorkers); ok && n > 0 {
+		cfg.WorkerCount = int(n)
+	}
+	if n, ok := envInt64(EnvMaxWorkers); ok && n >= 0 {
+		cfg.MaxWorkers = int(n)
+	}
+	if n, ok := envInt64(EnvMaxBodyBytes); ok && n > 0 {
+		cfg.MaxBodyBytes = n
+	}
+	if n, ok := envInt64(EnvMaxOutput); ok && n >= 0 {
+		cfg.MaxOutputBytes = n
+	}
+	if n, ok := envInt64(EnvMaxResBytes); ok && n >= 0 {
+		cfg.MaxResultBytes = n
+	}
+	if n, ok := envInt64(EnvMaxResults); ok && n >= 0 {
+		cfg.MaxTotalResults = int(n)
+	}
+	if n, ok := envInt64(EnvMaxConns); ok && n >= 0 {
+		cfg.MaxConnsPerHost = int(n)
+	}
+	if n, ok := envInt64(EnvParseWorkers); ok && n > 0 {
+		cfg.ParseWorkers = int(n)
+	}
+	if n, ok := envInt64(EnvMaxIdleConns); ok && n > 0 {
+		cfg.MaxIdlePerHost = int(n)
+	}
+	if d, err := time.ParseDuration(os.Getenv(EnvCrawlDelay)); err == nil && d >= 0 {
+		cfg.CrawlDelay = d
+	}
+	if d, err := time.ParseDuration(os.Getenv(EnvPageTimeout)); err == nil && d >= 0 {
+		cfg.PageTimeout = d
+	}
+	if n, ok := envInt64(EnvMaxRetries); ok && n > 0 {
+		cfg.MaxRetries = int(n)
+	}
+	if codes, ok := envStatuses(EnvRetryStatus); ok {
+		cfg.RetryStatuses = codes
+	}
+	if hh, err := envHostHeaders(); err != nil {
+		log.Printf("ignoring host headers: %v", err)
+	} else {
+		cfg.HostHeaders = hh
+	}
+	if proxies, err := ParseProxies(os.Getenv(EnvProxies)); err != nil {
+		log.Printf("ignoring proxies: %v", err)
+	} else {
+		cfg.Proxies = proxies
+	}
+	if r := strings.ToLower(os.Getenv(EnvProxyRotate)); r == ProxyRandom || r == ProxyRoundRobin {
+		cfg.ProxyRotation = r
+	}
+	return cfg
+}
+
+// envHostHeaders reads SCRAPER_HOST_HEADERS, else the file named by
+// SCRAPER_HOST_HEADERS_FILE. Unlike other variables a malformed value is
+// reported, since silently dropping a site's headers is hard to notice.
+func envHostHeaders() (HostHeaders, error) {
+	data := []byte(os.Getenv(EnvHostHeaders))
+	if len(data) == 0 {
+		path := os.Getenv(EnvHeadersFile)
+		if path == "" {
+			return nil, nil
+		}
+		var err error
+		if data, err = os.ReadFile(path); err != nil {
+			return nil, err
+		}
+	}
+	return ParseHostHeaders(data)
+}
+
+// envInt64 parses the named environment variable as a base-10 integer.
+func envInt64(key string) (int64, bool) {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return n, true
+}
+
+// envStatuses parses a comma-separated list of HTTP status codes. It reports
+// false when the variable is unset or holds anything but codes 100-599.
+func envStatuses(key string) ([]int, bool) {
+	parts := splitList(os.Getenv(key))
+	if len(parts) == 0 {
+		return nil, false
+	}
+	codes := make([]int, 0, len(parts))
+	for _, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 100 || n > 599 {
+			return nil, false
+		}
+		codes = append(codes, n)
+	}
+	return codes, true
+}
+
+// localeLanguage turns a POSIX locale such as "fr_CH.UTF-8" into a language
+// tag ("fr-CH"). "C", "POSIX" and malformed values yield "".
+func localeLanguage(locale string) string {
+	locale, _, _ = strings.Cut(locale, ".")
+	locale, _, _ = strings.Cut(locale, "@")
+	tag := strings.ReplaceAll(locale, "_", "-")
+	if tag == "C" || tag == "POSIX" || !validLanguage(tag) {
+		return ""
+	}
+	return tag
+}
+
+// envBool reports whether the named environment variable is set to a true
+// value as understood by strconv.ParseBool ("1", "true", ...).
+func envBool(key string) bool {
+	b, _ := strconv.ParseBool(os.Getenv(key))
+	return b
+}
@@ -0,0 +1,68 @@
+package scraper
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"syscall"
+)
+
+// ErrorInfo describes a failed scrape for people: what kind of failure it
+// was and what to try next. The web UI's error page shows it.
+type ErrorInfo struct {
+	Kind       string // short title, e.g. "Page not found"
+	Suggestion string // next step for the user
+}
+
+// ExplainError classifies err, an error from a scrape, into an ErrorInfo.
+// Errors it doesn't recognise get a generic explanation.
+func ExplainError(err error) ErrorInfo {
+	var (
+		status  *StatusError
+		dnsErr  *net.DNSError
+		certErr *tls.CertificateVerificationError
+	)
+	switch {
+	case errors.Is(err, ErrHostNotAllowed):
+		return ErrorInfo{"Host not allowed", "This server's host policy refuses that address. Scrape a public site, or ask the operator to adjust SCRAPER_ALLOW_HOSTS / SCRAPER_DENY_HOSTS."}
+	case errors.Is(err, ErrBlocked):
+		return ErrorInfo{"Blocked by anti-bot protection", "The site answered with a challenge page instead of content. Wait a while before retrying, or try render=true if the server has a render service."}
+	case errors.Is(err, ErrDisallowedByRobots):
+		return ErrorInfo{"Disallowed by robots.txt", "The site asks crawlers not to fetch this page. Respect that, or drop robots=true (or polite=true) if you have permission."}
+	case errors.Is(err, ErrRedirectLoop):
+		return ErrorInfo{"Redirect loop", "The site keeps redirecting back to a page it already sent you through, so it never serves one; the error lists the cycle. Check the URL (http vs https, trailing slash, www) or tell the site's owner."}
+	case errors.Is(err, ErrNotHTML):
+		return ErrorInfo{"Not a web page", "The URL serves a file rather than HTML. Link to the page that lists it instead."}
+	case errors.Is(err, ErrResponseTooLarge):
+		return ErrorInfo{"Page too large", "The page is bigger than this server accepts (SCRAPER_MAX_BODY_BYTES). Try a more specific page, such as a single listing page."}
+	case errors.Is(err, ErrResultsTooLarge):
+		return ErrorInfo{"Matches too large", "The selector matched more text than this server keeps for one page (SCRAPER_MAX_RESULT_BYTES). Use a more specific selector, such as links instead of whole sections."}
+	case errors.Is(err, ErrNoRenderService), errors.Is(err, ErrInsecureNotAllowed):
+		return ErrorInfo{"Option not available", "This server is not set up for the requested option. Retry without it."}
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrorInfo{"Timed out", "The site took too long to answer. Retry later; if it stays slow, precheck=true fails faster on pages that can't be scraped."}
+	case errors.As(err, &certErr):
+		return ErrorInfo{"Certificate problem", "The site's TLS certificate could not be verified. Check the address, or use insecure=true for a trusted internal host if the server allows it."}
+	case errors.As(err, &dnsErr):
+		return ErrorInfo{"Host not found", "The domain name does not resolve. Check the URL for typos."}
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return ErrorInfo{"Connection refused", "Nothing is listening at that address. Check the URL and port; for an http:// URL try upgradeInsecure=true."}
+	case errors.As(err, &status):
+		switch {
+		case status.StatusCode == http.StatusNotFound || status.StatusCode == http.StatusGone:
+			return ErrorInfo{"Page not found", "The site has no page at that address. Check the URL."}
+		case status.StatusCode == http.StatusUnauthorized || status.StatusCode == http.StatusForbidden:
+			return ErrorInfo{"Access denied", "The page needs a login or refuses scrapers. Only public pages can be scraped."}
+		case status.StatusCode == http.StatusTooManyRequests && status.RetryAfter > 0:
+			return ErrorInfo{"Rate limited", "The site is throttling requests and asked to wait " + status.RetryAfter.String() + " before retrying."}
+		case status.StatusCode == http.StatusTooManyRequests:
+			return ErrorInfo{"Rate limited", "The site is throttling requests. Wait a minute before retrying."}
+		case status.StatusCode >= 500:
+			return ErrorInfo{"Site error", "The site itself failed to serve the page, even after retries. Try again later."}
+		}
+		return ErrorInfo{"Unexpected response", "The site answered " + status.Status + ". Check the URL."}
+	}
+	return ErrorInfo{"Scrape failed", "Check the URL and selector, then retry."}
+}
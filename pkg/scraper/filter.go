@@ -0,0 +1,62 @@
+package scraper
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// filterTerms splits a filter query into lower-case terms.
+func filterTerms(filter string) []string {
+	return strings.Fields(strings.ToLower(filter))
+}
+
+// relevance scores title against terms. Each term adds how often it occurs
+// plus up to 1 for how early it first appears, so "Go tips" outranks
+// "Tips and more tips for Go" for the query "go". It returns 0 unless every
+// term occurs in title.
+func relevance(title string, terms []string) float64 {
+	title = strings.ToLower(title)
+	n := utf8.RuneCountInString(title)
+	var score float64
+	for _, t := range terms {
+		i := strings.Index(title, t)
+		if i < 0 {
+			return 0
+		}
+		pos := float64(utf8.RuneCountInString(title[:i])) / float64(n)
+		score += float64(strings.Count(title, t)) + 1 - pos
+	}
+	return math.Round(score*1000) / 1000
+}
+
+// filterResults keeps the results whose title contains every term of
+// Options.Filter, setting Score when Options.Rank is on.
+func (o Options) filterResults(results []ScrapeResult) []ScrapeResult {
+	terms := filterTerms(o.Filter)
+	if len(terms) == 0 {
+		return results
+	}
+	kept := results[:0]
+	for _, r := range results {
+		score := relevance(r.Title, terms)
+		if score == 0 {
+			continue
+		}
+		if o.Rank {
+			r.Score = score
+		}
+		kept = append(kept, r)
+	}
+	return kept
+}
+
+// rankResults orders results by descending Score when Options.Rank is on,
+// keeping document order among equal scores.
+func (o Options) rankResults(results []ScrapeResult) {
+	if !o.Rank {
+		return
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Score > results[j].Score })
+}
@@ -0,0 +1,118 @@
+package scraper
+
+import (
+	"context"
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Forms is the forms of a page, returned for mode=forms.
+type Forms struct {
+	URL   string `json:"url"`
+	Forms []Form `json:"forms"`
+}
+
+// Form is one <form>, described well enough to submit it, e.g. with
+// Options.Login.
+type Form struct {
+	ID      string      `json:"id,omitempty"`
+	Name    string      `json:"name,omitempty"`
+	Action  string      `json:"action"`            // resolved against the final page URL; the page itself when empty
+	Method  string      `json:"method"`            // "GET" or "POST"
+	Enctype string      `json:"enctype,omitempty"` // only when not the default urlencoded
+	Fields  []FormField `json:"fields"`
+}
+
+// FormField is one named control submitted with a form.
+type FormField struct {
+	Name     string   `json:"name"`
+	Type     string   `json:"type"`               // input type ("text" when unset), "select", "textarea" or "button"
+	Value    string   `json:"value,omitempty"`    // value sent by default, e.g. a hidden CSRF token
+	Required bool     `json:"required,omitempty"` // has the required attribute
+	Options  []string `json:"options,omitempty"`  // values of a select's options
+}
+
+// ScrapeForms fetches pageURL and describes each of its forms. A form's
+// fields include controls placed outside it that name it with form="id".
+// Controls without a name are left out: browsers don't submit them.
+func (c *Client) ScrapeForms(ctx context.Context, pageURL string) (Forms, error) {
+	doc, page, err := c.document(ctx, pageURL)
+	if err != nil {
+		return Forms{}, err
+	}
+	base, _ := url.Parse(page.finalURL)
+
+	out := Forms{URL: pageURL, Forms: []Form{}}
+	doc.Find("form").Each(func(_ int, s *goquery.Selection) {
+		f := Form{
+			ID:      strings.TrimSpace(s.AttrOr("id", "")),
+			Name:    strings.TrimSpace(s.AttrOr("name", "")),
+			Action:  page.finalURL,
+			Method:  "GET",
+			Enctype: strings.ToLower(strings.TrimSpace(s.AttrOr("enctype", ""))),
+			Fields:  []FormField{},
+		}
+		if action := strings.TrimSpace(s.AttrOr("action", "")); action != "" {
+			f.Action = resolveLink(base, action)
+		}
+		if strings.EqualFold(strings.TrimSpace(s.AttrOr("method", "")), "post") {
+			f.Method = "POST"
+		}
+		if f.Enctype == "application/x-www-form-urlencoded" {
+			f.Enctype = ""
+		}
+		controls := s.Find("input, select, textarea, button")
+		if f.ID != "" {
+			controls = controls.AddSelection(doc.Find("input, select, textarea, button").FilterFunction(func(_ int, el *goquery.Selection) bool {
+				return el.AttrOr("form", "") == f.ID
+			}))
+		}
+		controls.Each(func(_ int, el *goquery.Selection) {
+			if field, ok := formField(el); ok {
+				f.Fields = append(f.Fields, field)
+			}
+		})
+		out.Forms = append(out.Forms, f)
+	})
+	return out, nil
+}
+
+// formField describes the form control el, or reports false when it has no
+// name.
+func formField(el *goquery.Selection) (FormField, bool) {
+	name := strings.TrimSpace(el.AttrOr("name", ""))
+	if name == "" {
+		return FormField{}, false
+	}
+	_, required := el.Attr("required")
+	field := FormField{Name: name, Required: required}
+	switch tag := goquery.NodeName(el); tag {
+	case "select":
+		field.Type = "select"
+		el.Find("option").Each(func(_ int, o *goquery.Selection) {
+			v, ok := o.Attr("value")
+			if !ok {
+				v = strings.TrimSpace(o.Text())
+			}
+			field.Options = append(field.Options, v)
+			if _, selected := o.Attr("selected"); selected || field.Value == "" && len(field.Options) == 1 {
+				field.Value = v
+			}
+		})
+	case "textarea":
+		field.Type = "textarea"
+		field.Value = el.Text()
+	case "button":
+		field.Type = "button"
+		field.Value = el.AttrOr("value", "")
+	default:
+		field.Type = strings.ToLower(strings.TrimSpace(el.AttrOr("type", "")))
+		if field.Type == "" {
+			field.Type = "text"
+		}
+		field.Value = el.AttrOr("value", "")
+	}
+	return field, true
+}
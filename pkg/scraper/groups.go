@@ -0,0 +1,216 @@
+package scraper
+
+import (
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+// SelectorGroup is one labeled part of a grouped selector such as
+// "headline:h2 a, byline:.author".
+type SelectorGroup struct {
+	Name     string
+	Selector string
+}
+
+// ResultGroup is the results of one SelectorGroup.
+type ResultGroup struct {
+	Name    string         `json:"name"`
+	Results []ScrapeResult `json:"results"`
+}
+
+var groupLabel = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+
+// pseudoClasses are the CSS pseudo-classes that can follow "tag:", so
+// "a:hover" or "li:nth-child(2)" are never mistaken for a label.
+var pseudoClasses = []string{
+	"active", "any-link", "checked", "contains", "containsown", "default", "defined",
+	"disabled", "empty", "enabled", "first", "first-child", "first-of-type", "focus",
+	"focus-visible", "focus-within", "has", "haschild", "hover", "in-range",
+	"indeterminate", "input", "invalid", "is", "lang", "last-child", "last-of-type",
+	"link", "matches", "matchesown", "not", "nth-child", "nth-last-child",
+	"nth-last-of-type", "nth-of-type", "only-child", "only-of-type", "optional",
+	"out-of-range", "placeholder-shown", "read-only", "read-write", "required", "root",
+	"scope", "selected", "target", "valid", "visited", "where",
+}
+
+// ParseSelectorGroups reads a selector of the form "name1:sel1,name2:sel2".
+// It reports false, meaning selector is a plain CSS selector, unless every
+// top-level comma-separated part has a label. A label is a word followed by
+// a colon that does not start a pseudo-class, so "a:hover, h2" stays plain.
+func ParseSelectorGroups(selector string) ([]SelectorGroup, bool) {
+	var groups []SelectorGroup
+	for _, part := range splitTopLevel(selector) {
+		name, sel, ok := strings.Cut(part, ":")
+		name, sel = strings.TrimSpace(name), strings.TrimSpace(sel)
+		if !ok || sel == "" || !groupLabel.MatchString(name) || isPseudo(sel) {
+			return nil, false
+		}
+		groups = append(groups, SelectorGroup{Name: name, Selector: sel})
+	}
+	return groups, len(groups) > 0
+}
+
+// isPseudo reports whether rest, the text after a colon, continues a
+// pseudo-class or pseudo-element rather than starting a selector.
+func isPseudo(rest string) bool {
+	if strings.HasPrefix(rest, ":") {
+		return true // ::before
+	}
+	end := strings.IndexFunc(rest, func(r rune) bool {
+		return !(r == '-' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z')
+	})
+	if end < 0 {
+		end = len(rest)
+	}
+	return slices.Contains(pseudoClasses, strings.ToLower(rest[:end]))
+}
+
+// splitTopLevel splits s on commas outside (), [] and quotes.
+func splitTopLevel(s string) []string {
+	var parts []string
+	depth, start := 0, 0
+	var quote rune
+	for i, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '(' || r == '[':
+			depth++
+		case r == ')' || r == ']':
+			depth--
+		case r == ',' && depth == 0:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// GroupResults splits results tagged with ScrapeResult.Group into one
+// ResultGroup per SelectorGroup, in selector order. Groups with no matches
+// are kept with empty Results.
+func GroupResults(groups []SelectorGroup, results []ScrapeResult) []ResultGroup {
+	out := make([]ResultGroup, 0, len(groups))
+	index := make(map[string]int, len(groups))
+	for _, g := range groups {
+		if _, dup := index[g.Name]; dup {
+			continue
+		}
+		index[g.Name] = len(out)
+		out = append(out, ResultGroup{Name: g.Name, Results: []ScrapeResult{}})
+	}
+	for _, r := range results {
+		if i, ok := index[r.Group]; ok {
+			out[i].Results = append(out[i].Results, r)
+		}
+	}
+	return out
+}
+
+// InternalGroup is the GroupByHost group of results without an absolute link.
+const InternalGroup = "internal"
+
+// GroupByHost sets each result's Group to the host of its Link, lower-cased
+// and without a leading "www.", or InternalGroup when the link is empty or
+// relative. Results are reordered so each host's results are together, hosts
+// in order of first appearance.
+func GroupByHost(results []ScrapeResult) {
+	var order []SelectorGroup
+	seen := map[string]bool{}
+	for i := range results {
+		host := InternalGroup
+		if u, err := url.Parse(results[i].Link); err == nil && u.Host != "" {
+			host = strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+		}
+		results[i].Group = host
+		if !seen[host] {
+			seen[host] = true
+			order = append(order, SelectorGroup{Name: host})
+		}
+	}
+	sortByGroup(order, results)
+}
+
+// sortByGroup orders results by the position of their group in groups,
+// keeping the order within each group.
+func sortByGroup(groups []SelectorGroup, results []ScrapeResult) {
+	rank := make(map[string]int, len(groups))
+	for i, g := range groups {
+		if _, dup := rank[g.Name]; !dup {
+			rank[g.Name] = i
+		}
+	}
+	slices.SortStableFunc(results, func(a, b ScrapeResult) int { return rank[a.Group] - rank[b.Group] })
+}
+
+// Record is one item of a grouped selector's matches, keyed by group name:
+// a match of the first group and, for every other group, the match that
+// shares its container, or nil (JSON null) when that group has none there.
+type Record map[string]*ScrapeResult
+
+// records pairs up the matches of groups on doc into Records, in document
+// order of the first group, the primary one. Each primary match's container
+// is its highest ancestor holding no other primary match, such as the <li>
+// or <article> of a story; each other group contributes its first match
+// inside that container, so a story without a byline gets a null byline
+// instead of its neighbour's. Primary matches that extract or
+// Options.Filter drop make no record.
+func (c *Client) records(doc *goquery.Document, groups []SelectorGroup, base *url.URL) []Record {
+	primary := doc.Find(groups[0].Selector)
+	// How many primary matches each node holds, so a container is found by
+	// climbing while the parent holds just the one.
+	held := make(map[*html.Node]int)
+	for _, n := range primary.Nodes {
+		for a := n; a != nil; a = a.Parent {
+			held[a]++
+		}
+	}
+
+	var out []Record
+	slot := make(map[*html.Node]int) // container → index in out
+	primary.Each(func(_ int, s *goquery.Selection) {
+		r, ok := c.extract(s, base)
+		if !ok || len(c.opts.filterResults([]ScrapeResult{r})) == 0 {
+			return
+		}
+		container := s.Get(0)
+		for container.Parent != nil && container.Parent.Type == html.ElementNode && held[container.Parent] == 1 {
+			container = container.Parent
+		}
+		rec := Record{groups[0].Name: &r}
+		for _, g := range groups[1:] {
+			if _, dup := rec[g.Name]; !dup {
+				rec[g.Name] = nil
+			}
+		}
+		slot[container] = len(out)
+		out = append(out, rec)
+	})
+
+	for _, g := range groups[1:] {
+		doc.Find(g.Selector).Each(func(_ int, s *goquery.Selection) {
+			for a := s.Get(0); a != nil; a = a.Parent {
+				i, ok := slot[a]
+				if !ok {
+					continue
+				}
+				if out[i][g.Name] == nil {
+					if r, ok := c.extract(s, base); ok {
+						out[i][g.Name] = &r
+					}
+				}
+				return
+			}
+		})
+	}
+	return out
+}
@@ -0,0 +1,98 @@
+package scraper
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/textproto"
+	"sort"
+	"strings"
+)
+
+// maxHeaders bounds the headers one request may add with the header
+// parameter.
+const maxHeaders = 20
+
+// HostHeaders maps a host to headers sent with every request to it, e.g.
+// {"example.com": {"Referer": "https://example.com/"}}. A host also covers
+// its subdomains, like HostPolicy; when several entries match, the most
+// specific wins for a header they both set.
+type HostHeaders map[string]map[string]string
+
+// ParseHostHeaders decodes HostHeaders from JSON and checks every header name.
+func ParseHostHeaders(data []byte) (HostHeaders, error) {
+	var hh HostHeaders
+	if err := json.Unmarshal(data, &hh); err != nil {
+		return nil, fmt.Errorf("host headers: %w", err)
+	}
+	for host, headers := range hh {
+		if strings.TrimSpace(host) == "" {
+			return nil, fmt.Errorf("host headers: empty host")
+		}
+		for name := range headers {
+			if err := checkHeaderName(name); err != nil {
+				return nil, fmt.Errorf("host headers for %s: %w", host, err)
+			}
+		}
+	}
+	return hh, nil
+}
+
+// apply sets the headers configured for host on h, least specific entry first.
+func (hh HostHeaders) apply(h http.Header, host string) {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	var matched []string
+	for pattern := range hh {
+		if matchHost(host, pattern) {
+			matched = append(matched, pattern)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool { return len(matched[i]) < len(matched[j]) })
+	for _, pattern := range matched {
+		for name, value := range hh[pattern] {
+			h.Set(name, value)
+		}
+	}
+}
+
+// ParseHeaders reads header parameters of the form "Name: value", e.g.
+// "Referer: https://example.com/". A repeated name keeps the last value.
+func ParseHeaders(raw []string) (http.Header, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	if len(raw) > maxHeaders {
+		return nil, fmt.Errorf("too many headers: %d (max %d)", len(raw), maxHeaders)
+	}
+	h := make(http.Header, len(raw))
+	for _, entry := range raw {
+		name, value, ok := strings.Cut(entry, ":")
+		name = strings.TrimSpace(name)
+		if !ok {
+			return nil, fmt.Errorf("header %q: want \"Name: value\"", entry)
+		}
+		if err := checkHeaderName(name); err != nil {
+			return nil, err
+		}
+		if strings.ContainsAny(value, "\r\n") {
+			return nil, fmt.Errorf("header %s: value contains a line break", name)
+		}
+		h.Set(name, strings.TrimSpace(value))
+	}
+	return h, nil
+}
+
+// checkHeaderName rejects malformed names and the headers the HTTP client
+// manages itself.
+func checkHeaderName(name string) error {
+	if name == "" || strings.ContainsFunc(name, func(r rune) bool {
+		return r <= ' ' || r >= 0x7f || strings.ContainsRune(`"(),/:;<=>?@[\]{}`, r)
+	}) {
+		return fmt.Errorf("invalid header name %q", name)
+	}
+	switch textproto.CanonicalMIMEHeaderKey(name) {
+	case "Host", "Content-Length", "Connection", "Transfer-Encoding", "Te", "Upgrade", "Trailer":
+		return fmt.Errorf("header %s cannot be set", name)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package scraper
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// isImage reports whether s is an <img> or a <picture> <source>, whose link
+// is the image itself, see imageLink.
+func isImage(s *goquery.Selection) bool {
+	switch goquery.NodeName(s) {
+	case "img", "source":
+		return true
+	}
+	return false
+}
+
+// imageLink is the link of an image element: the largest candidate of its
+// srcset, else its src. It is "" when the element has neither.
+func imageLink(s *goquery.Selection) string {
+	if best := bestSrcsetCandidate(s.AttrOr("srcset", "")); best != "" {
+		return best
+	}
+	return strings.TrimSpace(s.AttrOr("src", ""))
+}
+
+// imageTitle stands in for the text of an image element, which has none:
+// its alt text, else its title attribute.
+func imageTitle(s *goquery.Selection) string {
+	return firstNonEmpty(strings.TrimSpace(s.AttrOr("alt", "")), strings.TrimSpace(s.AttrOr("title", "")))
+}
+
+// bestSrcsetCandidate returns the URL of the highest-resolution candidate
+// in a srcset such as "a.jpg 480w, b.jpg 1080w" or "a.jpg, b.jpg 2x". Width
+// descriptors win over density ones, which a browser can only compare
+// against the layout size; a candidate without a descriptor counts as 1x.
+// Among equal candidates the first is kept.
+func bestSrcsetCandidate(srcset string) string {
+	var best string
+	bestW, bestX := -1.0, -1.0
+	for _, c := range parseSrcset(srcset) {
+		desc := strings.ToLower(c.descriptor)
+		switch {
+		case strings.HasSuffix(desc, "w"):
+			if w, err := strconv.ParseFloat(desc[:len(desc)-1], 64); err == nil && w > bestW {
+				best, bestW = c.url, w
+			}
+		case bestW < 0:
+			x := 1.0
+			if strings.HasSuffix(desc, "x") {
+				var err error
+				if x, err = strconv.ParseFloat(desc[:len(desc)-1], 64); err != nil {
+					continue
+				}
+			} else if desc != "" {
+				continue // a height ("h") or unknown descriptor
+			}
+			if x > bestX {
+				best, bestX = c.url, x
+			}
+		}
+	}
+	return best
+}
+
+// srcsetCandidate is one "url descriptor" entry of a srcset.
+type srcsetCandidate struct {
+	url, descriptor string
+}
+
+// parseSrcset splits a srcset into its candidates the way the HTML spec
+// does: a URL runs to the next whitespace, so commas inside it (as in data:
+// URLs) are kept, and a comma ends the descriptor that follows it.
+func parseSrcset(srcset string) []srcsetCandidate {
+	var out []srcsetCandidate
+	for s := srcset; ; {
+		s = strings.TrimLeft(s, " \t\n\r\f,")
+		if s == "" {
+			return out
+		}
+		end := strings.IndexAny(s, " \t\n\r\f")
+		if end < 0 {
+			end = len(s)
+		}
+		c := srcsetCandidate{url: s[:end]}
+		s = s[end:]
+		if trimmed := strings.TrimRight(c.url, ","); trimmed != c.url {
+			// "a.jpg, b.jpg": the comma ended a candidate with no descriptor.
+			c.url = trimmed
+		} else {
+			desc, rest, _ := strings.Cut(s, ",")
+			c.descriptor, s = strings.TrimSpace(desc), rest
+		}
+		if f := strings.Fields(c.descriptor); len(f) > 0 {
+			c.descriptor = f[0]
+		}
+		out = append(out, c)
+	}
+}
@@ -0,0 +1,107 @@
+package scraper
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// Request body types accepted by the contentType parameter for mode=api.
+const (
+	ContentTypeForm = "application/x-www-form-urlencoded" // default; body is sent as written, e.g. "q=go&page=2"
+	ContentTypeJSON = "application/json"                  // body must be valid JSON, e.g. a GraphQL {"query": ...}
+)
+
+// maxRequestBody caps Options.Body, in bytes.
+const maxRequestBody = 64 << 10
+
+// ErrNotJSON is returned by ScrapeAPI when the endpoint answers with
+// something other than JSON.
+var ErrNotJSON = errors.New("response is not JSON")
+
+// APIData is what a JSON API answered, returned for mode=api.
+type APIData struct {
+	URL    string `json:"url"`
+	Method string `json:"method"`
+	Path   string `json:"path,omitempty"`
+	Values []any  `json:"values"` // what Path selects in the response
+}
+
+// parseContentType maps the contentType parameter to ContentTypeForm or
+// ContentTypeJSON; "json" and "form" are short for them.
+func parseContentType(raw string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(raw)) {
+	case "", "form", ContentTypeForm:
+		return ContentTypeForm, nil
+	case "json", ContentTypeJSON:
+		return ContentTypeJSON, nil
+	}
+	return "", fmt.Errorf("unknown contentType %q: use \"json\" or \"form\"", raw)
+}
+
+// ScrapeAPI queries endpoint as a JSON API and collects what path selects
+// in its answer; see ParseJSONPath. With Options.Body set the body is
+// POSTed as Options.ContentType, once, since a POST may not be safe to
+// repeat; otherwise the endpoint is fetched with GET and retried like a
+// page. The answer must be JSON, whatever Content-Type it claims.
+func (c *Client) ScrapeAPI(ctx context.Context, endpoint, path string) (APIData, error) {
+	keys, err := ParseJSONPath(path)
+	if err != nil {
+		return APIData{}, err
+	}
+	hc, err := c.pageClient(ctx)
+	if err != nil {
+		return APIData{}, err
+	}
+
+	method, attempts := http.MethodGet, c.cfg.MaxRetries
+	if c.opts.Body != "" {
+		method, attempts = http.MethodPost, 1
+	}
+	req, err := c.newRequest(ctx, method, endpoint)
+	if err != nil {
+		return APIData{}, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if method == http.MethodPost {
+		body := c.opts.Body
+		req.Body = io.NopCloser(strings.NewReader(body))
+		req.GetBody = func() (io.ReadCloser, error) { return io.NopCloser(strings.NewReader(body)), nil }
+		req.ContentLength = int64(len(body))
+		ct := c.opts.ContentType
+		if ct == "" {
+			ct = ContentTypeForm
+		}
+		req.Header.Set("Content-Type", ct)
+	}
+
+	res, err := withRetry(ctx, attempts, c.cfg.BaseRetryDelay, c.cfg.RetryStatuses, func() (*http.Response, error) {
+		return hc.Do(req)
+	})
+	if err != nil {
+		return APIData{}, fmt.Errorf("%s: %w", endpoint, err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return APIData{}, newStatusError(res)
+	}
+	raw, err := readBody(res.Body, c.cfg.MaxBodyBytes)
+	if err != nil {
+		return APIData{}, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber() // keep ids and prices exactly as written
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		mediaType, _, _ := mime.ParseMediaType(res.Header.Get("Content-Type"))
+		return APIData{}, fmt.Errorf("%s: %w (Content-Type %q): %w", endpoint, ErrNotJSON, mediaType, err)
+	}
+	return APIData{URL: endpoint, Method: method, Path: strings.TrimSpace(path), Values: append([]any{}, selectJSON(v, keys)...)}, nil
+}
@@ -0,0 +1,66 @@
+package scraper
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// DefaultLinkSchemes are the schemes a result's link may have when
+// Options.LinkSchemes is nil.
+var DefaultLinkSchemes = []string{"http", "https"}
+
+// AnyLinkScheme in Options.LinkSchemes keeps every link, as before the
+// filter existed.
+const AnyLinkScheme = "any"
+
+var schemeName = regexp.MustCompile(`^[a-z][a-z0-9+.-]*$`)
+
+// parseLinkSchemes reads the linkSchemes parameter, e.g. "http,https,mailto".
+func parseLinkSchemes(raw string) ([]string, error) {
+	schemes := splitList(raw)
+	for i, s := range schemes {
+		s = strings.TrimSuffix(s, ":") // "mailto:" as written in a link
+		if s != AnyLinkScheme && !schemeName.MatchString(s) {
+			return nil, fmt.Errorf("linkSchemes: %q is not a URL scheme", s)
+		}
+		schemes[i] = s
+	}
+	return schemes, nil
+}
+
+// linkAllowed reports whether an element with link href, as found before
+// resolving, may become a result under Options.LinkSchemes. An element
+// carrying none of attrs is a text result and always allowed; one whose
+// link attribute is empty or just "#" leads nowhere and is dropped, as is
+// a link with a scheme not listed. Relative links count as http(s).
+func (o Options) linkAllowed(s *goquery.Selection, href string, attrs []string) bool {
+	schemes := o.LinkSchemes
+	if schemes == nil {
+		schemes = DefaultLinkSchemes
+	}
+	if slices.Contains(schemes, AnyLinkScheme) {
+		return true
+	}
+	switch href {
+	case "":
+		return !slices.ContainsFunc(attrs, func(name string) bool {
+			_, ok := s.Attr(name)
+			return ok
+		})
+	case "#":
+		return false
+	}
+	u, err := url.Parse(href)
+	if err != nil {
+		return false
+	}
+	if u.Scheme == "" {
+		return slices.Contains(schemes, "http") || slices.Contains(schemes, "https")
+	}
+	return slices.Contains(schemes, strings.ToLower(u.Scheme))
+}
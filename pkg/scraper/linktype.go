@@ -0,0 +1,173 @@
+package scraper
+
+import (
+	"context"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+)
+
+// Link types set in ScrapeResult.LinkType by Options.ClassifyLinks.
+const (
+	LinkPage     = "page"     // an HTML page
+	LinkPDF      = "pdf"      // application/pdf
+	LinkImage    = "image"    // image/*
+	LinkVideo    = "video"    // video/*
+	LinkAudio    = "audio"    // audio/*
+	LinkDocument = "document" // office documents, plain text, CSV
+	LinkArchive  = "archive"  // zip, gzip, tar, 7z, rar
+	LinkData     = "data"     // JSON, XML, feeds
+	LinkOther    = "other"    // any other content type
+	LinkUnknown  = "unknown"  // the HEAD request failed
+)
+
+const (
+	classifyWorkers   = 8    // HEAD requests in flight while classifying links
+	linkTypeCacheSize = 4096 // classified URLs remembered per Client
+)
+
+// linkTypeCache remembers the LinkType of URLs across scrapes, evicting the
+// oldest once full. Failed lookups are not cached.
+type linkTypeCache struct {
+	mu      sync.Mutex
+	entries map[string]string
+	order   []string // keys, oldest first
+}
+
+func newLinkTypeCache() *linkTypeCache {
+	return &linkTypeCache{entries: make(map[string]string)}
+}
+
+func (lc *linkTypeCache) get(link string) (string, bool) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	t, ok := lc.entries[link]
+	return t, ok
+}
+
+func (lc *linkTypeCache) put(link, linkType string) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	if _, ok := lc.entries[link]; !ok {
+		if len(lc.order) >= linkTypeCacheSize {
+			delete(lc.entries, lc.order[0])
+			lc.order = lc.order[1:]
+		}
+		lc.order = append(lc.order, link)
+	}
+	lc.entries[link] = linkType
+}
+
+// classifyLinks sets LinkType on every result with an absolute http(s)
+// link, sending one HEAD per distinct uncached link, classifyWorkers at a
+// time. Links outside the HostPolicy are left unclassified.
+func (c *Client) classifyLinks(ctx context.Context, results []ScrapeResult) {
+	types := map[string]string{}
+	var pending []string
+	for _, r := range results {
+		if _, done := types[r.Link]; done || !classifiable(r.Link) {
+			continue
+		}
+		if t, ok := c.linkTypes.get(r.Link); ok {
+			types[r.Link] = t
+			continue
+		}
+		types[r.Link] = ""
+		pending = append(pending, r.Link)
+	}
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		jobs = make(chan string)
+	)
+	for range min(classifyWorkers, len(pending)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for link := range jobs {
+				t := c.linkType(ctx, link)
+				mu.Lock()
+				types[link] = t
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, link := range pending {
+		jobs <- link
+	}
+	close(jobs)
+	wg.Wait()
+
+	for i := range results {
+		results[i].LinkType = types[results[i].Link]
+	}
+}
+
+// classifiable reports whether link is an absolute http(s) URL.
+func classifiable(link string) bool {
+	u, err := url.Parse(link)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// linkType HEADs link and classifies its Content-Type, falling back to the
+// file extension when the server sends none.
+func (c *Client) linkType(ctx context.Context, link string) string {
+	u, _ := url.Parse(link)
+	if c.cfg.HostPolicy.checkHost(u.Hostname()) != nil {
+		return ""
+	}
+	hc, err := c.pageClient(ctx)
+	if err != nil {
+		return LinkUnknown
+	}
+	req, err := c.newRequest(ctx, http.MethodHead, link)
+	if err != nil {
+		return LinkUnknown
+	}
+	res, err := hc.Do(req)
+	if err != nil {
+		return LinkUnknown
+	}
+	res.Body.Close()
+	if res.StatusCode >= http.StatusBadRequest && res.StatusCode != http.StatusMethodNotAllowed {
+		return LinkUnknown
+	}
+	ct := res.Header.Get("Content-Type")
+	if ct == "" || res.StatusCode == http.StatusMethodNotAllowed {
+		ct = mime.TypeByExtension(path.Ext(u.Path))
+	}
+	t := classifyContentType(ct)
+	c.linkTypes.put(link, t)
+	return t
+}
+
+// classifyContentType maps a Content-Type header to a link type.
+func classifyContentType(contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return LinkOther
+	}
+	major, minor, _ := strings.Cut(mediaType, "/")
+	switch {
+	case isHTML(mediaType):
+		return LinkPage
+	case mediaType == "application/pdf":
+		return LinkPDF
+	case major == "image" || major == "video" || major == "audio":
+		return major
+	case mediaType == "text/plain" || mediaType == "text/csv" || mediaType == "application/rtf" ||
+		mediaType == "application/msword" || strings.HasPrefix(minor, "vnd.openxmlformats") ||
+		strings.HasPrefix(minor, "vnd.ms-") || strings.HasPrefix(minor, "vnd.oasis.opendocument"):
+		return LinkDocument
+	case strings.Contains(minor, "zip") || strings.Contains(minor, "tar") || minor == "x-7z-compressed" ||
+		minor == "vnd.rar" || minor == "x-rar-compressed":
+		return LinkArchive
+	case strings.Contains(minor, "json") || strings.Contains(minor, "xml"):
+		return LinkData
+	}
+	return LinkOther
+}
@@ -0,0 +1,100 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// ErrLoginFailed is returned when Options.Login is set and the login request
+// fails or yields no session cookie.
+var ErrLoginFailed = errors.New("login failed")
+
+// Default form field names for Login.
+const (
+	DefaultLoginUserField = "username"
+	DefaultLoginPassField = "password"
+)
+
+// Login describes a login form to submit before scraping: User and Pass are
+// POSTed as UserField and PassField to URL, and the session cookies it sets
+// are sent with every request of the scrape.
+type Login struct {
+	URL       string
+	UserField string
+	PassField string
+	User      string
+	Pass      string
+}
+
+// String describes l without its password, so logging a Login or an Options
+// holding one can't leak it.
+func (l Login) String() string {
+	return fmt.Sprintf("login to %s as %s", l.URL, l.User)
+}
+
+// GoString is String, for %#v.
+func (l Login) GoString() string { return l.String() }
+
+// loginSession logs in once, on the first request of a scrape, and holds
+// the resulting cookie-carrying client for the rest of it.
+type loginSession struct {
+	once   sync.Once
+	client *http.Client
+	err    error
+}
+
+// sessionClient returns hc with a cookie jar holding the session of
+// c.opts.Login, logging in on the first call.
+func (c *Client) sessionClient(ctx context.Context, hc *http.Client) (*http.Client, error) {
+	c.session.once.Do(func() {
+		c.session.client, c.session.err = c.login(ctx, hc, *c.opts.Login)
+	})
+	return c.session.client, c.session.err
+}
+
+// login POSTs l's credentials with a fresh cookie jar and returns a copy of
+// hc using that jar. Errors name the login URL, never the credentials.
+func (c *Client) login(ctx context.Context, hc *http.Client, l Login) (*http.Client, error) {
+	if err := c.CheckURL(ctx, l.URL); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrLoginFailed, err)
+	}
+	jar, err := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+	if err != nil {
+		return nil, err
+	}
+	session := *hc
+	session.Jar = jar
+
+	body := url.Values{l.UserField: {l.User}, l.PassField: {l.Pass}}.Encode()
+	req, err := c.newRequest(ctx, http.MethodPost, l.URL)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrLoginFailed, err)
+	}
+	req.Body = io.NopCloser(strings.NewReader(body))
+	req.GetBody = func() (io.ReadCloser, error) { return io.NopCloser(strings.NewReader(body)), nil }
+	req.ContentLength = int64(len(body))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	res, err := session.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrLoginFailed, err)
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, c.cfg.MaxBodyBytes))
+	res.Body.Close()
+	if res.StatusCode >= http.StatusBadRequest {
+		return nil, fmt.Errorf("%w: %s answered %s", ErrLoginFailed, l.URL, res.Status)
+	}
+	if len(jar.Cookies(req.URL)) == 0 && len(jar.Cookies(res.Request.URL)) == 0 {
+		return nil, fmt.Errorf("%w: %s set no session cookie", ErrLoginFailed, l.URL)
+	}
+	return &session, nil
+}
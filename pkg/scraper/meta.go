@@ -0,0 +1,76 @@
+package scraper
+
+import (
+	"context"
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// MetaTags is the link-preview data of a page, returned for mode=meta.
+// Title, Description and Image prefer OpenGraph, then Twitter cards, then
+// the plain <title> and <meta name="description">.
+type MetaTags struct {
+	URL         string            `json:"url"`
+	Title       string            `json:"title,omitempty"`
+	Description string            `json:"description,omitempty"`
+	Image       string            `json:"image,omitempty"` // resolved against the final (post-redirect) page URL
+	OpenGraph   map[string]string `json:"og"`              // og:* properties, prefix stripped
+	Twitter     map[string]string `json:"twitter"`         // twitter:* names, prefix stripped
+}
+
+// ScrapeMetaTags fetches pageURL and reads its OpenGraph and Twitter card
+// <meta> tags. When a property repeats (e.g. several og:image), the first wins.
+func (c *Client) ScrapeMetaTags(ctx context.Context, pageURL string) (MetaTags, error) {
+	doc, page, err := c.document(ctx, pageURL)
+	if err != nil {
+		return MetaTags{}, err
+	}
+
+	m := MetaTags{
+		URL:       pageURL,
+		OpenGraph: metaValues(doc, "meta[property^='og:']", "property", "og:"),
+		Twitter:   metaValues(doc, "meta[name^='twitter:']", "name", "twitter:"),
+	}
+	// Many sites use name= for OpenGraph too; property= still wins.
+	for k, v := range metaValues(doc, "meta[name^='og:']", "name", "og:") {
+		if _, ok := m.OpenGraph[k]; !ok {
+			m.OpenGraph[k] = v
+		}
+	}
+
+	m.Title = firstNonEmpty(m.OpenGraph["title"], m.Twitter["title"], strings.TrimSpace(doc.Find("title").First().Text()))
+	desc, _ := doc.Find("meta[name='description']").First().Attr("content")
+	m.Description = firstNonEmpty(m.OpenGraph["description"], m.Twitter["description"], strings.TrimSpace(desc))
+	if img := firstNonEmpty(m.OpenGraph["image"], m.Twitter["image"]); img != "" {
+		base, _ := url.Parse(page.finalURL)
+		m.Image = resolveLink(base, img)
+	}
+	return m, nil
+}
+
+// metaValues maps the attr of every tag matched by sel, minus prefix, to its content.
+func metaValues(doc *goquery.Document, sel, attr, prefix string) map[string]string {
+	values := map[string]string{}
+	doc.Find(sel).Each(func(_ int, s *goquery.Selection) {
+		key := strings.TrimPrefix(strings.ToLower(s.AttrOr(attr, "")), prefix)
+		content := strings.TrimSpace(s.AttrOr("content", ""))
+		if key == "" || content == "" {
+			return
+		}
+		if _, ok := values[key]; !ok {
+			values[key] = content
+		}
+	})
+	return values
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
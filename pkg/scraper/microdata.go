@@ -0,0 +1,121 @@
+package scraper
+
+import (
+	"context"
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+// Microdata is the schema.org-style microdata of a page, returned for
+// mode=microdata.
+type Microdata struct {
+	URL   string      `json:"url"`
+	Items []MicroItem `json:"items"`
+}
+
+// MicroItem is one itemscope, in the shape of the WHATWG microdata JSON
+// conversion: each property maps to its values, which are strings or nested
+// MicroItems, in document order.
+type MicroItem struct {
+	Type       []string         `json:"type,omitempty"` // itemtype URLs, e.g. "https://schema.org/Product"
+	ID         string           `json:"id,omitempty"`   // itemid
+	Properties map[string][]any `json:"properties"`
+}
+
+// ScrapeMicrodata fetches pageURL and returns its top-level items: elements
+// with itemscope that are not themselves a property of another item. Nested
+// itemscopes become MicroItem values of the property they sit in. URL-valued
+// properties (href, src, ...) are resolved against the final page URL.
+// itemref is not followed.
+func (c *Client) ScrapeMicrodata(ctx context.Context, pageURL string) (Microdata, error) {
+	doc, page, err := c.document(ctx, pageURL)
+	if err != nil {
+		return Microdata{}, err
+	}
+	base, _ := url.Parse(page.finalURL)
+
+	out := Microdata{URL: pageURL, Items: []MicroItem{}}
+	doc.Find("[itemscope]").Not("[itemprop]").Each(func(_ int, s *goquery.Selection) {
+		out.Items = append(out.Items, microItem(s.Get(0), base))
+	})
+	return out, nil
+}
+
+// microItem reads the item rooted at n.
+func microItem(n *html.Node, base *url.URL) MicroItem {
+	item := MicroItem{Properties: map[string][]any{}, ID: attr(n, "itemid")}
+	if types := strings.Fields(attr(n, "itemtype")); len(types) > 0 {
+		item.Type = types
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		collectProperties(child, &item, base)
+	}
+	return item
+}
+
+// collectProperties adds the properties found at and under n to item,
+// stopping at itemscopes: their properties belong to them.
+func collectProperties(n *html.Node, item *MicroItem, base *url.URL) {
+	if n.Type != html.ElementNode {
+		return
+	}
+	_, scoped := attrOK(n, "itemscope")
+	if names := strings.Fields(attr(n, "itemprop")); len(names) > 0 {
+		var value any
+		if scoped {
+			value = microItem(n, base)
+		} else {
+			value = microValue(n, base)
+		}
+		for _, name := range names {
+			item.Properties[name] = append(item.Properties[name], value)
+		}
+	}
+	if scoped {
+		return
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		collectProperties(child, item, base)
+	}
+}
+
+// microValue is the value of a non-item property element, per the
+// microdata spec: an attribute for the elements that have one, else text.
+func microValue(n *html.Node, base *url.URL) string {
+	switch n.Data {
+	case "meta":
+		return attr(n, "content")
+	case "audio", "embed", "iframe", "img", "source", "track", "video":
+		return resolveLink(base, attr(n, "src"))
+	case "a", "area", "link":
+		return resolveLink(base, attr(n, "href"))
+	case "object":
+		return resolveLink(base, attr(n, "data"))
+	case "data", "meter":
+		return attr(n, "value")
+	case "time":
+		if v, ok := attrOK(n, "datetime"); ok {
+			return v
+		}
+	}
+	return strings.Join(strings.Fields(goquery.NewDocumentFromNode(n).Text()), " ")
+}
+
+// attr returns n's attribute key, or "".
+func attr(n *html.Node, key string) string {
+	v, _ := attrOK(n, key)
+	return v
+}
+
+// attrOK returns n's attribute key and whether it is present.
+func attrOK(n *html.Node, key string) (string, bool) {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return strings.TrimSpace(a.Val), true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,55 @@
+package scraper
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// acceptFormats maps the media types a client may Accept to the format
+// parameter value that produces them. Types mapping to "" mean the HTML page.
+var acceptFormats = map[string]string{
+	"application/json":     "json",
+	"text/csv":             "csv",
+	"application/x-ndjson": "jsonl",
+	"application/jsonl":    "jsonl",
+	"text/markdown":        "md",
+	"text/html":            "",
+	"*/*":                  "",
+}
+
+// RequestFormat is the response format r asks for: its format parameter
+// when set, else the format its Accept header prefers ("json" for
+// application/json, "csv" for text/csv, ...), else fallback. A request
+// that names no type but */*, as curl does, also gets fallback, while one
+// preferring text/html, as browsers do, gets "" for the HTML page. Among
+// equally preferred types the first listed wins.
+func RequestFormat(r *http.Request, fallback string) string {
+	if f := r.URL.Query().Get("format"); f != "" {
+		return f
+	}
+	best, bestType, bestQ := fallback, "", 0.0
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, params, _ := strings.Cut(part, ";")
+		mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+		format, ok := acceptFormats[mediaType]
+		if !ok {
+			continue
+		}
+		q := 1.0
+		for _, p := range strings.Split(params, ";") {
+			if k, v, ok := strings.Cut(strings.TrimSpace(p), "="); ok && strings.TrimSpace(k) == "q" {
+				if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+					q = f
+				}
+			}
+		}
+		if q > bestQ {
+			best, bestType, bestQ = format, mediaType, q
+		}
+	}
+	if bestType == "*/*" {
+		return fallback
+	}
+	return best
+}
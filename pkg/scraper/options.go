@@ -0,0 +1,709 @@
+package scraper
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Table modes accepted by the tableMode parameter.
+const (
+	TableRows    = "rows"    // each <tr> becomes a []string of cell text
+	TableObjects = "objects" // each <tr> becomes a map keyed by header text, see Table.Objects
+)
+
+// Extract modes accepted by the extract parameter.
+const (
+	ExtractText = "text" // default: flattened text only
+	ExtractHTML = "html" // also copy the element's inner HTML into ScrapeResult.HTML
+)
+
+// Modes accepted by the mode parameter.
+const (
+	ModeMeta       = "meta"       // ignore the selector and return the page's OpenGraph/Twitter tags, see ScrapeMetaTags
+	ModeScriptJSON = "scriptjson" // parse JSON script tags (JSON-LD, __NEXT_DATA__) and apply Path, see ScrapeScriptJSON
+	ModeMicrodata  = "microdata"  // ignore the selector and return the page's itemscope/itemprop items, see ScrapeMicrodata
+	ModeForms      = "forms"      // ignore the selector and describe the page's forms and their fields, see ScrapeForms
+	ModeAPI        = "api"        // query the URL as a JSON API (POSTing Body when set) and apply Path, see ScrapeAPI
+)
+
+// Groupings accepted by the groupBy parameter.
+const (
+	GroupByDomain = "domain" // group results by the host of their link, see GroupByHost
+)
+
+// maxHTMLLen caps ScrapeResult.HTML, in characters, so one huge element
+// can't blow up the response or the page.
+const maxHTMLLen = 4000
+
+// Options tunes extraction for a single scrape call.
+// The zero value extracts each element's text and resolved href.
+type Options struct {
+	// Attrs lists extra attributes copied verbatim into ScrapeResult.Attrs.
+	Attrs []string
+
+	// TableMode, when set, treats selector matches as tables; see ScrapeTables.
+	TableMode string
+
+	// Mode is "" for a normal selector scrape, ModeMeta, ModeScriptJSON,
+	// ModeMicrodata or ModeForms.
+	Mode string
+
+	// Path is the ParseJSONPath expression applied to each script with
+	// ModeScriptJSON, or to the response with ModeAPI, e.g.
+	// "props.pageProps.posts[*].title".
+	Path string
+
+	// Body, with ModeAPI, is POSTed to the URL as ContentType
+	// (ContentTypeForm or ContentTypeJSON); empty means a GET.
+	Body, ContentType string
+
+	// GroupBy is "" or GroupByDomain, which sets ScrapeResult.Group to the
+	// host of each link, replacing selector group labels.
+	GroupBy string
+
+	// MaxTitleLen truncates titles longer than this many characters, keeping
+	// the original in ScrapeResult.FullTitle. 0 means unlimited.
+	MaxTitleLen int
+
+	// TitleRegex and LinkRegex, when set, replace each result's title or
+	// link with the first capture group of their match (the whole match for
+	// a pattern without groups). Values they don't match are kept as is.
+	TitleRegex *regexp.Regexp
+	LinkRegex  *regexp.Regexp
+
+	// Precheck issues a HEAD request first and skips the GET when the target
+	// is not HTML or is larger than Config.MaxBodyBytes.
+	Precheck bool
+
+	// Render fetches the page through Config.RenderURL so content built by
+	// JavaScript is present; fails with ErrNoRenderService when unset.
+	Render bool
+
+	// Extract is ExtractText (also "") or ExtractHTML.
+	Extract string
+
+	// Fields builds ScrapeResult.Fields from sub-selectors of each match.
+	Fields []FieldSpec
+
+	// Lang is sent as the Accept-Language header, e.g. "fr-CH" or
+	// "de, en;q=0.5", to fetch a localized version of the page.
+	Lang string
+
+	// Headers are sent with every request of the scrape, overriding
+	// Config.HostHeaders for the same name.
+	Headers http.Header
+
+	// Robots skips pages the site's robots.txt disallows for our user agent,
+	// failing them with ErrDisallowedByRobots.
+	Robots bool
+
+	// Workers replaces Config.WorkerCount for this scrape, up to
+	// Config.MaxWorkers (only lowering it when Polite is set); CrawlDelay
+	// raises Config.CrawlDelay and can't lower it.
+	Workers    int
+	CrawlDelay time.Duration
+
+	// Polite is set by the polite preset, see ParseOptions; it also sends
+	// PoliteUserAgent when Config.UserAgent is empty.
+	Polite bool
+
+	// Login, when set, is submitted before the first request of the scrape
+	// and its session cookies sent with the rest. Such scrapes bypass the
+	// page cache, so a logged-in page is never served to anyone else.
+	Login *Login
+
+	// RawLinks keeps each href exactly as written in the page instead of
+	// resolving it against the page URL.
+	RawLinks bool
+
+	// LinkAttrs overrides Config.LinkAttrs, the attributes tried in order
+	// for ScrapeResult.Link.
+	LinkAttrs []string
+
+	// Insecure skips TLS certificate verification for this scrape, e.g. for
+	// an internal site with a self-signed certificate. It needs
+	// Config.AllowInsecure, else the scrape fails with ErrInsecureNotAllowed.
+	Insecure bool
+
+	// UpgradeInsecure fetches http:// URLs over https:// first, falling back
+	// to http:// when that fails, for sites that only work over TLS.
+	UpgradeInsecure bool
+
+	// Filter keeps only results whose title contains every whitespace-separated
+	// term, ignoring case. Rank then sorts them by relevance, best first, and
+	// sets ScrapeResult.Score.
+	Filter string
+	Rank   bool
+
+	// Dedup drops results repeating an earlier title and link, across all
+	// pages of a scrape. DedupMode (DedupExact, DedupTrim or DedupLower)
+	// sets how strictly they are compared; "" is DedupExact.
+	Dedup     bool
+	DedupMode string
+
+	// CountOnly counts the matches instead of returning them: fetch builds
+	// no results, only ScrapeRun.Count. Filter still applies; Dedup cannot
+	// be combined with it.
+	CountOnly bool
+
+	// Sitemap treats the URL as a sitemap.xml (or sitemap index) and works on
+	// the page URLs it lists instead, see Client.Sitemap.
+	Sitemap bool
+
+	// ClassifyLinks sends a HEAD request to each result's link (a few at a
+	// time, cached per URL) and sets ScrapeResult.LinkType from its
+	// Content-Type. Off by default: it costs one request per distinct link.
+	ClassifyLinks bool
+
+	// Debug sets ScrapeResult.DOMPath, for refining a selector.
+	Debug bool
+
+	// AutoFix retries a selector that matches nothing on a page with
+	// variants fixing common mistakes, such as ".a.b" for ".a .b" or a
+	// class name without its dot; PageInfo.FixedSelector reports the one
+	// used. Grouped selectors are left alone.
+	AutoFix bool
+
+	// Offset skips that many results and Count keeps at most that many of
+	// the rest (0 keeps all). They apply to the combined results of a scrape.
+	// A cursor parameter also sets Offset, see DecodeCursor.
+	Offset, Count int
+
+	// LinkSchemes lists the schemes a result's link may have, lower-case
+	// and without the colon; results linking elsewhere, or through an empty
+	// or "#" link attribute, are dropped. Matches without a link attribute
+	// are kept. nil means DefaultLinkSchemes; AnyLinkScheme keeps all.
+	LinkSchemes []string
+
+	// MaxPages stops a multi-page scrape (a URL list or a sitemap's pages)
+	// after that many pages, and MaxTotalResults once that many results
+	// have been found, leaving the rest unfetched; ScrapeRun.StoppedReason
+	// says which ceiling was hit. 0 means no limit beyond the URLs given and
+	// Config.MaxTotalResults, which also caps a larger MaxTotalResults.
+	MaxPages, MaxTotalResults int
+}
+
+// ParseOptions reads scrape options from URL query parameters, so every
+// handler accepts the same names:
+//
+//	attrs       comma-separated attribute names, e.g. "href,title,data-id"
+//	tableMode   "rows" (or "true") to extract matched tables row by row, "objects"
+//	            for rows keyed by header text
+//	mode        "meta" to return OpenGraph/Twitter meta tags instead of selector matches,
+//	            "scriptjson" to return values from JSON script tags (JSON-LD, __NEXT_DATA__)
+//	            "api" to query the URL as a JSON API
+//	path        with mode=scriptjson or api, the JSON path to extract, e.g. "@graph[*].name"
+//	body        with mode=api, a request body to POST, e.g. a GraphQL query
+//	contentType with mode=api, "form" (default) or "json" for body
+//	groupBy     "domain" to group results by the host of their link
+//	maxTitleLen truncate titles to N characters (ellipsis included)
+//	titleRegex, linkRegex  keep only the first capture group of this regular expression
+//	            in each title or link
+//	precheck    "true" to HEAD the page before downloading it
+//	render      "true" to fetch through the headless render service
+//	extract     "html" to return each element's inner HTML as well as its text
+//	rawLinks    "true" to return hrefs verbatim, unresolved
+//	insecure    "true" to skip TLS certificate checks (needs Config.AllowInsecure)
+//	upgradeInsecure "true" to try https:// before http:// URLs
+//	linkAttrs   comma-separated link fallback chain, e.g. "data-url,href"
+//	linkSchemes comma-separated link schemes results may have, default "http,https"; "any" keeps all
+//	lang        Accept-Language value such as "fr-CH" or "de, en;q=0.5"
+//	header      extra request header "Name: value", repeatable, e.g. "Referer: https://example.com/"
+//	polite      "true" for robots=true, crawlDelay=2s, workers=2 and PoliteUserAgent;
+//	            the explicit parameters (and header=User-Agent: ...) override it
+//	robots      "true" to skip pages robots.txt disallows
+//	crawlDelay  minimum gap between requests to one host, e.g. "1s" (up to 1m; only raises the server's)
+//	concurrency pages fetched at once, up to Config.MaxWorkers ("workers" is
+//	            an older name for it)
+//	loginUrl    login form to POST loginUser and loginPass to before scraping, see Login
+//	loginUserField, loginPassField  form field names, default "username" and "password"
+//	fields      field mapping such as "title=.name;link=a@href;price=.cost", see ParseFields
+//	filter      keep results whose title contains every term, e.g. "go release"
+//	rank        "true" to sort filtered results by relevance and return their score
+//	dedup       "true" to drop repeated title+link results
+//	dedupMode   "exact" (default), "trim" or "lower"; implies dedup
+//	countOnly   "true" to return only the number of matches
+//	sitemap     "true" to read the URL as a sitemap.xml and list or scrape its pages
+//	debug       "true" to return each match's DOM path
+//	autoFix     "true" to retry a selector matching nothing with fixes for common mistakes
+//	classifyLinks "true" to HEAD each link and report whether it is a page, PDF, image, ...
+//	offset      skip the first N results (negative counts as 0)
+//	count       keep at most N results after offset (0 or negative keeps all)
+//	cursor      next_cursor of an earlier JSON response, in place of offset
+//	maxPages    stop a multi-page scrape after N pages
+//	maxTotalResults stop a multi-page scrape once N results are found (up to Config.MaxTotalResults)
+func ParseOptions(q url.Values) (Options, error) {
+	var (
+		opts Options
+		err  error
+	)
+	opts.Attrs = splitList(q.Get("attrs"))
+	opts.LinkAttrs = splitList(q.Get("linkAttrs"))
+	if opts.LinkSchemes, err = parseLinkSchemes(q.Get("linkSchemes")); err != nil {
+		return Options{}, err
+	}
+	if opts.Precheck, err = boolParam(q, "precheck"); err != nil {
+		return Options{}, err
+	}
+	if opts.Render, err = boolParam(q, "render"); err != nil {
+		return Options{}, err
+	}
+	if opts.RawLinks, err = boolParam(q, "rawLinks"); err != nil {
+		return Options{}, err
+	}
+	if opts.Insecure, err = boolParam(q, "insecure"); err != nil {
+		return Options{}, err
+	}
+	if opts.UpgradeInsecure, err = boolParam(q, "upgradeInsecure"); err != nil {
+		return Options{}, err
+	}
+	opts.Filter = strings.TrimSpace(q.Get("filter"))
+	if opts.Rank, err = boolParam(q, "rank"); err != nil {
+		return Options{}, err
+	}
+	if opts.Fields, err = ParseFields(q.Get("fields")); err != nil {
+		return Options{}, err
+	}
+	if opts.Headers, err = ParseHeaders(q["header"]); err != nil {
+		return Options{}, err
+	}
+	if err := parsePoliteness(q, &opts); err != nil {
+		return Options{}, err
+	}
+	if opts.Login, err = parseLogin(q); err != nil {
+		return Options{}, err
+	}
+	if opts.Lang = strings.TrimSpace(q.Get("lang")); opts.Lang != "" && !validLanguage(opts.Lang) {
+		return Options{}, fmt.Errorf("lang %q is not a language tag such as en-US or a list like \"de, en;q=0.5\"", opts.Lang)
+	}
+
+	switch mode := strings.ToLower(q.Get("tableMode")); mode {
+	case "", "false":
+	case TableRows, "true":
+		opts.TableMode = TableRows
+	case TableObjects:
+		opts.TableMode = TableObjects
+	default:
+		return Options{}, fmt.Errorf("unknown tableMode %q: use %q or %q", mode, TableRows, TableObjects)
+	}
+
+	switch mode := strings.ToLower(q.Get("mode")); mode {
+	case "":
+	case ModeMeta, ModeScriptJSON, ModeMicrodata, ModeForms, ModeAPI:
+		opts.Mode = mode
+	default:
+		return Options{}, fmt.Errorf("unknown mode %q: use %q, %q, %q, %q or %q", mode, ModeMeta, ModeScriptJSON, ModeMicrodata, ModeForms, ModeAPI)
+	}
+	opts.Path = strings.TrimSpace(q.Get("path"))
+	if _, err := ParseJSONPath(opts.Path); err != nil {
+		return Options{}, err
+	}
+	if err := parseRequestBody(q, &opts); err != nil {
+		return Options{}, err
+	}
+
+	switch by := strings.ToLower(q.Get("groupBy")); by {
+	case "":
+	case GroupByDomain:
+		opts.GroupBy = GroupByDomain
+	default:
+		return Options{}, fmt.Errorf("unknown groupBy %q: use %q", by, GroupByDomain)
+	}
+
+	if opts.Dedup, err = boolParam(q, "dedup"); err != nil {
+		return Options{}, err
+	}
+	switch mode := strings.ToLower(q.Get("dedupMode")); mode {
+	case "":
+	case DedupExact, DedupTrim, DedupLower:
+		opts.Dedup, opts.DedupMode = true, mode
+	default:
+		return Options{}, fmt.Errorf("unknown dedupMode %q: use %q, %q or %q", mode, DedupExact, DedupTrim, DedupLower)
+	}
+
+	switch mode := strings.ToLower(q.Get("extract")); mode {
+	case "", ExtractText:
+	case ExtractHTML:
+		opts.Extract = ExtractHTML
+	default:
+		return Options{}, fmt.Errorf("unknown extract %q: use %q or %q", mode, ExtractText, ExtractHTML)
+	}
+
+	if raw := q.Get("maxTitleLen"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			return Options{}, fmt.Errorf("maxTitleLen must be a non-negative integer, got %q", raw)
+		}
+		opts.MaxTitleLen = n
+	}
+	if opts.TitleRegex, err = regexParam(q, "titleRegex"); err != nil {
+		return Options{}, err
+	}
+	if opts.LinkRegex, err = regexParam(q, "linkRegex"); err != nil {
+		return Options{}, err
+	}
+	if opts.Debug, err = boolParam(q, "debug"); err != nil {
+		return Options{}, err
+	}
+	if opts.AutoFix, err = boolParam(q, "autoFix"); err != nil {
+		return Options{}, err
+	}
+	if opts.ClassifyLinks, err = boolParam(q, "classifyLinks"); err != nil {
+		return Options{}, err
+	}
+	if opts.Sitemap, err = boolParam(q, "sitemap"); err != nil {
+		return Options{}, err
+	}
+	if opts.CountOnly, err = boolParam(q, "countOnly"); err != nil {
+		return Options{}, err
+	}
+	if opts.CountOnly && opts.Dedup {
+		return Options{}, errors.New("countOnly cannot be combined with dedup: counting does not keep the results to compare")
+	}
+	if opts.Offset, err = intParam(q, "offset"); err != nil {
+		return Options{}, err
+	}
+	if raw := q.Get("cursor"); raw != "" {
+		if q.Get("offset") != "" {
+			return Options{}, errors.New("cursor cannot be combined with offset: the cursor already holds the position")
+		}
+		if opts.Offset, err = DecodeCursor(raw); err != nil {
+			return Options{}, err
+		}
+	}
+	if opts.Count, err = intParam(q, "count"); err != nil {
+		return Options{}, err
+	}
+	if opts.MaxPages, err = intParam(q, "maxPages"); err != nil {
+		return Options{}, err
+	}
+	if opts.MaxTotalResults, err = intParam(q, "maxTotalResults"); err != nil {
+		return Options{}, err
+	}
+	if opts.MaxPages < 0 || opts.MaxTotalResults < 0 {
+		return Options{}, errors.New("maxPages and maxTotalResults must not be negative")
+	}
+	return opts, nil
+}
+
+// window returns the part of results selected by Offset and Count,
+// clamping both to the available range.
+func (o Options) window(results []ScrapeResult) []ScrapeResult {
+	offset := min(max(o.Offset, 0), len(results))
+	results = results[offset:]
+	if o.Count > 0 && o.Count < len(results) {
+		results = results[:o.Count]
+	}
+	return results
+}
+
+// ErrInsecureNotAllowed is returned for Options.Insecure when the operator
+// has not set Config.AllowInsecure.
+var ErrInsecureNotAllowed = errors.New("insecure=true is disabled on this server: skipping certificate verification " +
+	"lets anyone on the network path impersonate the site and alter what is scraped, so it must be enabled " +
+	"explicitly with Config.AllowInsecure (SCRAPER_ALLOW_INSECURE=true), ideally only where every target is a trusted internal host")
+
+// CheckOptions reports options this client cannot honour, so handlers can
+// reject them up front instead of failing every URL.
+func (c *Client) CheckOptions(opts Options) error {
+	if opts.Render && c.cfg.RenderURL == "" {
+		return ErrNoRenderService
+	}
+	if opts.Insecure && !c.cfg.AllowInsecure {
+		return ErrInsecureNotAllowed
+	}
+	if opts.Login != nil && opts.Render {
+		return errors.New("loginUrl cannot be combined with render=true: the render service has no session")
+	}
+	return nil
+}
+
+// WithOptions returns a Client that applies opts to every scrape.
+// The copy shares the HTTP client (and its connection pool) with c.
+//
+// With Options.Login the copy gets its own session and no page cache, and
+// with Options.Headers no page cache either: they may carry credentials
+// (Cookie, Authorization), and a page fetched with one caller's must not be
+// revalidated into another's.
+func (c *Client) WithOptions(opts Options) *Client {
+	cp := *c
+	cp.opts = opts
+	cp.opts.Attrs, cp.opts.Fields = c.allowedAttrs(opts.Attrs, opts.Fields)
+	cp.session = nil
+	if opts.Workers > 0 {
+		ceiling := max(cp.cfg.WorkerCount, cp.cfg.MaxWorkers)
+		if opts.Polite {
+			ceiling = cp.cfg.WorkerCount
+		}
+		cp.cfg.WorkerCount = min(opts.Workers, ceiling)
+	}
+	cp.cfg.CrawlDelay = max(cp.cfg.CrawlDelay, opts.CrawlDelay)
+	if opts.MaxTotalResults > 0 && (cp.cfg.MaxTotalResults == 0 || opts.MaxTotalResults < cp.cfg.MaxTotalResults) {
+		cp.cfg.MaxTotalResults = opts.MaxTotalResults
+	}
+	if opts.Polite && cp.cfg.UserAgent == "" {
+		cp.cfg.UserAgent = PoliteUserAgent
+	}
+	if opts.Login != nil {
+		cp.session = &loginSession{}
+		cp.cache = nil
+	}
+	if len(opts.Headers) > 0 {
+		cp.cache = nil
+	}
+	return &cp
+}
+
+// allowedAttrs drops the attributes, and the fields reading attributes, that
+// Config.AllowedAttrs does not list.
+func (c *Client) allowedAttrs(attrs []string, fields []FieldSpec) ([]string, []FieldSpec) {
+	if c.cfg.AllowedAttrs == nil {
+		return attrs, fields
+	}
+	allowed := func(name string) bool {
+		return slices.ContainsFunc(c.cfg.AllowedAttrs, func(a string) bool { return strings.EqualFold(a, name) })
+	}
+	attrs = slices.DeleteFunc(slices.Clone(attrs), func(a string) bool { return !allowed(a) })
+	fields = slices.DeleteFunc(slices.Clone(fields), func(f FieldSpec) bool { return f.Attr != "" && !allowed(f.Attr) })
+	return attrs, fields
+}
+
+// WithLimits returns a Client that scrapes at most workers pages at once,
+// each within pageTimeout, e.g. for a background fan-out that should stay
+// small. A value of 0 keeps c's. The copy shares the HTTP client and the
+// page cache with c.
+func (c *Client) WithLimits(workers int, pageTimeout time.Duration) *Client {
+	cp := *c
+	if workers > 0 {
+		cp.cfg.WorkerCount = workers
+	}
+	if pageTimeout > 0 {
+		cp.cfg.PageTimeout = pageTimeout
+	}
+	return &cp
+}
+
+// Settings of the polite preset.
+const (
+	PoliteWorkers    = 2
+	PoliteCrawlDelay = 2 * time.Second
+	PoliteUserAgent  = "GoScraper/1.0 (+https://github.com/GhanshyamJha05/WEB_SCRAPPER_Using-GO)"
+)
+
+// maxCrawlDelay bounds the crawlDelay parameter so one request can't hold a
+// worker for long.
+const maxCrawlDelay = time.Minute
+
+// parsePoliteness reads polite, robots, crawlDelay and concurrency into opts.
+// polite supplies defaults for the other three.
+func parsePoliteness(q url.Values, opts *Options) error {
+	var err error
+	if opts.Polite, err = boolParam(q, "polite"); err != nil {
+		return err
+	}
+	if opts.Polite {
+		opts.Robots, opts.CrawlDelay, opts.Workers = true, PoliteCrawlDelay, PoliteWorkers
+	}
+	if q.Has("robots") {
+		if opts.Robots, err = boolParam(q, "robots"); err != nil {
+			return err
+		}
+	}
+	if raw := q.Get("crawlDelay"); raw != "" {
+		d, err := time.ParseDuration(raw)
+		if err != nil || d < 0 || d > maxCrawlDelay {
+			return fmt.Errorf("crawlDelay must be a duration between 0s and %s, got %q", maxCrawlDelay, raw)
+		}
+		opts.CrawlDelay = d
+	}
+	for _, name := range []string{"workers", "concurrency"} {
+		if raw := q.Get(name); raw != "" {
+			n, err := strconv.Atoi(raw)
+			if err != nil || n < 1 {
+				return fmt.Errorf("%s must be a positive number, got %q", name, raw)
+			}
+			opts.Workers = n
+		}
+	}
+	return nil
+}
+
+// parseRequestBody reads body and contentType, which only mode=api uses.
+func parseRequestBody(q url.Values, opts *Options) error {
+	body, ct := q.Get("body"), q.Get("contentType")
+	if opts.Mode != ModeAPI {
+		if body != "" || ct != "" {
+			return errors.New("body and contentType are only used with mode=api")
+		}
+		return nil
+	}
+	if len(body) > maxRequestBody {
+		return fmt.Errorf("body is longer than %d bytes", maxRequestBody)
+	}
+	var err error
+	if opts.ContentType, err = parseContentType(ct); err != nil {
+		return err
+	}
+	if opts.ContentType == ContentTypeJSON && body != "" && !json.Valid([]byte(body)) {
+		return errors.New(`body is not valid JSON, as contentType=json requires, e.g. {"query":"{ posts { title } }"}`)
+	}
+	opts.Body = body
+	return nil
+}
+
+// parseLogin reads the login parameters; nil when loginUrl is absent.
+func parseLogin(q url.Values) (*Login, error) {
+	l := Login{
+		URL:       strings.TrimSpace(q.Get("loginUrl")),
+		UserField: strings.TrimSpace(q.Get("loginUserField")),
+		PassField: strings.TrimSpace(q.Get("loginPassField")),
+		User:      q.Get("loginUser"),
+		Pass:      q.Get("loginPass"),
+	}
+	if l.URL == "" {
+		if l.User != "" || l.Pass != "" {
+			return nil, errors.New("loginUser and loginPass need a loginUrl")
+		}
+		return nil, nil
+	}
+	if l.User == "" || l.Pass == "" {
+		return nil, errors.New("loginUrl needs loginUser and loginPass")
+	}
+	if l.UserField == "" {
+		l.UserField = DefaultLoginUserField
+	}
+	if l.PassField == "" {
+		l.PassField = DefaultLoginPassField
+	}
+	if l.UserField == l.PassField {
+		return nil, fmt.Errorf("loginUserField and loginPassField are both %q", l.UserField)
+	}
+	return &l, nil
+}
+
+// boolParam parses an optional boolean query parameter; absent means false.
+func boolParam(q url.Values, name string) (bool, error) {
+	raw := q.Get(name)
+	if raw == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(raw)
+	if err != nil {
+		return false, fmt.Errorf("%s must be true or false, got %q", name, raw)
+	}
+	return b, nil
+}
+
+// maxRegexLen bounds titleRegex and linkRegex; Go's regexp runs in linear
+// time, but compiling a huge pattern still costs.
+const maxRegexLen = 500
+
+// regexParam compiles an optional regular expression query parameter;
+// absent means nil.
+func regexParam(q url.Values, name string) (*regexp.Regexp, error) {
+	raw := q.Get(name)
+	if raw == "" {
+		return nil, nil
+	}
+	if len(raw) > maxRegexLen {
+		return nil, fmt.Errorf("%s is longer than %d characters", name, maxRegexLen)
+	}
+	re, err := regexp.Compile(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a valid regular expression: %w", name, err)
+	}
+	return re, nil
+}
+
+// FieldSpec is one entry of a field mapping: Name gets the text of the first
+// element matching Selector inside each result, or its Attr when set.
+// An empty Selector means the matched element itself.
+type FieldSpec struct {
+	Name     string
+	Selector string
+	Attr     string
+}
+
+// maxFields bounds a field mapping so one request can't fan out into
+// thousands of sub-queries per element.
+const maxFields = 20
+
+// ParseFields parses a field mapping of ";"-separated name=selector[@attr]
+// entries, e.g. "title=.name;link=a@href;id=@data-id". "" yields no fields.
+func ParseFields(raw string) ([]FieldSpec, error) {
+	var specs []FieldSpec
+	for _, entry := range strings.Split(raw, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, spec, ok := strings.Cut(entry, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("fields: %q is not name=selector[@attr]", entry)
+		}
+		f := FieldSpec{Name: name, Selector: strings.TrimSpace(spec)}
+		if i := strings.LastIndex(f.Selector, "@"); i >= 0 {
+			f.Selector, f.Attr = strings.TrimSpace(f.Selector[:i]), strings.TrimSpace(f.Selector[i+1:])
+			if f.Attr == "" {
+				return nil, fmt.Errorf("fields: %q has an empty attribute after @", entry)
+			}
+		}
+		specs = append(specs, f)
+	}
+	if len(specs) > maxFields {
+		return nil, fmt.Errorf("fields: at most %d fields, got %d", maxFields, len(specs))
+	}
+	return specs, nil
+}
+
+// languageRange loosely matches one Accept-Language entry: a BCP 47 style tag
+// (or "*") with an optional quality value.
+var languageRange = regexp.MustCompile(`^(\*|[A-Za-z]{1,8}(-[A-Za-z0-9]{1,8})*)(;q=[01](\.[0-9]{1,3})?)?$`)
+
+// validLanguage reports whether lang is a comma-separated list of language ranges.
+func validLanguage(lang string) bool {
+	for _, part := range strings.Split(lang, ",") {
+		if !languageRange.MatchString(strings.ReplaceAll(strings.TrimSpace(part), " ", "")) {
+			return false
+		}
+	}
+	return true
+}
+
+// intParam parses an optional integer query parameter; absent means 0.
+func intParam(q url.Values, name string) (int, error) {
+	raw := q.Get(name)
+	if raw == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be an integer, got %q", name, raw)
+	}
+	return n, nil
+}
+
+// splitList splits a comma-separated parameter into trimmed, lower-cased,
+// de-duplicated entries, dropping empty ones.
+func splitList(raw string) []string {
+	var out []string
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(raw, ",") {
+		v := strings.ToLower(strings.TrimSpace(part))
+		if v == "" || seen[v] {
+			continue
+		}
+		seen[v] = true
+		out = append(out, v)
+	}
+	return out
+}
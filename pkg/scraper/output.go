@@ -1,8 +1,13 @@
 package scraper
 
 import (
+	"encoding/csv"
 	"encoding/json"
+	"fmt"
+	"io"
 	"os"
+	"slices"
+	"strings"
 	"time"
 )
 
@@ -15,19 +20,58 @@ type ScrapeOutput struct {
 	// Results holds every element matched across all scraped URLs.
 	Results []ScrapeResult `json:"results"`
 
+	// Groups splits Results by ScrapeResult.Group: by label when the selector
+	// was grouped, e.g. "headline:h2 a, byline:.author" (see
+	// ParseSelectorGroups), or by host with groupBy=domain.
+	Groups map[string][]ScrapeResult `json:"groups,omitempty"`
+
+	// Records pairs up the matches of a grouped selector: one object per
+	// item, keyed by group name, with null for a group the item lacks (see
+	// Record). Offset and Count don't apply to it; Truncate drops it first.
+	Records []Record `json:"records,omitempty"`
+
 	// Errors lists per-URL errors that occurred during the run (may be empty).
 	Errors []string `json:"errors,omitempty"`
+
+	// Truncated is set when Truncate dropped Records or trailing results to
+	// fit a size cap; Meta.TotalItems still counts all of them.
+	Truncated bool `json:"truncated,omitempty"`
+
+	// NextCursor, when set, is passed back as the cursor parameter to get
+	// the results after these, see SetNextCursor.
+	NextCursor string `json:"next_cursor,omitempty"`
 }
 
 // ScrapeMeta carries context about the scrape run.
 type ScrapeMeta struct {
-	ScrapedAt   time.Time `json:"scraped_at"`   // UTC timestamp when the run started
-	Selector    string    `json:"selector"`     // CSS selector that was applied
-	URLs        []string  `json:"urls"`         // input URLs (in order)
-	TotalURLs   int       `json:"total_urls"`   // len(URLs)
-	TotalItems  int       `json:"total_items"`  // len(Results)
-	TotalErrors int       `json:"total_errors"` // len(Errors)
-	Workers     int       `json:"workers"`      // worker goroutines used
+	ScrapedAt   time.Time    `json:"scraped_at"`        // UTC timestamp when the run started
+	Selector    string       `json:"selector"`          // CSS selector that was applied
+	URLs        []string     `json:"urls"`              // input URLs (in order)
+	TotalURLs   int          `json:"total_urls"`        // len(URLs)
+	TotalItems  int          `json:"total_items"`       // len(Results)
+	TotalErrors int          `json:"total_errors"`      // len(Errors)
+	Workers     int          `json:"workers"`           // worker goroutines used
+	Cached      bool         `json:"cached,omitempty"`  // every page answered 304 and was reused from the cache
+	Reused      bool         `json:"reused,omitempty"`  // the result of the same client's identical scrape moments ago, see SCRAPER_DEBOUNCE
+	Skipped     []string     `json:"skipped,omitempty"` // URLs that hit Config.PageTimeout
+	Timings     []PageTiming `json:"timings,omitempty"` // per-page DNS/connect/TLS/TTFB/total/parse breakdown
+	Pages       []PageInfo   `json:"pages,omitempty"`   // per-page <title> and meta description
+
+	BytesDownloaded int64  `json:"bytes_downloaded"`         // response body bytes read across all pages
+	StoppedReason   string `json:"stopped_reason,omitempty"` // why pages were left unscraped, see Options.MaxPages
+}
+
+// PageInfo is what a scraped page says about itself: its <title>, meta
+// description (og:description when there is none) and canonical URL, plus a
+// warning when the page parsed to suspiciously little.
+type PageInfo struct {
+	URL         string `json:"url"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	Canonical   string `json:"canonical,omitempty"` // <link rel="canonical">, absolute
+	Warning     string `json:"warning,omitempty"`   // empty page or next to no elements parsed, with the body size
+
+	FixedSelector string `json:"fixed_selector,omitempty"` // with Options.AutoFix, the variant of the selector used because it matched nothing
 }
 
 // NewScrapeOutput builds a ScrapeOutput from the raw scrape results.
@@ -37,7 +81,7 @@ func NewScrapeOutput(urls []string, selector string, workers int, results []Scra
 		errStrings = append(errStrings, e.Error())
 	}
 
-	return ScrapeOutput{
+	out := ScrapeOutput{
 		Meta: ScrapeMeta{
 			ScrapedAt:   time.Now().UTC(),
 			Selector:    selector,
@@ -50,6 +94,103 @@ func NewScrapeOutput(urls []string, selector string, workers int, results []Scra
 		Results: results,
 		Errors:  errStrings,
 	}
+	out.setGroups()
+	return out
+}
+
+// setGroups rebuilds Groups from Results.
+func (o *ScrapeOutput) setGroups() {
+	o.Groups = nil
+	groups := resultGroups(o.Meta.Selector, o.Results)
+	if len(groups) > 0 {
+		o.Groups = make(map[string][]ScrapeResult, len(groups))
+		for _, g := range GroupResults(groups, o.Results) {
+			o.Groups[g.Name] = g.Results
+		}
+	}
+}
+
+// Truncate keeps as many leading results as fit when o is encoded as JSON
+// in at most maxBytes, and reports whether any were dropped. maxBytes <= 0
+// means no cap. Records are dropped before any result; the rest of o is
+// always kept, so a cap smaller than it leaves no results.
+func (o *ScrapeOutput) Truncate(maxBytes int64) bool {
+	if maxBytes <= 0 || o.encodedSize() <= maxBytes {
+		return false
+	}
+	o.Truncated = true
+	if o.Records != nil {
+		// Records repeat what Results hold, so they go before any result.
+		o.Records = nil
+		if o.encodedSize() <= maxBytes {
+			return true
+		}
+	}
+	all := o.Results
+	// Binary search the longest prefix that fits: the size grows with n.
+	lo, hi := 0, len(all)-1
+	for lo < hi {
+		mid := (lo + hi + 1) / 2
+		o.Results = all[:mid]
+		o.setGroups()
+		if o.encodedSize() <= maxBytes {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+	o.Results = all[:lo]
+	o.setGroups()
+	return true
+}
+
+// encodedSize is the length of o as written by json.Encoder, newline included.
+func (o *ScrapeOutput) encodedSize() int64 {
+	b, err := json.Marshal(o)
+	if err != nil {
+		return 0
+	}
+	return int64(len(b)) + 1
+}
+
+// CountOutput is the JSON body for countOnly=true: the number of matches
+// across all URLs, without the matches themselves.
+type CountOutput struct {
+	ScrapedAt time.Time `json:"scraped_at"`
+	Selector  string    `json:"selector"`
+	URLs      []string  `json:"urls"`
+	Count     int       `json:"count"`
+	Errors    []string  `json:"errors,omitempty"`
+	Stopped   string    `json:"stopped_reason,omitempty"` // see ScrapeRun.StoppedReason
+}
+
+// NewCountOutput builds the CountOutput of a ScrapeRun made with Options.CountOnly.
+func NewCountOutput(urls []string, selector string, run ScrapeRun) CountOutput {
+	out := CountOutput{ScrapedAt: time.Now().UTC(), Selector: selector, URLs: urls, Count: run.Count, Stopped: run.StoppedReason}
+	for _, e := range run.Errs {
+		out.Errors = append(out.Errors, e.Error())
+	}
+	return out
+}
+
+// resultGroups lists the groups of ScrapeOutput.Groups: the labels of a
+// grouped selector, including ones that matched nothing, or, when results
+// were regrouped (groupBy=domain), their groups in order of appearance.
+func resultGroups(selector string, results []ScrapeResult) []SelectorGroup {
+	labels, _ := ParseSelectorGroups(selector)
+	var seen []SelectorGroup
+	regrouped := false
+	for _, r := range results {
+		if r.Group == "" || slices.ContainsFunc(seen, func(g SelectorGroup) bool { return g.Name == r.Group }) {
+			continue
+		}
+		seen = append(seen, SelectorGroup{Name: r.Group})
+		regrouped = regrouped || !slices.ContainsFunc(labels, func(g SelectorGroup) bool { return g.Name == r.Group })
+	}
+	if regrouped {
+		return seen
+	}
+	return labels
 }
 
 // SaveJSON writes out as indented JSON to the given file path.
@@ -65,3 +206,123 @@ func (o ScrapeOutput) SaveJSON(path string) error {
 	enc.SetIndent("", "  ")
 	return enc.Encode(o)
 }
+
+// StreamError is the JSON Lines record written by WriteJSONL for a URL that
+// failed, so consumers can tell it apart from a ScrapeResult by its "error" key.
+type StreamError struct {
+	URL   string `json:"url"`
+	Error string `json:"error"`
+}
+
+// WriteJSONL writes every result from pages as one JSON object per line as
+// soon as its page finishes, instead of buffering the whole run. A failed
+// page becomes a StreamError line. If w has a Flush method (http.Flusher)
+// it is called after each page so clients see results incrementally.
+// opts.Offset and opts.Count window the results across all pages.
+func WriteJSONL(w io.Writer, pages <-chan JobResult, opts Options) error {
+	enc := json.NewEncoder(w)
+	flusher, _ := w.(interface{ Flush() })
+	skip, left := max(opts.Offset, 0), opts.Count
+	for p := range pages {
+		if p.Err != nil {
+			if err := enc.Encode(StreamError{URL: p.URL, Error: p.Err.Error()}); err != nil {
+				return err
+			}
+		}
+		for _, item := range p.Items {
+			if skip > 0 {
+				skip--
+				continue
+			}
+			if opts.Count > 0 && left == 0 {
+				break
+			}
+			left--
+			if err := enc.Encode(item); err != nil {
+				return err
+			}
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+	return nil
+}
+
+// mdTitle escapes the characters that would end or nest a Markdown link text.
+var mdTitle = strings.NewReplacer(`\`, `\\`, "[", `\[`, "]", `\]`, "\n", " ")
+
+// mdLink percent-encodes the characters that would end a Markdown link target.
+var mdLink = strings.NewReplacer(" ", "%20", "(", "%28", ")", "%29")
+
+// WriteMarkdown writes results as a Markdown bullet list, one
+// "- [Title](Link)" line per result; results without a link are plain items.
+// Errors are appended as HTML comments so they don't show when rendered.
+func WriteMarkdown(w io.Writer, results []ScrapeResult, errs []error) error {
+	for _, r := range results {
+		var err error
+		if r.Link == "" {
+			_, err = fmt.Fprintf(w, "- %s\n", mdTitle.Replace(r.Title))
+		} else {
+			_, err = fmt.Fprintf(w, "- [%s](%s)\n", mdTitle.Replace(r.Title), mdLink.Replace(r.Link))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	for _, e := range errs {
+		msg := strings.ReplaceAll(e.Error(), "--", "- -")
+		if _, err := fmt.Fprintf(w, "<!-- error: %s -->\n", msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CSVHeader is the header row WriteCSV writes for opts: "text" (the result
+// title) and "link", then one column per Options.Attrs entry and one per
+// Options.Fields name, in the order requested. The text column is not
+// called "title" so that attrs=title gets a column of its own; a name
+// requested twice gets one column.
+func CSVHeader(opts Options) []string {
+	header := []string{"text", "link"}
+	add := func(name string) {
+		if !slices.Contains(header, name) {
+			header = append(header, name)
+		}
+	}
+	for _, a := range opts.Attrs {
+		add(a)
+	}
+	for _, f := range opts.Fields {
+		add(f.Name)
+	}
+	return header
+}
+
+// WriteCSV writes results as CSV with the columns of CSVHeader(opts), one
+// row per result. An attribute or field a result lacks is an empty cell.
+// Errors have no place in the table and are left to the caller.
+func WriteCSV(w io.Writer, results []ScrapeResult, opts Options) error {
+	header := CSVHeader(opts)
+	cw := csv.NewWriter(w)
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	row := make([]string, len(header))
+	for _, r := range results {
+		row[0], row[1] = r.Title, r.Link
+		for i, col := range header[2:] {
+			v, ok := r.Fields[col]
+			if !ok {
+				v = r.Attrs[col]
+			}
+			row[i+2] = v
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,120 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"syscall"
+)
+
+// ErrHostNotAllowed is returned when a URL is rejected by the HostPolicy.
+var ErrHostNotAllowed = errors.New("host not allowed")
+
+// HostPolicy restricts which hosts a Client may fetch from. It protects a
+// public deployment from being used as an open proxy into internal networks.
+// The zero value allows every host.
+type HostPolicy struct {
+	Allow        []string // if non-empty, only these hosts and their subdomains
+	Deny         []string // hosts (and subdomains) that are always rejected
+	BlockPrivate bool     // reject loopback, private and link-local addresses
+}
+
+// CheckURL reports whether rawURL may be scraped under the client's policy.
+// Host names are resolved so that names pointing at private addresses are
+// rejected too; the dialer re-checks every connection, redirects included.
+func (c *Client) CheckURL(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("%w: unsupported scheme %q", ErrHostNotAllowed, u.Scheme)
+	}
+	if err := c.cfg.HostPolicy.checkHost(u.Hostname()); err != nil {
+		return err
+	}
+	if !c.cfg.HostPolicy.BlockPrivate {
+		return nil
+	}
+
+	err = checkResolved(ctx, u.Hostname())
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return nil // let the fetch report the DNS failure
+	}
+	return err
+}
+
+// checkResolved resolves host, unless it is an IP address, and rejects it
+// when any of its addresses is private, see checkIP. A failed lookup is
+// returned as its *net.DNSError.
+func checkResolved(ctx context.Context, host string) error {
+	if ip := net.ParseIP(host); ip != nil {
+		return checkIP(ip)
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return err
+	}
+	for _, a := range addrs {
+		if err := checkIP(a.IP); err != nil {
+			return fmt.Errorf("%s: %w", host, err)
+		}
+	}
+	return nil
+}
+
+// checkHost applies the allow and deny lists to a host name.
+func (p HostPolicy) checkHost(host string) error {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "" {
+		return fmt.Errorf("%w: missing host", ErrHostNotAllowed)
+	}
+	for _, d := range p.Deny {
+		if matchHost(host, d) {
+			return fmt.Errorf("%w: %s is on the deny list", ErrHostNotAllowed, host)
+		}
+	}
+	if len(p.Allow) == 0 {
+		return nil
+	}
+	for _, a := range p.Allow {
+		if matchHost(host, a) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s is not on the allow list", ErrHostNotAllowed, host)
+}
+
+// matchHost reports whether host is pattern or a subdomain of it.
+func matchHost(host, pattern string) bool {
+	pattern = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(pattern), "."))
+	return pattern != "" && (host == pattern || strings.HasSuffix(host, "."+pattern))
+}
+
+// checkIP rejects addresses that point into the local or private network:
+// 127.0.0.0/8, 10.0.0.0/8, 172.16.0.0/12, 192.168.0.0/16, 169.254.0.0/16 and
+// their IPv6 equivalents.
+func checkIP(ip net.IP) error {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() {
+		return fmt.Errorf("%w: %s is a private address", ErrHostNotAllowed, ip)
+	}
+	return nil
+}
+
+// dialControl runs after DNS resolution for every outgoing connection, so a
+// public name that resolves (or is rebound) to a private address still fails.
+func dialControl(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		return checkIP(ip)
+	}
+	return nil
+}
@@ -14,9 +14,10 @@ type pool struct {
 }
 
 // newPool starts `workers` goroutines immediately.
-// Each worker pulls a job, calls rl.wait() to honour the rate limit, then fetches.
+// Each worker pulls a job, waits for its host's slot in hs and then for
+// rl to honour the global rate limit, then fetches with ctx.
 // Call submit() to enqueue work, done() to signal no more jobs, then range results.
-func newPool(workers int, fetch fetchFn, rl *rateLimiter) *pool {
+func newPool(ctx context.Context, workers int, fetch fetchFn, rl *rateLimiter, hs *hostSpacer) *pool {
 	p := &pool{
 		// Unbuffered: workers block until a job is available (natural backpressure).
 		jobs: make(chan scrapeJob),
@@ -29,13 +30,15 @@ func newPool(workers int, fetch fetchFn, rl *rateLimiter) *pool {
 		go func() {
 			defer p.wg.Done()
 			for job := range p.jobs {
-				rl.wait() // honour global rate limit before each request
+				hs.wait(job.url) // crawl delay between pages of the same site
+				rl.wait()        // honour global rate limit before each request
 				start := time.Now()
-				items, err := fetch(context.Background(), job.url, job.selector)
+				items, meta, err := fetch(ctx, job.url, job.selector)
 				p.results <- jobResult{
 					index:      job.index,
 					url:        job.url,
 					items:      items,
+					meta:       meta,
 					durationMs: time.Since(start).Milliseconds(),
 					err:        err,
 				}
@@ -60,4 +63,5 @@ func (p *pool) submit(job scrapeJob) { p.jobs <- job }
 func (p *pool) done() { close(p.jobs) }
 
 // fetchFn is the function workers call to fetch and parse a single page.
-type fetchFn func(ctx context.Context, pageURL, selector string) ([]ScrapeResult, error)
+// meta reports how the page was fetched (cache reuse, phase timings).
+type fetchFn func(ctx context.Context, pageURL, selector string) (items []ScrapeResult, meta pageMeta, err error)
@@ -1,6 +1,9 @@
 package scraper
 
-import "time"
+import (
+	"sync"
+	"time"
+)
 
 // rateLimiter gates concurrent workers to a maximum global request rate.
 // All workers share one limiter; each must call wait() before firing a request.
@@ -31,3 +34,36 @@ func (r *rateLimiter) wait() {
 func (r *rateLimiter) stop() {
 	r.ticker.Stop()
 }
+
+// hostSpacer keeps at least delay between the start of two requests to the
+// same host, on top of the global rateLimiter. A scrape of many pages on one
+// site then trickles in politely while pages on different hosts still run in
+// parallel. A nil hostSpacer or a delay <= 0 never waits.
+type hostSpacer struct {
+	delay time.Duration
+	mu    sync.Mutex
+	next  map[string]time.Time // earliest start of the next request per host
+}
+
+func newHostSpacer(delay time.Duration) *hostSpacer {
+	if delay <= 0 {
+		return nil
+	}
+	return &hostSpacer{delay: delay, next: make(map[string]time.Time)}
+}
+
+// wait reserves the next slot for pageURL's host and sleeps until it starts.
+func (h *hostSpacer) wait(pageURL string) {
+	if h == nil {
+		return
+	}
+	host := Host(pageURL)
+	h.mu.Lock()
+	start := time.Now()
+	if t := h.next[host]; t.After(start) {
+		start = t
+	}
+	h.next[host] = start.Add(h.delay)
+	h.mu.Unlock()
+	time.Sleep(time.Until(start))
+}
@@ -0,0 +1,49 @@
+package scraper
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// maxRedirects is how many redirects one request follows.
+const maxRedirects = 10
+
+// ErrRedirectLoop is matched (errors.Is) by a RedirectLoopError.
+var ErrRedirectLoop = errors.New("redirect loop detected")
+
+// RedirectLoopError is returned when a redirect leads back to a URL the
+// request already visited. Cycle lists the URLs of the loop, starting and
+// ending with the repeated one.
+type RedirectLoopError struct {
+	Cycle []string
+}
+
+func (e *RedirectLoopError) Error() string {
+	return ErrRedirectLoop.Error() + ": " + strings.Join(e.Cycle, " → ")
+}
+
+func (e *RedirectLoopError) Is(target error) bool { return target == ErrRedirectLoop }
+
+// checkRedirect is the http.Client CheckRedirect hook: it stops at a
+// redirect loop or after maxRedirects, and applies policy to every target.
+func checkRedirect(policy HostPolicy) func(*http.Request, []*http.Request) error {
+	return func(req *http.Request, via []*http.Request) error {
+		next := req.URL.String()
+		for i, prev := range via {
+			if prev.URL.String() != next {
+				continue
+			}
+			cycle := make([]string, 0, len(via)-i+1)
+			for _, r := range via[i:] {
+				cycle = append(cycle, r.URL.String())
+			}
+			return &RedirectLoopError{Cycle: append(cycle, next)}
+		}
+		if len(via) >= maxRedirects {
+			return fmt.Errorf("stopped after %d redirects", maxRedirects)
+		}
+		return policy.checkHost(req.URL.Hostname())
+	}
+}
@@ -0,0 +1,27 @@
+package scraper
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+)
+
+// ErrNoRenderService is returned for Options.Render when Config.RenderURL is empty.
+var ErrNoRenderService = errors.New("render=true needs a render service: set Config.RenderURL (SCRAPER_RENDER_URL)")
+
+// renderRequestURL returns the render service URL that fetches pageURL in a
+// headless browser. The target is passed as the "url" query parameter, which
+// Splash (/render.html) and prerender (/render) both accept.
+func (c *Client) renderRequestURL(pageURL string) (string, error) {
+	if c.cfg.RenderURL == "" {
+		return "", ErrNoRenderService
+	}
+	u, err := url.Parse(c.cfg.RenderURL)
+	if err != nil {
+		return "", fmt.Errorf("render service URL: %w", err)
+	}
+	q := u.Query()
+	q.Set("url", pageURL)
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
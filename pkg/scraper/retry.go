@@ -1,11 +1,26 @@
 package scraper
 
 import (
+	"context"
+	"crypto/tls"
 	"errors"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 )
 
+// maxRetryAfter is the longest Retry-After withRetry waits out; a site
+// asking for more fails the page at once, its StatusError saying how long
+// to wait.
+const maxRetryAfter = time.Minute
+
+// DefaultRetryStatuses are the HTTP codes retried when Config.RetryStatuses
+// is nil: the gateway errors a proxy or load balancer answers while the site
+// behind it restarts. A 429 is only retried when listed, see withRetry.
+var DefaultRetryStatuses = []int{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout}
+
 // retryableError wraps the last error after all attempts are exhausted.
 type retryableError struct {
 	attempts int
@@ -17,13 +32,26 @@ func (e *retryableError) Unwrap() error { return e.err }
 
 // isRetryable returns true for errors worth retrying:
 //   - any network/timeout error from http.Client.Do
-//   - HTTP 429 Too Many Requests
-//   - HTTP 5xx server errors
-func isRetryable(err error, statusCode int) bool {
+//   - a status in statuses, or in DefaultRetryStatuses when statuses is nil
+//
+// Requests rejected by the HostPolicy, blocked by anti-bot protection or
+// caught in a redirect loop are permanent and never retried.
+func isRetryable(err error, statusCode int, statuses []int) bool {
+	if errors.Is(err, ErrHostNotAllowed) || errors.Is(err, ErrBlocked) || errors.Is(err, ErrInsecureNotAllowed) ||
+		errors.Is(err, ErrRedirectLoop) {
+		return false
+	}
+	var certErr *tls.CertificateVerificationError
+	if errors.As(err, &certErr) {
+		return false // a bad certificate won't fix itself between attempts
+	}
 	if err != nil {
 		return true // covers timeouts, connection resets, DNS failures
 	}
-	return statusCode == http.StatusTooManyRequests || statusCode >= 500
+	if statuses == nil {
+		statuses = DefaultRetryStatuses
+	}
+	return slices.Contains(statuses, statusCode)
 }
 
 // withRetry calls do up to maxRetries times with exponential backoff between attempts.
@@ -34,19 +62,24 @@ func isRetryable(err error, statusCode int) bool {
 //	attempt 1 fails → wait 600ms  → attempt 2
 //	attempt 2 fails → wait 1200ms → attempt 3  (last)
 //
-// Returns immediately on the first success or non-retryable error.
-// Respects Retry-After header on 429 responses.
-func withRetry(maxRetries int, baseDelay time.Duration, do func() (*http.Response, error)) (*http.Response, error) {
+// Returns immediately on the first success or non-retryable error, and stops
+// waiting as soon as ctx is done (e.g. Config.PageTimeout expired).
+// statuses lists the retryable HTTP codes; see isRetryable.
+// Respects the Retry-After header of a retried response (429, 503, ...),
+// see parseRetryAfter.
+func withRetry(ctx context.Context, maxRetries int, baseDelay time.Duration, statuses []int, do func() (*http.Response, error)) (*http.Response, error) {
 	var (
-		resp *http.Response
-		err  error
+		resp     *http.Response
+		err      error
+		attempts int
 	)
 
 	for attempt := range maxRetries {
+		attempts++
 		resp, err = do()
 
 		// Success — no error and status is not retryable.
-		if err == nil && !isRetryable(nil, resp.StatusCode) {
+		if err == nil && !isRetryable(nil, resp.StatusCode, statuses) {
 			return resp, nil
 		}
 
@@ -55,30 +88,63 @@ func withRetry(maxRetries int, baseDelay time.Duration, do func() (*http.Respons
 			resp.Body.Close()
 		}
 
-		// Last attempt — don't sleep, fall through to return the error.
-		if attempt == maxRetries-1 {
+		// Permanent failure, last attempt or no time left — don't sleep, return the error.
+		if (err != nil && !isRetryable(err, 0, statuses)) || attempt == maxRetries-1 || ctx.Err() != nil {
 			break
 		}
 
 		// Exponential backoff: baseDelay * 2^attempt
 		sleep := baseDelay * (1 << attempt)
 
-		// Honour Retry-After header if the server sent one (common with 429).
-		if resp != nil && resp.StatusCode == http.StatusTooManyRequests {
-			if ra := resp.Header.Get("Retry-After"); ra != "" {
-				if secs, parseErr := time.ParseDuration(ra + "s"); parseErr == nil {
-					sleep = secs
+		// Honour Retry-After header if the server sent one (common with 429
+		// and 503), unless it asks for longer than we're willing or able to
+		// wait: then give up now with the wait in the error.
+		if resp != nil {
+			if wait, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+				if deadline, has := ctx.Deadline(); wait > maxRetryAfter || has && time.Until(deadline) < wait {
+					break
 				}
+				sleep = wait
 			}
 		}
 
-		time.Sleep(sleep)
+		select {
+		case <-time.After(sleep):
+		case <-ctx.Done():
+			err = ctx.Err()
+			resp = nil
+		}
+		if ctx.Err() != nil {
+			break
+		}
 	}
 
 	// Wrap the last error with attempt count for observability.
 	lastErr := err
 	if lastErr == nil {
-		lastErr = errors.New(resp.Status)
+		lastErr = newStatusError(resp)
+	}
+	return nil, &retryableError{attempts: attempts, err: lastErr}
+}
+
+// parseRetryAfter reads a Retry-After header in either of its forms, a
+// number of seconds ("120") or an HTTP date ("Wed, 21 Oct 2026 07:28:00
+// GMT"), as the time to wait from now. A date in the past waits 0; an empty
+// or malformed header reports false.
+func parseRetryAfter(v string, now time.Time) (time.Duration, bool) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	t, err := http.ParseTime(v)
+	if err != nil {
+		return 0, false
 	}
-	return nil, &retryableError{attempts: maxRetries, err: lastErr}
+	return max(t.Sub(now), 0).Round(time.Second), true
 }
@@ -0,0 +1,197 @@
+package scraper
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrDisallowedByRobots is returned with Options.Robots for a page the
+// site's robots.txt does not allow our user agent to fetch.
+var ErrDisallowedByRobots = errors.New("disallowed by robots.txt")
+
+const (
+	robotsTTL      = time.Hour // how long a robots.txt is trusted before refetching
+	robotsMaxBytes = 512 << 10 // larger robots.txt files are cut off here
+	robotsCacheMax = 1024      // sites remembered per Client
+)
+
+// robotsRule is one Allow or Disallow line, compiled to a regexp anchored
+// at the start of the path ("*" matches anything, a final "$" the end).
+type robotsRule struct {
+	allow   bool
+	length  int // of the pattern as written; the longest match wins
+	pattern *regexp.Regexp
+}
+
+// robotsRules are the rules of a robots.txt that apply to one user agent.
+type robotsRules []robotsRule
+
+// allowed reports whether path (with its query) may be fetched: the longest
+// matching rule decides, Allow winning a tie, and no match allows.
+func (rules robotsRules) allowed(path string) bool {
+	best, allow := -1, true
+	for _, r := range rules {
+		if r.pattern.MatchString(path) && (r.length > best || r.length == best && r.allow) {
+			best, allow = r.length, r.allow
+		}
+	}
+	return allow
+}
+
+// parseRobots reads the groups of a robots.txt that apply to agent, the
+// product token of our User-Agent, matched without regard to case. Without
+// a group naming it, the "*" groups apply.
+func parseRobots(body []byte, agent string) robotsRules {
+	agent = strings.ToLower(agent)
+	var (
+		specific, wildcard robotsRules
+		named, star        bool // the current group applies to agent / to "*"
+		inAgents           bool // still reading the group's User-agent lines
+		matchedAgent       bool
+	)
+	sc := bufio.NewScanner(bytes.NewReader(body))
+	for sc.Scan() {
+		line, _, _ := strings.Cut(sc.Text(), "#")
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key, value = strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(value)
+		switch key {
+		case "user-agent":
+			if !inAgents {
+				named, star, inAgents = false, false, true
+			}
+			ua := strings.ToLower(value)
+			if ua == "*" {
+				star = true
+			} else if agent != "" && ua == agent {
+				named, matchedAgent = true, true
+			}
+		case "allow", "disallow":
+			inAgents = false
+			if value == "" || (!named && !star) {
+				continue // an empty Disallow allows everything
+			}
+			rule := robotsRule{allow: key == "allow", length: len(value), pattern: robotsPattern(value)}
+			if named {
+				specific = append(specific, rule)
+			} else {
+				wildcard = append(wildcard, rule)
+			}
+		default:
+			inAgents = false // Crawl-delay, Sitemap, ... end the User-agent lines
+		}
+	}
+	if matchedAgent {
+		return specific
+	}
+	return wildcard
+}
+
+// robotsPattern compiles a robots.txt path pattern.
+func robotsPattern(p string) *regexp.Regexp {
+	end := strings.HasSuffix(p, "$")
+	p = strings.TrimSuffix(p, "$")
+	expr := "^" + strings.ReplaceAll(regexp.QuoteMeta(p), `\*`, ".*")
+	if end {
+		expr += "$"
+	}
+	return regexp.MustCompile(expr)
+}
+
+// robotsEntry is the robots.txt of one site, fetched on first use.
+type robotsEntry struct {
+	expires time.Time // set on creation, robotsTTL ahead
+	once    sync.Once
+	body    []byte // nil: no robots.txt, everything allowed
+}
+
+// robotsCache holds robots.txt files per scheme and host.
+type robotsCache struct {
+	mu    sync.Mutex
+	sites map[string]*robotsEntry
+}
+
+func newRobotsCache() *robotsCache {
+	return &robotsCache{sites: make(map[string]*robotsEntry)}
+}
+
+// entry returns the cache entry for site, replacing it once it is stale.
+func (rc *robotsCache) entry(site string) *robotsEntry {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if e, ok := rc.sites[site]; ok && time.Now().Before(e.expires) {
+		return e
+	}
+	if len(rc.sites) >= robotsCacheMax {
+		clear(rc.sites) // rare; refetching is cheap
+	}
+	e := &robotsEntry{expires: time.Now().Add(robotsTTL)}
+	rc.sites[site] = e
+	return e
+}
+
+// checkRobots fails with ErrDisallowedByRobots when the robots.txt of
+// pageURL's site disallows it for our user agent. A missing or unreadable
+// robots.txt allows everything.
+func (c *Client) checkRobots(ctx context.Context, pageURL string) error {
+	u, err := url.Parse(pageURL)
+	if err != nil || u.Host == "" {
+		return nil // fetching reports the bad URL
+	}
+	site := u.Scheme + "://" + u.Host
+	e := c.robots.entry(site)
+	e.once.Do(func() { e.body = c.fetchRobots(ctx, site) })
+	if e.body == nil {
+		return nil
+	}
+	ua := c.cfg.UserAgent
+	if h := c.opts.Headers.Get("User-Agent"); h != "" {
+		ua = h
+	}
+	agent, _, _ := strings.Cut(ua, "/")
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+	if !parseRobots(e.body, strings.TrimSpace(agent)).allowed(path) {
+		return fmt.Errorf("%w: %s", ErrDisallowedByRobots, path)
+	}
+	return nil
+}
+
+// fetchRobots downloads site's robots.txt, or returns nil when it has none
+// or it can't be read.
+func (c *Client) fetchRobots(ctx context.Context, site string) []byte {
+	req, err := c.newRequest(ctx, http.MethodGet, site+"/robots.txt")
+	if err != nil {
+		return nil
+	}
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil
+	}
+	body, err := io.ReadAll(io.LimitReader(res.Body, robotsMaxBytes))
+	if err != nil {
+		return nil
+	}
+	return body
+}
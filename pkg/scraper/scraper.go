@@ -2,20 +2,40 @@
 package scraper
 
 import (
+	"bytes"
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
+	"io"
+	"net"
 	"net/http"
+	"net/http/httptrace"
 	"net/url"
+	"regexp"
+	"slices"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
 )
 
-// ScrapeResult is one matched element: its text and resolved href.
+// ScrapeResult is one matched element: its text and resolved link.
 type ScrapeResult struct {
-	Title string `json:"title"`
-	Link  string `json:"link"`
+	Title     string            `json:"title"`
+	Link      string            `json:"link"`
+	FullTitle string            `json:"full_title,omitempty"` // untruncated Title when Options.MaxTitleLen cut it
+	Attrs     map[string]string `json:"attrs,omitempty"`      // values of Options.Attrs present on the element
+	HTML      string            `json:"html,omitempty"`       // inner HTML with Options.Extract "html", capped in length
+	Fields    map[string]string `json:"fields,omitempty"`     // values built from Options.Fields
+	Score     float64           `json:"score,omitempty"`      // relevance to Options.Filter with Options.Rank
+	Group     string            `json:"group,omitempty"`      // label of the matching part of a grouped selector
+	LinkType  string            `json:"link_type,omitempty"`  // what Link points to (LinkPage, LinkPDF, ...) with Options.ClassifyLinks
+	DOMPath   string            `json:"dom_path,omitempty"`   // with Options.Debug, the element's ancestors, e.g. "html > body > div#main.list > a.story"
 }
 
 // internal job/result types passed through the worker pool channels.
@@ -29,6 +49,7 @@ type jobResult struct {
 	index      int
 	url        string
 	items      []ScrapeResult
+	meta       pageMeta
 	durationMs int64
 	err        error
 }
@@ -55,27 +76,85 @@ type BulkScrapeResponse struct {
 	Results          []BulkScrapeResult `json:"results"`
 }
 
+// BatchItem is one url+selector pair in the JSON body for POST /api/batch.
+type BatchItem struct {
+	URL      string `json:"url"`
+	Selector string `json:"selector"`
+}
+
+// BatchResult is the outcome of one BatchItem.
+type BatchResult struct {
+	URL        string         `json:"url"`
+	Selector   string         `json:"selector"`
+	Results    []ScrapeResult `json:"results"`
+	DurationMs int64          `json:"duration_ms"`
+	Timing     *Timing        `json:"timing,omitempty"` // phases of the fetch; nil when it failed
+	Error      string         `json:"error,omitempty"`
+	TimedOut   bool           `json:"timed_out,omitempty"` // Config.PageTimeout expired
+}
+
+// BatchResponse is the full response for a batch run, rows in input order.
+type BatchResponse struct {
+	TotalBatchTimeMs int           `json:"total_batch_time_ms"`
+	Results          []BatchResult `json:"results"`
+}
+
 // --- Config & Client ---
 
 // Config holds tunables for the worker pool and HTTP client.
 type Config struct {
 	WorkerCount       int           // number of concurrent worker goroutines
+	MaxWorkers        int           // ceiling for Options.Workers, which may raise WorkerCount up to it; below WorkerCount it has no effect
 	RateLimit         float64       // maximum requests per second across all workers
 	MaxURLsPerRequest int           // hard cap on URLs per call
 	HTTPTimeout       time.Duration // per-request HTTP timeout
-	MaxRetries        int           // max retry attempts on failure (0 = no retries)
+	MaxRetries        int           // attempts per page, the first included; 1 turns retries off, 0 means 3
 	BaseRetryDelay    time.Duration // initial backoff delay; doubles each attempt
+	RetryStatuses     []int         // HTTP codes worth retrying; nil means DefaultRetryStatuses
+	MaxBodyBytes      int64         // largest response body read before parsing
+	HostPolicy        HostPolicy    // which hosts may be fetched; zero value allows all
+	CacheSize         int           // pages kept for conditional GET revalidation; 0 disables
+	CrawlDelay        time.Duration // minimum gap between requests to one host in a single scrape; 0 disables
+	RenderURL         string        // headless render service for Options.Render, e.g. "http://splash:8050/render.html"
+	UserAgent         string        // User-Agent header for every request; empty keeps Go's default
+	AcceptLanguage    string        // default Accept-Language, e.g. "en-US"; Options.Lang overrides it
+	MaxConnsPerHost   int           // open connections per target host (http.Transport); 0 is unlimited
+	MaxIdlePerHost    int           // idle keep-alive connections kept per host for reuse
+	PageTimeout       time.Duration // deadline for one URL in a multi-URL scrape, retries included; 0 is none
+	LinkAttrs         []string      // attributes tried in order for ScrapeResult.Link; nil means DefaultLinkAttrs
+	AllowInsecure     bool          // let Options.Insecure skip TLS certificate checks; off by default
+	ParseWorkers      int           // goroutines extracting the matches of one large page; 0 or 1 is serial
+	HostHeaders       HostHeaders   // headers sent to matching hosts, e.g. a Referer one site needs; Options.Headers override them
+	MaxOutputBytes    int64         // cap on a scrape's JSON response; results past it are dropped and "truncated" set. 0 is none
+	MaxResultBytes    int64         // budget for the titles and links extracted from one page, see ErrResultsTooLarge; 0 is none
+	MaxTotalResults   int           // results after which a multi-page scrape stops fetching pages, see ScrapeAll; 0 is none
+	Proxies           []*url.URL    // proxies to rotate page requests through, see ParseProxies; nil connects directly
+	ProxyRotation     string        // ProxyRoundRobin (default) or ProxyRandom
+	AllowedAttrs      []string      // attributes Options.Attrs and Options.Fields may read; others are dropped. nil allows all
 }
 
+// DefaultLinkAttrs is the link fallback chain: JS-driven sites often leave
+// href="#" and keep the real URL in a data attribute.
+var DefaultLinkAttrs = []string{"href", "data-href", "data-url"}
+
 // DefaultConfig returns sensible production defaults.
 func DefaultConfig() Config {
 	return Config{
 		WorkerCount:       6,
+		MaxWorkers:        16,
 		RateLimit:         5,
 		MaxURLsPerRequest: 25,
 		HTTPTimeout:       12 * time.Second,
 		MaxRetries:        3,
 		BaseRetryDelay:    300 * time.Millisecond,
+		MaxBodyBytes:      10 << 20,
+		CacheSize:         32,
+		CrawlDelay:        500 * time.Millisecond,
+		MaxConnsPerHost:   4,
+		MaxIdlePerHost:    4,
+		PageTimeout:       30 * time.Second,
+		MaxOutputBytes:    10 << 20,
+		MaxResultBytes:    32 << 20,
 	}
 }
 
@@ -83,6 +162,20 @@ func DefaultConfig() Config {
 type Client struct {
 	httpClient *http.Client
 	cfg        Config
+	opts       Options    // per-call extraction options, see WithOptions
+	cache      *pageCache // nil when Config.CacheSize is 0; shared by WithOptions copies
+	linkTypes  *linkTypeCache
+	session    *loginSession // set by WithOptions for Options.Login
+	robots     *robotsCache  // shared by WithOptions copies
+	stats      *statsTable   // per-URL scrape statistics, shared by WithOptions copies
+
+	// renderClient talks to the operator-configured Config.RenderURL, so it
+	// skips the HostPolicy that guards user-supplied URLs.
+	renderClient *http.Client
+
+	// insecureClient is httpClient without certificate verification, used
+	// for Options.Insecure. nil unless Config.AllowInsecure is set.
+	insecureClient *http.Client
 }
 
 // NewClient returns a Client with validated config values.
@@ -105,62 +198,835 @@ func NewClient(cfg Config) *Client {
 	if cfg.BaseRetryDelay <= 0 {
 		cfg.BaseRetryDelay = 300 * time.Millisecond
 	}
-	return &Client{
-		httpClient: &http.Client{Timeout: cfg.HTTPTimeout},
-		cfg:        cfg,
+	if cfg.MaxBodyBytes <= 0 {
+		cfg.MaxBodyBytes = 10 << 20
+	}
+	if len(cfg.LinkAttrs) == 0 {
+		cfg.LinkAttrs = DefaultLinkAttrs
+	}
+
+	httpClient := &http.Client{
+		Timeout: cfg.HTTPTimeout,
+		// Redirect targets must pass the allow/deny lists too.
+		CheckRedirect: checkRedirect(cfg.HostPolicy),
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxConnsPerHost = max(cfg.MaxConnsPerHost, 0)
+	if cfg.MaxIdlePerHost > 0 {
+		transport.MaxIdleConnsPerHost = cfg.MaxIdlePerHost
+	}
+	if cfg.HostPolicy.BlockPrivate {
+		dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second, Control: dialControl}
+		transport.DialContext = dialer.DialContext
+	}
+	httpClient.Transport = transport
+	if len(cfg.Proxies) > 0 {
+		httpClient.Transport = newProxyRotator(transport, cfg.Proxies, cfg.ProxyRotation, cfg.HostPolicy.BlockPrivate)
+	}
+
+	c := &Client{
+		httpClient:   httpClient,
+		cfg:          cfg,
+		renderClient: &http.Client{Timeout: cfg.HTTPTimeout},
+		linkTypes:    newLinkTypeCache(),
+		robots:       newRobotsCache(),
+		stats:        newStatsTable(),
+	}
+	if cfg.CacheSize > 0 {
+		c.cache = newPageCache(cfg.CacheSize)
+	}
+	if cfg.AllowInsecure {
+		insecure := transport.Clone()
+		insecure.TLSClientConfig = &tls.Config{InsecureSkipVerify: true} // opt-in per request, see Options.Insecure
+		ic := *httpClient
+		ic.Transport = insecure
+		if len(cfg.Proxies) > 0 {
+			ic.Transport = newProxyRotator(insecure, cfg.Proxies, cfg.ProxyRotation, cfg.HostPolicy.BlockPrivate)
+		}
+		c.insecureClient = &ic
 	}
+	return c
 }
 
 // MaxURLs returns the configured cap for one request.
 func (c *Client) MaxURLs() int { return c.cfg.MaxURLsPerRequest }
 
+// MaxOutputBytes returns the configured cap on a JSON scrape response, see
+// ScrapeOutput.Truncate.
+func (c *Client) MaxOutputBytes() int64 { return c.cfg.MaxOutputBytes }
+
+// Workers returns the configured worker pool size.
+func (c *Client) Workers() int { return c.cfg.WorkerCount }
+
 // --- Core fetch logic ---
 
-// fetch performs an HTTP GET with automatic retry + exponential backoff.
-// It retries on network errors, timeouts, 429, and 5xx responses (up to maxRetries).
-// This is the fetchFn passed to the worker pool.
-func (c *Client) fetch(ctx context.Context, pageURL, selector string) ([]ScrapeResult, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+// ErrResponseTooLarge is returned when a page body exceeds Config.MaxBodyBytes.
+var ErrResponseTooLarge = errors.New("response too large")
+
+// ErrResultsTooLarge is returned when the titles and links extracted from a
+// page exceed Config.MaxResultBytes. Unlike the result count, this catches
+// pages with few but enormous matches, such as a selector matching <body>.
+var ErrResultsTooLarge = errors.New("results too large")
+
+// StatusError is returned when a page answers with a status other than
+// 200 OK (or 304 for a cached page), including retryable statuses that kept
+// failing.
+type StatusError struct {
+	StatusCode int
+	Status     string        // e.g. "404 Not Found"
+	RetryAfter time.Duration // the wait a 429 or 503 asked for in Retry-After; 0 when it gave none
+}
+
+// newStatusError returns the StatusError of res.
+func newStatusError(res *http.Response) *StatusError {
+	e := &StatusError{StatusCode: res.StatusCode, Status: res.Status}
+	if res.StatusCode == http.StatusTooManyRequests || res.StatusCode == http.StatusServiceUnavailable {
+		e.RetryAfter, _ = parseRetryAfter(res.Header.Get("Retry-After"), time.Now())
+	}
+	return e
+}
+
+func (e *StatusError) Error() string {
+	msg := "HTTP " + e.Status // Status starts with the code
+	switch {
+	case e.StatusCode == http.StatusTooManyRequests && e.RetryAfter > 0:
+		return fmt.Sprintf("rate limited, retry after %s (%s)", e.RetryAfter, msg)
+	case e.StatusCode == http.StatusTooManyRequests:
+		return "rate limited (" + msg + ")"
+	case e.RetryAfter > 0:
+		return fmt.Sprintf("%s, retry after %s", msg, e.RetryAfter)
+	}
+	return msg
+}
+
+// readBody reads at most limit bytes from r. It reads one extra byte so a body
+// of exactly limit bytes is accepted while anything longer is rejected.
+func readBody(r io.Reader, limit int64) ([]byte, error) {
+	body, err := io.ReadAll(io.LimitReader(r, limit+1))
 	if err != nil {
 		return nil, err
 	}
+	if int64(len(body)) > limit {
+		return nil, fmt.Errorf("%w: limit is %d bytes", ErrResponseTooLarge, limit)
+	}
+	return body, nil
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.n += int64(n)
+	return n, err
+}
+
+// RawPage is a response body exactly as fetched, before any parsing.
+type RawPage struct {
+	URL         string
+	FinalURL    string // URL after redirects; relative links resolve against it
+	ContentType string // Content-Type header sent by the target (may be empty)
+	Body        []byte
+	Cached      bool   // the target answered 304 Not Modified and Body came from the cache
+	Timing      Timing // network phases of the fetch; ParseMs is left zero
+	Downloaded  int64  // body bytes read from the network; 0 when revalidated with a 304
+	Scheme      string // scheme the page was fetched over; "https" when Options.UpgradeInsecure upgraded URL
+	Proxy       string // the Config.Proxies entry that answered, password removed; "" without proxies
+}
+
+// ErrNotHTML is returned by the HEAD precheck when the target is not a web page.
+var ErrNotHTML = errors.New("not an HTML page")
+
+// precheck HEADs pageURL and rejects it when the server reports a non-HTML
+// content type or a body larger than Config.MaxBodyBytes. Servers that fail
+// or refuse HEAD are given the benefit of the doubt: the GET goes ahead.
+func (c *Client) precheck(ctx context.Context, pageURL string) error {
+	req, err := c.newRequest(ctx, http.MethodHead, pageURL)
+	if err != nil {
+		return err
+	}
+	hc, err := c.pageClient(ctx)
+	if err != nil {
+		return err
+	}
+	res, err := hc.Do(req)
+	if err != nil {
+		if errors.Is(err, ErrHostNotAllowed) {
+			return err
+		}
+		return nil
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil // 405, 501, ... — fall back to GET
+	}
+
+	if ct := res.Header.Get("Content-Type"); ct != "" && !isHTML(ct) {
+		return fmt.Errorf("%w: content type is %s", ErrNotHTML, ct)
+	}
+	if res.ContentLength > c.cfg.MaxBodyBytes {
+		return fmt.Errorf("%w: %d bytes, limit is %d bytes", ErrResponseTooLarge, res.ContentLength, c.cfg.MaxBodyBytes)
+	}
+	return nil
+}
+
+// newRequest builds a body-less request carrying the configured User-Agent
+// and Accept-Language, then the Config.HostHeaders of its host, then
+// Options.Headers and Options.Lang: the more specific source wins.
+func (c *Client) newRequest(ctx context.Context, method, target string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.cfg.UserAgent != "" {
+		req.Header.Set("User-Agent", c.cfg.UserAgent)
+	}
+	if c.cfg.AcceptLanguage != "" {
+		req.Header.Set("Accept-Language", c.cfg.AcceptLanguage)
+	}
+	c.cfg.HostHeaders.apply(req.Header, req.URL.Hostname())
+	for name, values := range c.opts.Headers {
+		req.Header[name] = values
+	}
+	if c.opts.Lang != "" {
+		req.Header.Set("Accept-Language", c.opts.Lang)
+	}
+	return req, nil
+}
+
+// language is the Accept-Language to send: Options.Lang, else Config.AcceptLanguage.
+func (c *Client) language() string {
+	if c.opts.Lang != "" {
+		return c.opts.Lang
+	}
+	return c.cfg.AcceptLanguage
+}
+
+// pageClient returns the HTTP client for target pages: the insecure one for
+// Options.Insecure, which needs Config.AllowInsecure, and with Options.Login
+// one carrying the session cookies, logging in first if need be.
+func (c *Client) pageClient(ctx context.Context) (*http.Client, error) {
+	hc := c.httpClient
+	if c.opts.Insecure {
+		if c.insecureClient == nil {
+			return nil, ErrInsecureNotAllowed
+		}
+		hc = c.insecureClient
+	}
+	if c.session != nil {
+		return c.sessionClient(ctx, hc)
+	}
+	return hc, nil
+}
+
+// isHTML reports whether a Content-Type header describes an HTML document.
+func isHTML(contentType string) bool {
+	ct := strings.ToLower(contentType)
+	return strings.HasPrefix(ct, "text/html") || strings.HasPrefix(ct, "application/xhtml+xml")
+}
 
-	res, err := withRetry(c.cfg.MaxRetries, c.cfg.BaseRetryDelay, func() (*http.Response, error) {
-		return c.httpClient.Do(req)
+// get fetches pageURL with fetchPage, after checking robots.txt for
+// Options.Robots. With Options.UpgradeInsecure an
+// http:// URL is tried over https:// first, in a single attempt, and fetched as
+// given only when that fails; RawPage.Scheme tells which one answered.
+func (c *Client) get(ctx context.Context, pageURL string) (RawPage, error) {
+	if c.opts.Robots {
+		if err := c.checkRobots(ctx, pageURL); err != nil {
+			return RawPage{}, err
+		}
+	}
+	if c.opts.UpgradeInsecure {
+		if secure, ok := upgradeScheme(pageURL); ok {
+			page, err := c.fetchPage(ctx, secure, 1)
+			if err == nil {
+				page.URL = pageURL
+				return page, nil
+			}
+			if ctx.Err() != nil || errors.Is(err, ErrHostNotAllowed) {
+				return RawPage{}, err
+			}
+		}
+	}
+	return c.fetchPage(ctx, pageURL, c.cfg.MaxRetries)
+}
+
+// upgradeScheme returns pageURL with http:// replaced by https://, or false
+// when it is not an http URL.
+func upgradeScheme(pageURL string) (string, bool) {
+	u, err := url.Parse(pageURL)
+	if err != nil || !strings.EqualFold(u.Scheme, "http") {
+		return "", false
+	}
+	u.Scheme = "https"
+	return u.String(), true
+}
+
+// fetchPage performs an HTTP GET with automatic retry + exponential backoff
+// and returns the body, capped at Config.MaxBodyBytes.
+// It retries on network errors, timeouts and Config.RetryStatuses, making at
+// most attempts tries.
+//
+// Pages fetched before are revalidated with If-None-Match / If-Modified-Since;
+// a 304 answer returns the cached body with RawPage.Cached set.
+//
+// With Options.Render the page is fetched through Config.RenderURL instead,
+// bypassing the precheck and the cache.
+func (c *Client) fetchPage(ctx context.Context, pageURL string, attempts int) (RawPage, error) {
+	fetchURL := pageURL
+	hc, err := c.pageClient(ctx)
+	if err != nil {
+		return RawPage{}, err
+	}
+	if c.opts.Render {
+		if fetchURL, err = c.renderRequestURL(pageURL); err != nil {
+			return RawPage{}, err
+		}
+		hc = c.renderClient
+	} else if c.opts.Precheck {
+		if err := c.precheck(ctx, pageURL); err != nil {
+			return RawPage{}, err
+		}
+	}
+	useCache := c.cache != nil && !c.opts.Render
+	cacheKey := pageURL + "\x00" + c.language()
+
+	trace := &phaseTrace{}
+	via := &proxyReport{}
+	req, err := c.newRequest(httptrace.WithClientTrace(withProxyReport(ctx, via), trace.clientTrace()), http.MethodGet, fetchURL)
+	if err != nil {
+		return RawPage{}, err
+	}
+	var cached cacheEntry
+	var haveCached bool
+	if useCache {
+		cached, haveCached = c.cache.lookup(cacheKey, req)
+	}
+
+	res, err := withRetry(ctx, attempts, c.cfg.BaseRetryDelay, c.cfg.RetryStatuses, func() (*http.Response, error) {
+		trace.begin()
+		res, err := hc.Do(req)
+		if err == nil && res.StatusCode != http.StatusOK && res.StatusCode != http.StatusNotModified && blockedResponse(res) {
+			res.Body.Close()
+			return nil, ErrBlocked // retrying won't get past the challenge
+		}
+		return res, err
 	})
 	if err != nil {
-		return nil, fmt.Errorf("%s: %w", pageURL, err)
+		return RawPage{}, fmt.Errorf("%s: %w", pageURL, err)
 	}
 	defer res.Body.Close()
 
+	if res.StatusCode == http.StatusNotModified && haveCached {
+		page := cached.page
+		page.Cached = true
+		page.Timing = trace.finish()
+		page.Downloaded = 0
+		page.Proxy = via.proxy
+		return page, nil
+	}
 	if res.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("HTTP %d %s", res.StatusCode, res.Status)
+		return RawPage{}, newStatusError(res)
 	}
 
-	doc, err := goquery.NewDocumentFromReader(res.Body)
+	// Reject early when the server announces an oversized body; otherwise the
+	// limited read below catches bodies that lie about (or omit) their length.
+	if res.ContentLength > c.cfg.MaxBodyBytes {
+		return RawPage{}, fmt.Errorf("%w: limit is %d bytes", ErrResponseTooLarge, c.cfg.MaxBodyBytes)
+	}
+	counted := &countingReader{r: res.Body}
+	body, err := readBody(counted, c.cfg.MaxBodyBytes)
 	if err != nil {
-		return nil, err
+		return RawPage{}, err
+	}
+	if blockedPage(body) {
+		return RawPage{}, fmt.Errorf("%s: %w", pageURL, ErrBlocked)
+	}
+
+	page := RawPage{URL: pageURL, FinalURL: pageURL, ContentType: res.Header.Get("Content-Type"), Body: body, Scheme: req.URL.Scheme}
+	if !c.opts.Render && res.Request != nil && res.Request.URL != nil {
+		// Render responses come from the render service, whose URL is no base.
+		page.FinalURL = res.Request.URL.String()
+	}
+	if useCache {
+		c.cache.store(cacheKey, page, res.Header)
+	}
+	page.Timing = trace.finish()
+	page.Downloaded = counted.n
+	page.Proxy = via.proxy
+	return page, nil
+}
+
+// FetchRaw returns the page body without running any selector over it.
+// It shares the retry and body-size limits of a normal scrape.
+func (c *Client) FetchRaw(ctx context.Context, pageURL string) (RawPage, error) {
+	return c.get(ctx, pageURL)
+}
+
+// pageMeta describes how a page was fetched, alongside its parsed results.
+type pageMeta struct {
+	cached   bool   // the body was reused after a 304 Not Modified
+	finalURL string // RawPage.FinalURL, the base for relative links
+	timing   Timing
+	parsed   time.Duration // spent in document parsing, before selectors run
+	bytes    int64         // RawPage.Downloaded
+	scheme   string        // RawPage.Scheme
+	proxy    string        // RawPage.Proxy
+	count    int           // results kept, or counted with Options.CountOnly
+	title    string        // the page's <title>
+	desc     string        // the page's meta description
+	canon    string        // the page's <link rel="canonical">, resolved
+	warning  string        // parseWarning for the page, "" when it looks sound
+	fixed    string        // the selector variant used with Options.AutoFix
+	records  []Record      // a grouped selector's matches paired up, see Client.records
+}
+
+// document downloads a page and parses it into a goquery document.
+// meta.parsed is how long the parse took.
+func (c *Client) document(ctx context.Context, pageURL string) (doc *goquery.Document, meta pageMeta, err error) {
+	page, err := c.get(ctx, pageURL)
+	if err != nil {
+		return nil, pageMeta{}, err
+	}
+	start := time.Now()
+	meta = pageMeta{cached: page.Cached, finalURL: page.FinalURL, timing: page.Timing, bytes: page.Downloaded, scheme: page.Scheme, proxy: page.Proxy}
+
+	// Transcode to UTF-8 using the Content-Type charset, a <meta charset> tag
+	// or byte sniffing, so ISO-8859-1 or Shift-JIS titles don't turn into mojibake.
+	body, err := charset.NewReader(bytes.NewReader(page.Body), page.ContentType)
+	if err != nil {
+		return nil, pageMeta{}, fmt.Errorf("decode %s: %w", page.ContentType, err)
 	}
 
+	doc, err = goquery.NewDocumentFromReader(body)
+	meta.parsed = time.Since(start)
+	if err == nil {
+		meta.warning = parseWarning(doc, page.Body)
+	}
+	return doc, meta, err
+}
+
+// A body of suspectBodyBytes or more parsing to fewer than
+// minParsedElements elements looks truncated or like a block page.
+const (
+	minParsedElements = 5
+	suspectBodyBytes  = 1024
+)
+
+// parseWarning tells an empty response apart from one the parser made
+// nothing of: the HTML parser accepts anything, so plain text, a cut-off
+// download or a binary body all "parse" into a bare <html><body>.
+// It returns "" for a page that looks sound.
+func parseWarning(doc *goquery.Document, body []byte) string {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return fmt.Sprintf("empty page: the response body has no content (%d bytes)", len(body))
+	}
+	n := 0
+	if len(doc.Nodes) > 0 {
+		n = countElements(doc.Nodes[0], minParsedElements)
+	}
+	switch {
+	case n == 0:
+		return fmt.Sprintf("parse produced no elements from %d bytes: the response may not be HTML", len(body))
+	case n < minParsedElements && len(body) >= suspectBodyBytes:
+		return fmt.Sprintf("only %d elements parsed from %d bytes: the response may be truncated or blocked", n, len(body))
+	}
+	return ""
+}
+
+// countElements counts the element nodes under n, up to limit, leaving out
+// the <html>, <head> and <body> the parser adds to every document.
+func countElements(n *html.Node, limit int) int {
+	count := 0
+	for c := n.FirstChild; c != nil && count < limit; c = c.NextSibling {
+		if c.Type == html.ElementNode {
+			switch c.Data {
+			case "html", "head", "body":
+			default:
+				count++
+			}
+		}
+		count += countElements(c, limit-count)
+	}
+	return min(count, limit)
+}
+
+// fetch downloads a page and applies the CSS selector to it. A grouped
+// selector (see ParseSelectorGroups) is matched group by group, each result
+// tagged with its group's name. The text of :contains() may be unquoted, see
+// quoteContains.
+// This is the fetchFn passed to the worker pool.
+func (c *Client) fetch(ctx context.Context, pageURL, selector string) ([]ScrapeResult, pageMeta, error) {
+	doc, meta, err := c.document(ctx, pageURL)
+	if err != nil {
+		return nil, pageMeta{}, err
+	}
+	start := time.Now()
+	meta.title, meta.desc = pageSummary(doc)
+
+	// Resolve against where the page actually came from: a redirect
+	// (http→https, /old → /new/) changes what relative links mean.
+	base, _ := url.Parse(meta.finalURL) // nil base leaves links untouched
+	meta.canon = canonicalURL(doc, base)
+
+	selector = quoteContains(selector)
+	groups, grouped := ParseSelectorGroups(selector)
+	if !grouped {
+		groups = []SelectorGroup{{Selector: selector}}
+		if c.opts.AutoFix && doc.Find(selector).Length() == 0 {
+			if fixed := repairSelector(doc, selector); fixed != "" {
+				groups[0].Selector, meta.fixed = fixed, fixed
+			}
+		}
+	}
+	if grouped && !c.opts.CountOnly {
+		meta.records = c.records(doc, groups, base)
+	}
 	var results []ScrapeResult
-	doc.Find(selector).Each(func(_ int, s *goquery.Selection) {
-		title := strings.TrimSpace(s.Text())
-		if title == "" {
+	for _, g := range groups {
+		if c.opts.CountOnly {
+			meta.count += c.countMatches(doc.Find(g.Selector))
+			continue
+		}
+		extracted, err := c.extractAll(doc.Find(g.Selector), base)
+		if err != nil {
+			return nil, pageMeta{}, err
+		}
+		matched := c.opts.filterResults(extracted)
+		c.opts.rankResults(matched)
+		for i := range matched {
+			matched[i].Group = g.Name
+		}
+		results = append(results, matched...)
+		meta.count += len(matched)
+	}
+	meta.timing.ParseMs = millis(meta.parsed + time.Since(start))
+	return results, meta, nil
+}
+
+// pageSummary returns the <title> of doc and its meta description, falling
+// back to og:description.
+func pageSummary(doc *goquery.Document) (title, desc string) {
+	title = strings.Join(strings.Fields(doc.Find("title").First().Text()), " ")
+	desc = firstNonEmpty(
+		strings.TrimSpace(doc.Find("meta[name='description']").First().AttrOr("content", "")),
+		strings.TrimSpace(doc.Find("meta[property='og:description']").First().AttrOr("content", "")),
+	)
+	return title, desc
+}
+
+// canonicalURL returns the page's <link rel="canonical"> resolved against
+// base, or "" when it has none or it is not an http(s) URL.
+func canonicalURL(doc *goquery.Document, base *url.URL) string {
+	href := strings.TrimSpace(doc.Find(`link[rel~="canonical"]`).First().AttrOr("href", ""))
+	if href == "" || base == nil {
+		return ""
+	}
+	u, err := url.Parse(resolveLink(base, href))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ""
+	}
+	return u.String()
+}
+
+// countMatches is how many results extractAll and filterResults would keep
+// from sel, found without building them.
+func (c *Client) countMatches(sel *goquery.Selection) int {
+	terms := filterTerms(c.opts.Filter)
+	n := 0
+	sel.Each(func(_ int, s *goquery.Selection) {
+		title, link := strings.TrimSpace(s.Text()), ""
+		if isImage(s) {
+			title, link = imageTitle(s), imageLink(s)
+		}
+		if title == "" && link == "" {
+			if c.opts.Extract != ExtractHTML {
+				return
+			}
+			if inner, _ := s.Html(); strings.TrimSpace(inner) == "" {
+				return
+			}
+		}
+		if link == "" {
+			link = linkHref(s, c.linkAttrs())
+		}
+		if !c.opts.linkAllowed(s, link, c.linkAttrs()) {
 			return
 		}
-		link, _ := s.Attr("href")
-		if link != "" && !strings.HasPrefix(link, "mailto:") {
-			if base, err := url.Parse(pageURL); err == nil {
-				if href, err := url.Parse(link); err == nil {
-					link = base.ResolveReference(href).String()
-				}
+		if len(terms) > 0 {
+			title, _ = truncateTitle(applyRegex(c.opts.TitleRegex, title), c.opts.MaxTitleLen)
+			if relevance(title, terms) == 0 {
+				return
 			}
 		}
-		results = append(results, ScrapeResult{Title: title, Link: link})
+		n++
 	})
+	return n
+}
+
+// parallelMinMatches is the fewest matches worth splitting across
+// Config.ParseWorkers; below it goroutine start-up costs more than it saves.
+const parallelMinMatches = 256
+
+// extractAll turns every element of sel into a ScrapeResult, in document
+// order. With Config.ParseWorkers > 1 and enough matches, the elements are
+// split into contiguous chunks processed concurrently; the document is only
+// read, so the goroutines can share it.
+//
+// Extraction stops with ErrResultsTooLarge once the titles and links built
+// so far pass Config.MaxResultBytes.
+func (c *Client) extractAll(sel *goquery.Selection, base *url.URL) ([]ScrapeResult, error) {
+	n := sel.Length()
+	budget := c.cfg.MaxResultBytes
+	var used atomic.Int64
+	over := func(r ScrapeResult) bool {
+		return budget > 0 && used.Add(int64(len(r.Title)+len(r.Link))) > budget
+	}
+	tooLarge := func(kept int) error {
+		return fmt.Errorf("%w: titles and links passed the %d-byte budget after %d results", ErrResultsTooLarge, budget, kept)
+	}
+
+	workers := min(c.cfg.ParseWorkers, n/parallelMinMatches)
+	if workers <= 1 {
+		var results []ScrapeResult
+		var err error
+		sel.EachWithBreak(func(_ int, s *goquery.Selection) bool {
+			r, ok := c.extract(s, base)
+			if !ok {
+				return true
+			}
+			results = append(results, r)
+			if over(r) {
+				err = tooLarge(len(results))
+				return false
+			}
+			return true
+		})
+		if err != nil {
+			return nil, err
+		}
+		return results, nil
+	}
+
+	out := make([]ScrapeResult, n)
+	keep := make([]bool, n)
+	chunk := (n + workers - 1) / workers
+	var wg sync.WaitGroup
+	var stopped atomic.Bool
+	for lo := 0; lo < n; lo += chunk {
+		hi := min(lo+chunk, n)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := lo; i < hi && !stopped.Load(); i++ {
+				out[i], keep[i] = c.extract(sel.Eq(i), base)
+				if keep[i] && over(out[i]) {
+					stopped.Store(true)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	var results []ScrapeResult
+	for i, ok := range keep {
+		if ok {
+			results = append(results, out[i])
+		}
+	}
+	if stopped.Load() {
+		return nil, tooLarge(len(results))
+	}
 	return results, nil
 }
 
+// extract builds the ScrapeResult for one matched element. It reports false
+// for elements with neither text nor (with ExtractHTML) inner HTML, and
+// for links Options.LinkSchemes rules out. An image (<img> or <source>) has its alt text as title and the largest
+// candidate of its srcset, else its src, as link; it is kept for the link
+// alone.
+func (c *Client) extract(s *goquery.Selection, base *url.URL) (ScrapeResult, bool) {
+	title := strings.TrimSpace(s.Text())
+	var link string
+	if isImage(s) {
+		title, link = imageTitle(s), imageLink(s)
+	}
+	var inner string
+	if c.opts.Extract == ExtractHTML {
+		inner, _ = s.Html()
+		inner, _ = truncateTitle(strings.TrimSpace(inner), maxHTMLLen)
+	}
+	if title == "" && inner == "" && link == "" {
+		return ScrapeResult{}, false
+	}
+	if link == "" {
+		link = linkHref(s, c.linkAttrs())
+	}
+	if !c.opts.linkAllowed(s, link, c.linkAttrs()) {
+		return ScrapeResult{}, false
+	}
+	if !c.opts.RawLinks {
+		link = resolveLink(base, link)
+	}
+	title = applyRegex(c.opts.TitleRegex, title)
+	link = applyRegex(c.opts.LinkRegex, link)
+	r := ScrapeResult{
+		Title:  title,
+		Link:   link,
+		Attrs:  extractAttrs(s, c.opts.Attrs),
+		HTML:   inner,
+		Fields: extractFields(s, c.opts.Fields),
+	}
+	if c.opts.Debug {
+		r.DOMPath = domPath(s)
+	}
+	if short, cut := truncateTitle(title, c.opts.MaxTitleLen); cut {
+		r.Title, r.FullTitle = short, title
+	}
+	return r, true
+}
+
+// applyRegex returns the first capture group of re's match in s, or the
+// whole match when re has no groups. A nil re or no match leaves s as is.
+func applyRegex(re *regexp.Regexp, s string) string {
+	if re == nil {
+		return s
+	}
+	m := re.FindStringSubmatch(s)
+	switch {
+	case m == nil:
+		return s
+	case len(m) > 1:
+		return strings.TrimSpace(m[1])
+	default:
+		return strings.TrimSpace(m[0])
+	}
+}
+
+// truncateTitle shortens title to at most max characters, the last being an
+// ellipsis, and reports whether it did. max <= 0 leaves title unchanged.
+func truncateTitle(title string, max int) (string, bool) {
+	if max <= 0 {
+		return title, false
+	}
+	runes := []rune(title)
+	if len(runes) <= max {
+		return title, false
+	}
+	return strings.TrimSpace(string(runes[:max-1])) + "…", true
+}
+
+// linkAttrs is the link fallback chain: Options.LinkAttrs, else Config.LinkAttrs.
+func (c *Client) linkAttrs() []string {
+	if len(c.opts.LinkAttrs) > 0 {
+		return c.opts.LinkAttrs
+	}
+	return c.cfg.LinkAttrs
+}
+
+// linkHref returns the first attribute in names whose value is neither empty
+// nor a bare "#". When every candidate is a placeholder, the first non-empty
+// one is kept so href="#" still resolves as it always did.
+func linkHref(s *goquery.Selection, names []string) string {
+	var fallback string
+	for _, name := range names {
+		v := strings.TrimSpace(s.AttrOr(name, ""))
+		if v == "" {
+			continue
+		}
+		if v != "#" {
+			return v
+		}
+		if fallback == "" {
+			fallback = v
+		}
+	}
+	return fallback
+}
+
+// domPath describes the first element of s and its ancestors from <html>
+// down as "tag#id.class" steps joined by " > ".
+func domPath(s *goquery.Selection) string {
+	var steps []string
+	for n := s.Get(0); n != nil && n.Type == html.ElementNode; n = n.Parent {
+		step := n.Data
+		for _, a := range n.Attr {
+			switch a.Key {
+			case "id":
+				if id := strings.TrimSpace(a.Val); id != "" {
+					step += "#" + id
+				}
+			case "class":
+				for _, cls := range strings.Fields(a.Val) {
+					step += "." + cls
+				}
+			}
+		}
+		steps = append(steps, step)
+	}
+	slices.Reverse(steps)
+	return strings.Join(steps, " > ")
+}
+
+// extractAttrs returns the named attributes present on s, or nil when none
+// were requested or found.
+func extractAttrs(s *goquery.Selection, names []string) map[string]string {
+	var attrs map[string]string
+	for _, name := range names {
+		v, ok := s.Attr(name)
+		if !ok {
+			continue
+		}
+		if attrs == nil {
+			attrs = make(map[string]string, len(names))
+		}
+		attrs[name] = v
+	}
+	return attrs
+}
+
+// extractFields applies a field mapping to one matched element. Fields whose
+// selector matches nothing, or whose attribute is missing, are left out.
+func extractFields(s *goquery.Selection, specs []FieldSpec) map[string]string {
+	if len(specs) == 0 {
+		return nil
+	}
+	fields := make(map[string]string, len(specs))
+	for _, f := range specs {
+		target := s
+		if f.Selector != "" {
+			target = s.Find(f.Selector).First()
+		}
+		if target.Length() == 0 {
+			continue
+		}
+		if f.Attr == "" {
+			fields[f.Name] = strings.Join(strings.Fields(target.Text()), " ")
+		} else if v, ok := target.Attr(f.Attr); ok {
+			fields[f.Name] = v
+		}
+	}
+	return fields
+}
+
+// resolveLink makes href absolute against the page URL using RFC 3986 rules,
+// so relative paths, protocol-relative ("//cdn.example.com/x") and
+// fragment-only ("#section") links all resolve uniformly.
+// Empty, mailto: and unparseable hrefs are returned unchanged.
+func resolveLink(base *url.URL, href string) string {
+	href = strings.TrimSpace(href)
+	if href == "" || base == nil || strings.HasPrefix(href, "mailto:") {
+		return href
+	}
+	ref, err := url.Parse(href)
+	if err != nil {
+		return href
+	}
+	return base.ResolveReference(ref).String()
+}
+
 // --- Public scraping methods ---
 
 // JobResult is one completed URL delivered by ScrapeStreamed.
@@ -168,6 +1034,14 @@ func (c *Client) fetch(ctx context.Context, pageURL, selector string) ([]ScrapeR
 type JobResult struct {
 	URL        string
 	Items      []ScrapeResult
+	Cached     bool   // page was unchanged (304) and parsed from the cache
+	Timing     Timing // phase breakdown of the fetch; zero on error
+	Bytes      int64  // body bytes downloaded for this page
+	Scheme     string // "http" or "https", see Options.UpgradeInsecure
+	Proxy      string // proxy that answered, see Config.Proxies
+	Count      int    // len(Items), or the matches counted with Options.CountOnly
+	Page       PageInfo
+	Records    []Record // the page's matches of a grouped selector, paired up
 	DurationMs int64
 	Err        error
 }
@@ -182,6 +1056,13 @@ type JobResult struct {
 //	    ui.Progress(n, total, r.URL, len(r.Items), r.DurationMs, r.Err)
 //	}
 func (c *Client) ScrapeStreamed(urls []string, selector string) <-chan JobResult {
+	return c.ScrapeStreamedContext(context.Background(), urls, selector)
+}
+
+// ScrapeStreamedContext is ScrapeStreamed, stopped when ctx is done: pages
+// being fetched fail with ctx's error and the rest are never started, so a
+// caller whose client went away can drain the channel quickly.
+func (c *Client) ScrapeStreamedContext(ctx context.Context, urls []string, selector string) <-chan JobResult {
 	out := make(chan JobResult, len(urls))
 
 	if len(urls) == 0 {
@@ -189,24 +1070,26 @@ func (c *Client) ScrapeStreamed(urls []string, selector string) <-chan JobResult
 		return out
 	}
 
-	workers := min(c.cfg.WorkerCount, len(urls))
-	p := newPool(workers, c.fetch, newRateLimiter(c.cfg.RateLimit))
-
-	// Submit all jobs before starting the drain goroutine so the pool is
-	// fully loaded — workers start immediately as jobs arrive.
-	go func() {
-		for _, u := range urls {
-			p.submit(scrapeJob{url: u, selector: selector})
-		}
-		p.done() // signal no more jobs; workers drain then close p.results
-	}()
+	jobs := make([]scrapeJob, len(urls))
+	for i, u := range urls {
+		jobs[i] = scrapeJob{index: i, url: u, selector: selector}
+	}
+	results := c.runJobs(ctx, jobs)
 
 	// Translate internal jobResults into public JobResults and forward them.
 	go func() {
-		for r := range p.results {
+		for r := range results {
 			out <- JobResult{
 				URL:        r.url,
 				Items:      r.items,
+				Cached:     r.meta.cached,
+				Timing:     r.meta.timing,
+				Bytes:      r.meta.bytes,
+				Scheme:     r.meta.scheme,
+				Proxy:      r.meta.proxy,
+				Count:      r.meta.count,
+				Page:       PageInfo{URL: r.url, Title: r.meta.title, Description: r.meta.desc, Canonical: r.meta.canon, Warning: r.meta.warning, FixedSelector: r.meta.fixed},
+				Records:    r.meta.records,
 				DurationMs: r.durationMs,
 				Err:        r.err,
 			}
@@ -217,20 +1100,142 @@ func (c *Client) ScrapeStreamed(urls []string, selector string) <-chan JobResult
 	return out
 }
 
+// runJobs starts a worker pool sized for len(jobs), submits every job and
+// returns the pool's results channel, which is closed once all jobs finish.
+// Jobs not yet submitted when ctx is done are dropped. jobs must not be empty.
+func (c *Client) runJobs(ctx context.Context, jobs []scrapeJob) <-chan jobResult {
+	workers := min(c.cfg.WorkerCount, len(jobs))
+	fetch := c.fetch
+	if c.cfg.PageTimeout > 0 {
+		// Each URL gets its own deadline, so one slow page is skipped
+		// instead of holding up the rest of the scrape.
+		fetch = func(ctx context.Context, pageURL, selector string) ([]ScrapeResult, pageMeta, error) {
+			ctx, cancel := context.WithTimeout(ctx, c.cfg.PageTimeout)
+			defer cancel()
+			return c.fetch(ctx, pageURL, selector)
+		}
+	}
+	p := newPool(ctx, workers, c.recordStats(fetch), newRateLimiter(c.cfg.RateLimit), newHostSpacer(c.cfg.CrawlDelay))
+
+	// Submit from a separate goroutine so callers can start draining results
+	// immediately — workers start as soon as jobs arrive.
+	go func() {
+		for _, j := range jobs {
+			if ctx.Err() != nil {
+				break
+			}
+			p.submit(j)
+		}
+		p.done() // signal no more jobs; workers drain then close p.results
+	}()
+
+	return p.results
+}
+
+// recordStats wraps fetch to add each page it fetches to c.Stats.
+func (c *Client) recordStats(fetch fetchFn) fetchFn {
+	return func(ctx context.Context, pageURL, selector string) ([]ScrapeResult, pageMeta, error) {
+		start := time.Now()
+		items, meta, err := fetch(ctx, pageURL, selector)
+		c.stats.record(pageURL, time.Since(start), err)
+		return items, meta, err
+	}
+}
+
 // ScrapeWithWorkerPool scrapes all URLs concurrently and merges results into one slice.
 // Errors are collected separately so partial results are still returned.
 // For streaming per-URL progress use ScrapeStreamed instead.
 func (c *Client) ScrapeWithWorkerPool(urls []string, selector string) ([]ScrapeResult, []error) {
-	var combined []ScrapeResult
-	var errs []error
-	for r := range c.ScrapeStreamed(urls, selector) {
+	run := c.ScrapeAll(urls, selector)
+	return run.Results, run.Errs
+}
+
+// ScrapeRun is the outcome of ScrapeAll.
+type ScrapeRun struct {
+	Results []ScrapeResult
+	Errs    []error      // one per failed URL, prefixed with the URL
+	Cached  bool         // every page answered 304 Not Modified and was reused from the cache
+	Skipped []string     // URLs abandoned because Config.PageTimeout expired (also in Errs)
+	Timings []PageTiming // per-page phase breakdown of the successful fetches, in completion order
+	Pages   []PageInfo   // title and description of the successful pages, in completion order
+	Records []Record     // with a grouped selector, each page's matches paired up, pages in completion order
+
+	BytesDownloaded int64  // body bytes read across all pages
+	Count           int    // matches across all pages after Dedup, before Offset and Count; the only output of Options.CountOnly
+	StoppedReason   string // why pages were left unfetched, see Options.MaxPages; "" when all were scraped
+	Attempted       int    // pages scraped or failed, without those cut by MaxPages or abandoned; all failed when len(Errs) equals it
+}
+
+// ScrapeAll is ScrapeWithWorkerPool with the extra detail of a ScrapeRun.
+// Options.Dedup drops repeats across pages. With Options.Rank the combined
+// results are ordered by Score, and for a grouped selector or
+// Options.GroupBy they are ordered by group; Options.Offset and
+// Options.Count are then applied to them. Options.ClassifyLinks then
+// classifies the links of the results that are left.
+//
+// Only the first Options.MaxPages URLs are scraped. In a multi-page scrape,
+// once Config.MaxTotalResults (or the lower Options.MaxTotalResults)
+// results are found, after Dedup, the pages not yet finished are abandoned,
+// and after ranking the results past it are dropped (CountOnly keeps
+// counting). StoppedReason reports either.
+func (c *Client) ScrapeAll(urls []string, selector string) ScrapeRun {
+	run := ScrapeRun{Cached: len(urls) > 0}
+	if limit := c.opts.MaxPages; limit > 0 && len(urls) > limit {
+		run.StoppedReason = fmt.Sprintf("stopped after %d of %d pages (maxPages)", limit, len(urls))
+		urls = urls[:limit]
+	}
+	resultLimit := c.cfg.MaxTotalResults
+	if len(urls) < 2 || c.opts.CountOnly {
+		resultLimit = 0
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	for r := range c.ScrapeStreamedContext(ctx, urls, selector) {
+		if ctx.Err() != nil {
+			continue // stopped: drain the pages abandoned on the way out
+		}
 		if r.Err != nil {
-			errs = append(errs, fmt.Errorf("%s: %w", r.URL, r.Err))
+			run.Errs = append(run.Errs, fmt.Errorf("%s: %w", r.URL, r.Err))
+			if errors.Is(r.Err, context.DeadlineExceeded) {
+				run.Skipped = append(run.Skipped, r.URL)
+			}
+			run.Cached = false
 			continue
 		}
-		combined = append(combined, r.Items...)
+		run.Results = append(run.Results, r.Items...)
+		run.Cached = run.Cached && r.Cached
+		run.Timings = append(run.Timings, PageTiming{URL: r.URL, Bytes: r.Bytes, Scheme: r.Scheme, Proxy: r.Proxy, Timing: r.Timing})
+		run.Pages = append(run.Pages, r.Page)
+		run.Records = append(run.Records, r.Records...)
+		run.BytesDownloaded += r.Bytes
+		run.Count += r.Count
+		if resultLimit > 0 {
+			run.Results = c.opts.dedupResults(run.Results)
+			if done := len(run.Pages) + len(run.Errs); len(run.Results) >= resultLimit && (done < len(urls) || len(run.Results) > resultLimit) {
+				run.StoppedReason = fmt.Sprintf("stopped at %d results after %d of %d pages (maxTotalResults)", resultLimit, done, len(urls))
+				cancel()
+			}
+		}
+	}
+	run.Attempted = len(run.Pages) + len(run.Errs)
+	run.Results = c.opts.dedupResults(run.Results)
+	c.opts.rankResults(run.Results)
+	if resultLimit > 0 && len(run.Results) > resultLimit {
+		run.Results = run.Results[:resultLimit]
+	}
+	if !c.opts.CountOnly {
+		run.Count = len(run.Results)
+	}
+	if c.opts.GroupBy == GroupByDomain {
+		GroupByHost(run.Results)
+	} else if groups, ok := ParseSelectorGroups(selector); ok {
+		sortByGroup(groups, run.Results)
 	}
-	return combined, errs
+	run.Results = c.opts.window(run.Results)
+	if c.opts.ClassifyLinks {
+		c.classifyLinks(context.Background(), run.Results)
+	}
+	return run
 }
 
 // RunBulkScrape scrapes each URL independently and returns per-URL timing and status.
@@ -275,6 +1280,46 @@ func (c *Client) RunBulkScrape(urls []string, selector string) BulkScrapeRespons
 	return resp
 }
 
+// RunBatch scrapes every url+selector pair concurrently through the worker pool.
+// Results are returned in input order; a failed item records its error and
+// does not affect the others.
+func (c *Client) RunBatch(items []BatchItem) BatchResponse {
+	start := time.Now()
+	resp := BatchResponse{
+		Results: make([]BatchResult, len(items)),
+	}
+	if len(items) == 0 {
+		return resp
+	}
+
+	jobs := make([]scrapeJob, len(items))
+	for i, it := range items {
+		jobs[i] = scrapeJob{index: i, url: it.URL, selector: it.Selector}
+	}
+
+	for r := range c.runJobs(context.Background(), jobs) {
+		row := BatchResult{
+			URL:        r.url,
+			Selector:   items[r.index].Selector,
+			Results:    r.items,
+			DurationMs: r.durationMs,
+		}
+		if row.Results == nil {
+			row.Results = []ScrapeResult{} // encode as [] rather than null
+		}
+		if r.err != nil {
+			row.Error = r.err.Error()
+			row.TimedOut = errors.Is(r.err, context.DeadlineExceeded)
+		} else {
+			row.Timing = &r.meta.timing
+		}
+		resp.Results[r.index] = row
+	}
+
+	resp.TotalBatchTimeMs = int(time.Since(start).Milliseconds())
+	return resp
+}
+
 // ParseURLs splits a raw string on commas, spaces, and newlines into distinct URLs.
 func ParseURLs(raw string) []string {
 	parts := strings.FieldsFunc(raw, func(r rune) bool {
@@ -288,3 +1333,14 @@ func ParseURLs(raw string) []string {
 	}
 	return out
 }
+
+// Host returns the lower-cased host name of rawURL without its port or a
+// leading "www.", or "" when rawURL has no host. Use it as the key for
+// per-domain lookups so "https://www.Example.com:443/a" matches "example.com".
+func Host(rawURL string) string {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
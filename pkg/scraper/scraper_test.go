@@ -1,8 +1,26 @@
 package scraper
 
 import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
 )
 
 func TestParseURLs(t *testing.T) {
@@ -13,3 +31,2475 @@ func TestParseURLs(t *testing.T) {
 		t.Fatalf("ParseURLs() = %v, want %v", got, want)
 	}
 }
+
+func TestReadBodyLimit(t *testing.T) {
+	if _, err := readBody(strings.NewReader("12345"), 5); err != nil {
+		t.Fatalf("readBody at limit: unexpected error %v", err)
+	}
+	if _, err := readBody(strings.NewReader("123456"), 5); !errors.Is(err, ErrResponseTooLarge) {
+		t.Fatalf("readBody over limit: got %v, want ErrResponseTooLarge", err)
+	}
+}
+
+func TestResolveLink(t *testing.T) {
+	base, _ := url.Parse("https://example.com/blog/post?id=1")
+	tests := []struct {
+		name string
+		href string
+		want string
+	}{
+		{"absolute", "https://other.com/a", "https://other.com/a"},
+		{"root relative", "/about", "https://example.com/about"},
+		{"path relative", "next", "https://example.com/blog/next"},
+		{"parent relative", "../home", "https://example.com/home"},
+		{"protocol relative", "//cdn.example.com/x.js", "https://cdn.example.com/x.js"},
+		{"fragment only", "#section", "https://example.com/blog/post?id=1#section"},
+		{"query only", "?page=2", "https://example.com/blog/post?page=2"},
+		{"surrounding space", "  /trim  ", "https://example.com/trim"},
+		{"mailto untouched", "mailto:me@example.com", "mailto:me@example.com"},
+		{"empty", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := resolveLink(base, tt.href); got != tt.want {
+				t.Errorf("resolveLink(%q) = %q, want %q", tt.href, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHost(t *testing.T) {
+	tests := map[string]string{
+		"https://news.ycombinator.com/item?id=1": "news.ycombinator.com",
+		"https://www.Reddit.com:443/r/golang/":   "reddit.com",
+		"not a url":                              "",
+		"":                                       "",
+	}
+	for in, want := range tests {
+		if got := Host(in); got != want {
+			t.Errorf("Host(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestFetchTranscodesLatin1(t *testing.T) {
+	const latin1 = "Caf\xe9 cr\xe8me br\xfbl\xe9e" // "Café crème brûlée" in ISO-8859-1
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+	}{
+		{"content-type header", "text/html; charset=ISO-8859-1", `<a href="/d">` + latin1 + `</a>`},
+		{"meta charset", "text/html", `<html><head><meta charset="iso-8859-1"></head><body><a href="/d">` + latin1 + `</a></body></html>`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.Header().Set("Content-Type", tt.contentType)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+
+			got, _, err := NewClient(DefaultConfig()).fetch(context.Background(), srv.URL, "a")
+			if err != nil {
+				t.Fatalf("fetch: %v", err)
+			}
+			if len(got) != 1 || got[0].Title != "Café crème brûlée" {
+				t.Fatalf("fetch() = %+v, want one result titled %q", got, "Café crème brûlée")
+			}
+		})
+	}
+}
+
+func TestDiff(t *testing.T) {
+	prev := []ScrapeResult{{Title: "a", Link: "/a"}, {Title: "b", Link: "/b"}}
+	curr := []ScrapeResult{{Title: "b", Link: "/b"}, {Title: "c", Link: "/c"}}
+	got := Diff(prev, curr)
+	want := ResultDiff{
+		Added:   []ScrapeResult{{Title: "c", Link: "/c"}},
+		Removed: []ScrapeResult{{Title: "a", Link: "/a"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Diff() = %+v, want %+v", got, want)
+	}
+}
+
+// BenchmarkScrapeWithWorkerPool shows that goroutine count stays bounded by
+// WorkerCount as the number of URLs grows: compare the peak-goroutines metric
+// across sub-benchmarks.
+func BenchmarkScrapeWithWorkerPool(b *testing.B) {
+	var peak atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if n := int64(runtime.NumGoroutine()); n > peak.Load() {
+			peak.Store(n)
+		}
+		_, _ = w.Write([]byte(`<a href="/x">item</a>`))
+	}))
+	defer srv.Close()
+
+	cfg := DefaultConfig()
+	cfg.WorkerCount = 4
+	cfg.RateLimit = 1e6 // effectively unlimited
+	cfg.CrawlDelay = 0  // every URL is on the same test host
+	cli := NewClient(cfg)
+
+	for _, n := range []int{10, 100, 1000} {
+		urls := make([]string, n)
+		for i := range urls {
+			urls[i] = fmt.Sprintf("%s/?page=%d", srv.URL, i)
+		}
+		b.Run(fmt.Sprintf("urls=%d", n), func(b *testing.B) {
+			peak.Store(0)
+			b.ReportAllocs()
+			for range b.N {
+				if _, errs := cli.ScrapeWithWorkerPool(urls, "a"); len(errs) > 0 {
+					b.Fatal(errs[0])
+				}
+			}
+			b.ReportMetric(float64(peak.Load()), "peak-goroutines")
+		})
+	}
+}
+
+func TestParseTable(t *testing.T) {
+	const page = `<table>
+		<tr><th>Name</th><th>Age</th></tr>
+		<tr><td>Ann</td><td>3</td></tr>
+		<tr><td> Bob   B </td><td><table><tr><td>nested</td></tr></table>4</td></tr>
+	</table>`
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(page))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, ok := parseTable(doc.Find("table").First(), false)
+	if !ok {
+		t.Fatal("parseTable: no table found")
+	}
+	want := Table{
+		Header: []string{"Name", "Age"},
+		Rows:   [][]string{{"Ann", "3"}, {"Bob B", "nested4"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("parseTable() = %+v, want %+v", got, want)
+	}
+}
+
+func TestHostPolicy(t *testing.T) {
+	p := HostPolicy{Allow: []string{"example.com"}, Deny: []string{"bad.example.com"}}
+	tests := map[string]bool{
+		"example.com":         true,
+		"news.example.com":    true,
+		"bad.example.com":     false,
+		"x.bad.example.com":   false,
+		"notexample.com":      false,
+		"example.com.evil.io": false,
+	}
+	for host, ok := range tests {
+		if err := p.checkHost(host); (err == nil) != ok {
+			t.Errorf("checkHost(%q) = %v, want allowed=%v", host, err, ok)
+		}
+	}
+}
+
+func TestBlockPrivateAtDial(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`<a href="/">x</a>`))
+	}))
+	defer srv.Close()
+
+	cfg := DefaultConfig()
+	cfg.HostPolicy.BlockPrivate = true
+	cli := NewClient(cfg)
+
+	if err := cli.CheckURL(context.Background(), srv.URL); !errors.Is(err, ErrHostNotAllowed) {
+		t.Fatalf("CheckURL(%s) = %v, want ErrHostNotAllowed", srv.URL, err)
+	}
+	// Bypassing CheckURL must still fail at connect time, without retries.
+	start := time.Now()
+	if _, _, err := cli.fetch(context.Background(), srv.URL, "a"); !errors.Is(err, ErrHostNotAllowed) {
+		t.Fatalf("fetch(%s) = %v, want ErrHostNotAllowed", srv.URL, err)
+	}
+	if d := time.Since(start); d > cfg.BaseRetryDelay {
+		t.Fatalf("fetch took %v; policy rejections should not be retried", d)
+	}
+}
+
+func TestTruncateTitle(t *testing.T) {
+	tests := []struct {
+		title string
+		max   int
+		want  string
+		cut   bool
+	}{
+		{"short", 0, "short", false},
+		{"short", 5, "short", false},
+		{"a longer title", 7, "a long…", true},
+		{"héllo wörld", 6, "héllo…", true},
+		{"ab", 1, "…", true},
+	}
+	for _, tt := range tests {
+		got, cut := truncateTitle(tt.title, tt.max)
+		if got != tt.want || cut != tt.cut {
+			t.Errorf("truncateTitle(%q, %d) = %q, %v; want %q, %v", tt.title, tt.max, got, cut, tt.want, tt.cut)
+		}
+	}
+}
+
+func TestConditionalGet(t *testing.T) {
+	var full atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		full.Add(1)
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write([]byte(`<a href="/x">Item</a>`))
+	}))
+	defer srv.Close()
+
+	cli := NewClient(DefaultConfig())
+	for i, wantCached := range []bool{false, true} {
+		got, meta, err := cli.fetch(context.Background(), srv.URL, "a")
+		if err != nil {
+			t.Fatalf("fetch #%d: %v", i, err)
+		}
+		if cached := meta.cached; cached != wantCached || len(got) != 1 || got[0].Title != "Item" {
+			t.Fatalf("fetch #%d = %+v, cached %v; want one result, cached %v", i, got, meta.cached, wantCached)
+		}
+	}
+	if n := full.Load(); n != 1 {
+		t.Fatalf("server sent the full body %d times, want 1", n)
+	}
+
+	// A scrape with its own headers, which may be credentials, neither
+	// revalidates the shared cache nor fills it.
+	fresh := NewClient(DefaultConfig())
+	withAuth := fresh.WithOptions(Options{Headers: http.Header{"Authorization": {"Bearer t"}}})
+	for i := range 2 {
+		if _, meta, err := withAuth.fetch(context.Background(), srv.URL, "a"); err != nil || meta.cached {
+			t.Fatalf("fetch with headers #%d: cached %v, %v; want a full fetch", i, meta.cached, err)
+		}
+	}
+	if _, meta, err := fresh.fetch(context.Background(), srv.URL, "a"); err != nil || meta.cached {
+		t.Fatalf("fetch after the headers' scrapes: cached %v, %v; want a full fetch", meta.cached, err)
+	}
+	if n := full.Load(); n != 4 {
+		t.Fatalf("server sent the full body %d times, want 4", n)
+	}
+}
+
+func TestHostSpacer(t *testing.T) {
+	hs := newHostSpacer(50 * time.Millisecond)
+	start := time.Now()
+	hs.wait("http://a.example/1")
+	hs.wait("http://b.example/1") // other host: no wait
+	if d := time.Since(start); d > 25*time.Millisecond {
+		t.Fatalf("first requests to two hosts took %v, want no delay", d)
+	}
+	hs.wait("http://www.a.example/2") // same host after dropping www.
+	hs.wait("http://a.example/3")
+	if d := time.Since(start); d < 100*time.Millisecond {
+		t.Fatalf("three requests to one host took %v, want >= 100ms", d)
+	}
+	newHostSpacer(0).wait("http://a.example/") // nil spacer must not panic
+}
+
+func TestWriteJSONL(t *testing.T) {
+	pages := make(chan JobResult, 2)
+	pages <- JobResult{URL: "https://a.example", Items: []ScrapeResult{{Title: "one", Link: "/1"}, {Title: "two", Link: "/2"}}}
+	pages <- JobResult{URL: "https://b.example", Err: errors.New("HTTP 500")}
+	close(pages)
+
+	var buf strings.Builder
+	if err := WriteJSONL(&buf, pages, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	want := `{"title":"one","link":"/1"}
+{"title":"two","link":"/2"}
+{"url":"https://b.example","error":"HTTP 500"}
+`
+	if buf.String() != want {
+		t.Fatalf("WriteJSONL wrote\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestRenderRequestURL(t *testing.T) {
+	cli := NewClient(Config{RenderURL: "http://splash:8050/render.html?wait=1"})
+	got, err := cli.renderRequestURL("https://example.com/a?b=c")
+	if want := "http://splash:8050/render.html?url=https%3A%2F%2Fexample.com%2Fa%3Fb%3Dc&wait=1"; err != nil || got != want {
+		t.Fatalf("renderRequestURL = %q, %v; want %q", got, err, want)
+	}
+	if _, err := NewClient(Config{}).renderRequestURL("https://example.com"); !errors.Is(err, ErrNoRenderService) {
+		t.Fatalf("without RenderURL err = %v, want ErrNoRenderService", err)
+	}
+}
+
+func TestWriteMarkdown(t *testing.T) {
+	results := []ScrapeResult{
+		{Title: "Go [1.23] released", Link: "https://go.dev/doc/go1.23"},
+		{Title: "Wiki", Link: "https://en.wikipedia.org/wiki/Go_(language)"},
+		{Title: "No link"},
+	}
+	var buf strings.Builder
+	if err := WriteMarkdown(&buf, results, []error{errors.New("https://x.example: HTTP 404")}); err != nil {
+		t.Fatal(err)
+	}
+	want := `- [Go \[1.23\] released](https://go.dev/doc/go1.23)
+- [Wiki](https://en.wikipedia.org/wiki/Go_%28language%29)
+- No link
+<!-- error: https://x.example: HTTP 404 -->
+`
+	if buf.String() != want {
+		t.Fatalf("WriteMarkdown wrote\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	results := []ScrapeResult{
+		{Title: "Go, at last", Link: "https://go.dev/", Attrs: map[string]string{"title": "Go home", "data-id": "7"}},
+		{Title: "No attrs", Link: "/x"},
+		{Title: "Field", Link: "/y", Fields: map[string]string{"price": "$3"}},
+	}
+	opts := Options{Attrs: []string{"title", "data-id", "title"}, Fields: []FieldSpec{{Name: "price"}}}
+	var buf strings.Builder
+	if err := WriteCSV(&buf, results, opts); err != nil {
+		t.Fatal(err)
+	}
+	want := `text,link,title,data-id,price
+"Go, at last",https://go.dev/,Go home,7,
+No attrs,/x,,,
+Field,/y,,,$3
+`
+	if buf.String() != want {
+		t.Fatalf("WriteCSV wrote\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestScraperScrape(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintf(w, `<a href="/a">%s</a>`, r.UserAgent())
+	}))
+	defer srv.Close()
+
+	s := &Scraper{UserAgent: "test-bot/1.0"}
+	got, err := s.Scrape(context.Background(), srv.URL, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ScrapeResult{{Title: "test-bot/1.0", Link: srv.URL + "/a"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Scrape = %+v, want %+v", got, want)
+	}
+}
+
+func TestOptionsWindow(t *testing.T) {
+	results := []ScrapeResult{{Title: "a"}, {Title: "b"}, {Title: "c"}}
+	tests := []struct {
+		offset, count int
+		want          string
+	}{
+		{0, 0, "abc"},
+		{1, 0, "bc"},
+		{1, 1, "b"},
+		{-5, 2, "ab"},
+		{2, 10, "c"},
+		{9, 1, ""},
+	}
+	for _, tt := range tests {
+		var got string
+		for _, r := range (Options{Offset: tt.offset, Count: tt.count}).window(results) {
+			got += r.Title
+		}
+		if got != tt.want {
+			t.Errorf("window(offset=%d, count=%d) = %q, want %q", tt.offset, tt.count, got, tt.want)
+		}
+	}
+}
+
+const fixtureHTML = `<!DOCTYPE html>
+<html><body>
+  <ul class="stories">
+    <li><a class="story" href="/item/1">Relative story</a></li>
+    <li><a class="story" href="https://other.example/2">Absolute story</a></li>
+    <li><a class="story" href="//cdn.example/3">Protocol-relative story</a></li>
+    <li><a class="story" href="#comments">Fragment story</a></li>
+    <li><a class="story" href="mailto:tips@example.com">Mail story</a></li>
+    <li><a class="story">No href story</a></li>
+    <li><a class="story" href="/empty">   </a></li>
+  </ul>
+  <a href="/footer">Footer</a>
+</body></html>`
+
+func TestFetchFixture(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/news/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte(fixtureHTML))
+	}))
+	defer srv.Close()
+
+	got, _, err := NewClient(DefaultConfig()).fetch(context.Background(), srv.URL+"/news/", "ul.stories a.story")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ScrapeResult{
+		{Title: "Relative story", Link: srv.URL + "/item/1"},
+		{Title: "Absolute story", Link: "https://other.example/2"},
+		{Title: "Protocol-relative story", Link: "http://cdn.example/3"},
+		{Title: "Fragment story", Link: srv.URL + "/news/#comments"},
+		{Title: "No href story", Link: ""}, // mailto: is not in DefaultLinkSchemes
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("fetch() =\n%+v\nwant\n%+v", got, want)
+	}
+
+	raw, _, err := NewClient(DefaultConfig()).WithOptions(Options{RawLinks: true}).fetch(context.Background(), srv.URL+"/news/", "ul.stories a.story")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if raw[0].Link != "/item/1" || raw[2].Link != "//cdn.example/3" {
+		t.Fatalf("rawLinks fetch() = %+v, want hrefs as written", raw)
+	}
+}
+
+func TestFetchErrors(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close() // nothing listens on this address any more
+
+	cfg := DefaultConfig()
+	cfg.BaseRetryDelay = time.Millisecond
+	cli := NewClient(cfg)
+
+	tests := []struct {
+		name, url, want string
+	}{
+		{"404", srv.URL + "/missing", "HTTP 404 Not Found"},
+		{"connection refused", closed.URL, "connection refused"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _, err := cli.fetch(context.Background(), tt.url, "a")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("fetch(%s) = %v, %v; want error containing %q", tt.url, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrecheck(t *testing.T) {
+	var gets sync.Map // path -> *atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			switch r.URL.Path {
+			case "/file.pdf":
+				w.Header().Set("Content-Type", "application/pdf")
+			case "/huge":
+				w.Header().Set("Content-Type", "text/html")
+				w.Header().Set("Content-Length", "1048576")
+			case "/nohead":
+				w.WriteHeader(http.StatusMethodNotAllowed)
+			}
+			return
+		}
+		n, _ := gets.LoadOrStore(r.URL.Path, new(atomic.Int32))
+		n.(*atomic.Int32).Add(1)
+		_, _ = w.Write([]byte(`<a href="/x">page</a>`))
+	}))
+	defer srv.Close()
+
+	cfg := DefaultConfig()
+	cfg.MaxBodyBytes = 1024
+	cli := NewClient(cfg).WithOptions(Options{Precheck: true})
+
+	tests := []struct {
+		path string
+		want error // nil: the GET goes ahead
+	}{
+		{"/file.pdf", ErrNotHTML},
+		{"/huge", ErrResponseTooLarge},
+		{"/nohead", nil},
+		{"/page", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, _, err := cli.fetch(context.Background(), srv.URL+tt.path, "a")
+			var n int32
+			if v, ok := gets.Load(tt.path); ok {
+				n = v.(*atomic.Int32).Load()
+			}
+			if tt.want != nil {
+				if !errors.Is(err, tt.want) || n != 0 {
+					t.Errorf("fetch = %v after %d GETs, want %v and no GET", err, n, tt.want)
+				}
+				return
+			}
+			if err != nil || len(got) != 1 || n != 1 {
+				t.Errorf("fetch = %v, %v after %d GETs, want the page from one GET", got, err, n)
+			}
+		})
+	}
+}
+
+func TestPageTimeoutSkipsSlowPage(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(2 * time.Second):
+			}
+			return
+		}
+		_, _ = w.Write([]byte(`<a href="/x">fast</a>`))
+	}))
+	defer srv.Close()
+
+	cfg := DefaultConfig()
+	cfg.PageTimeout = 100 * time.Millisecond
+	cfg.CrawlDelay = 0
+	start := time.Now()
+	run := NewClient(cfg).ScrapeAll([]string{srv.URL + "/slow", srv.URL + "/fast"}, "a")
+	if d := time.Since(start); d > time.Second {
+		t.Fatalf("ScrapeAll took %v, want the slow page cut off at PageTimeout", d)
+	}
+	if len(run.Results) != 1 || run.Results[0].Title != "fast" {
+		t.Fatalf("Results = %+v, want the fast page only", run.Results)
+	}
+	if want := []string{srv.URL + "/slow"}; !reflect.DeepEqual(run.Skipped, want) {
+		t.Fatalf("Skipped = %v, want %v", run.Skipped, want)
+	}
+}
+
+func TestFetchExtractHTML(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`<div class="c"><b>Bold</b> text</div><div class="c"><img src="/i.png"></div>`))
+	}))
+	defer srv.Close()
+
+	cli := NewClient(DefaultConfig()).WithOptions(Options{Extract: ExtractHTML})
+	got, _, err := cli.fetch(context.Background(), srv.URL, "div.c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ScrapeResult{
+		{Title: "Bold text", HTML: "<b>Bold</b> text"},
+		{Title: "", HTML: `<img src="/i.png"/>`}, // kept: no text, but markup
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("fetch() = %+v, want %+v", got, want)
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	custom := []int{429, 503}
+	tests := []struct {
+		status   int
+		statuses []int
+		want     bool
+	}{
+		{http.StatusBadGateway, nil, true},
+		{http.StatusServiceUnavailable, nil, true},
+		{http.StatusGatewayTimeout, nil, true},
+		{http.StatusTooManyRequests, nil, false},
+		{http.StatusInternalServerError, nil, false},
+		{http.StatusNotFound, nil, false},
+		{http.StatusTooManyRequests, custom, true},
+		{http.StatusBadGateway, custom, false},
+		{http.StatusServiceUnavailable, custom, true},
+	}
+	for _, tt := range tests {
+		if got := isRetryable(nil, tt.status, tt.statuses); got != tt.want {
+			t.Errorf("isRetryable(%d, %v) = %v, want %v", tt.status, tt.statuses, got, tt.want)
+		}
+	}
+	if !isRetryable(errors.New("connection reset"), 0, custom) {
+		t.Error("network errors must stay retryable with a custom status list")
+	}
+}
+
+func TestExtractFields(t *testing.T) {
+	specs, err := ParseFields("title=.name; link=a@href ;price=.cost;id=@data-id;missing=.nope")
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(
+		`<div class="card" data-id="7"><a href="/p/7"><span class="name">Lamp</span></a><span class="cost"> $12 </span></div>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := extractFields(doc.Find(".card"), specs)
+	want := map[string]string{"title": "Lamp", "link": "/p/7", "price": "$12", "id": "7"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("extractFields = %v, want %v", got, want)
+	}
+
+	for _, bad := range []string{"=.x", "noequals", "a=.x@"} {
+		if _, err := ParseFields(bad); err == nil {
+			t.Errorf("ParseFields(%q) succeeded, want an error", bad)
+		}
+	}
+}
+
+func TestLanguage(t *testing.T) {
+	for lang, want := range map[string]bool{
+		"en": true, "fr-CH": true, "zh-Hant-TW": true, "de, en;q=0.5": true, "*": true,
+		"en_US": false, "fr-": false, "<script>": false, "en;q=2": false,
+	} {
+		if got := validLanguage(lang); got != want {
+			t.Errorf("validLanguage(%q) = %v, want %v", lang, got, want)
+		}
+	}
+	for locale, want := range map[string]string{"fr_CH.UTF-8": "fr-CH", "de_DE@euro": "de-DE", "C": "", "": ""} {
+		if got := localeLanguage(locale); got != want {
+			t.Errorf("localeLanguage(%q) = %q, want %q", locale, got, want)
+		}
+	}
+}
+
+func TestFetchDetectsAntiBot(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		header  string
+		body    string
+		blocked bool
+	}{
+		{"cloudflare challenge title", http.StatusOK, "", `<html><head><title>Just a moment...</title></head></html>`, true},
+		{"403 challenge body", http.StatusForbidden, "", `<div id="cf-browser-verification">`, true},
+		{"cf-mitigated header", http.StatusForbidden, "challenge", ``, true},
+		{"plain 403", http.StatusForbidden, "", `Forbidden`, false},
+		{"normal page with cf script", http.StatusOK, "", `<script src="/cdn-cgi/challenge-platform/x.js"></script><a href="/a">ok</a>`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var hits atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				hits.Add(1)
+				if tt.header != "" {
+					w.Header().Set("Cf-Mitigated", tt.header)
+				}
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+
+			_, _, err := NewClient(DefaultConfig()).fetch(context.Background(), srv.URL, "a")
+			if got := errors.Is(err, ErrBlocked); got != tt.blocked {
+				t.Fatalf("fetch err = %v, blocked %v; want blocked %v", err, got, tt.blocked)
+			}
+			if tt.blocked && hits.Load() != 1 {
+				t.Fatalf("server hit %d times, want no retries on a challenge", hits.Load())
+			}
+		})
+	}
+}
+
+func TestScrapeAllTimings(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(30 * time.Millisecond)
+		_, _ = w.Write([]byte(`<a href="/x">Item</a>`))
+	}))
+	defer srv.Close()
+
+	run := NewClient(DefaultConfig()).ScrapeAll([]string{srv.URL}, "a")
+	if len(run.Errs) != 0 || len(run.Timings) != 1 {
+		t.Fatalf("ScrapeAll = %+v, want one timing and no errors", run)
+	}
+	tm := run.Timings[0]
+	if tm.URL != srv.URL {
+		t.Fatalf("timing URL = %q, want %q", tm.URL, srv.URL)
+	}
+	if tm.TTFBMs < 30 || tm.TotalMs < tm.TTFBMs {
+		t.Fatalf("timing = %+v, want TTFB >= 30ms and Total >= TTFB", tm.Timing)
+	}
+}
+
+func TestLinkFallback(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(`
+		<a href="/a">plain</a>
+		<a href="#" data-href="/b">data-href</a>
+		<a href="#" data-url="/c">data-url</a>
+		<a data-href=" " data-url="/d">blank skipped</a>
+		<a href="#">placeholder only</a>
+		<a>none</a>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	doc.Find("a").Each(func(_ int, s *goquery.Selection) {
+		got = append(got, linkHref(s, DefaultLinkAttrs))
+	})
+	want := []string{"/a", "/b", "/c", "/d", "#", ""}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("linkHref = %q, want %q", got, want)
+	}
+
+	if got := linkHref(doc.Find("a").Eq(1), []string{"data-href", "href"}); got != "/b" {
+		t.Fatalf("custom chain = %q, want /b", got)
+	}
+	if got := linkHref(doc.Find("a").Eq(1), []string{"href"}); got != "#" {
+		t.Fatalf("href-only chain = %q, want #", got)
+	}
+}
+
+func TestFilterRank(t *testing.T) {
+	results := []ScrapeResult{
+		{Title: "Tips and more tips for Go"},
+		{Title: "Rust release notes"},
+		{Title: "Go tips"},
+		{Title: "Go go GO"},
+	}
+
+	opts := Options{Filter: "go"}
+	got := opts.filterResults(append([]ScrapeResult(nil), results...))
+	if len(got) != 3 || got[0].Score != 0 {
+		t.Fatalf("filter without rank = %+v, want 3 unscored results", got)
+	}
+
+	opts.Rank = true
+	got = opts.filterResults(append([]ScrapeResult(nil), results...))
+	opts.rankResults(got)
+	var titles []string
+	for _, r := range got {
+		titles = append(titles, r.Title)
+	}
+	want := []string{"Go go GO", "Go tips", "Tips and more tips for Go"}
+	if !reflect.DeepEqual(titles, want) {
+		t.Fatalf("ranked = %q, want %q", titles, want)
+	}
+	if got[0].Score <= got[1].Score || got[1].Score <= got[2].Score {
+		t.Fatalf("scores not descending: %+v", got)
+	}
+
+	if got := (Options{Filter: "go tips"}).filterResults(append([]ScrapeResult(nil), results...)); len(got) != 2 {
+		t.Fatalf("two-term filter kept %d results, want 2 (every term must match)", len(got))
+	}
+}
+
+func TestInsecureTLS(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`<a href="/x">Item</a>`))
+	}))
+	defer srv.Close()
+	ctx := context.Background()
+
+	strict := NewClient(DefaultConfig())
+	if _, _, err := strict.fetch(ctx, srv.URL, "a"); err == nil {
+		t.Fatal("self-signed certificate accepted by default")
+	}
+	if err := strict.CheckOptions(Options{Insecure: true}); !errors.Is(err, ErrInsecureNotAllowed) {
+		t.Fatalf("CheckOptions = %v, want ErrInsecureNotAllowed", err)
+	}
+	if _, _, err := strict.WithOptions(Options{Insecure: true}).fetch(ctx, srv.URL, "a"); !errors.Is(err, ErrInsecureNotAllowed) {
+		t.Fatalf("fetch with Insecure = %v, want ErrInsecureNotAllowed", err)
+	}
+
+	cfg := DefaultConfig()
+	cfg.AllowInsecure = true
+	allowed := NewClient(cfg)
+	if _, _, err := allowed.fetch(ctx, srv.URL, "a"); err == nil {
+		t.Fatal("AllowInsecure alone skipped verification; it must stay per request")
+	}
+	got, _, err := allowed.WithOptions(Options{Insecure: true}).fetch(ctx, srv.URL, "a")
+	if err != nil || len(got) != 1 {
+		t.Fatalf("fetch with Insecure = %+v, %v; want one result", got, err)
+	}
+}
+
+func TestUpgradeInsecure(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`<a href="/x">Item</a>`))
+	})
+	tlsSrv := httptest.NewTLSServer(handler)
+	defer tlsSrv.Close()
+	plain := httptest.NewServer(handler)
+	defer plain.Close()
+	ctx := context.Background()
+
+	cfg := DefaultConfig()
+	cfg.AllowInsecure = true // trust the test certificate
+	cli := NewClient(cfg).WithOptions(Options{UpgradeInsecure: true, Insecure: true})
+
+	httpURL := "http://" + strings.TrimPrefix(tlsSrv.URL, "https://")
+	page, err := cli.get(ctx, httpURL)
+	if err != nil || page.Scheme != "https" || page.URL != httpURL {
+		t.Fatalf("TLS-only site: scheme %q url %q err %v; want https and the URL as given", page.Scheme, page.URL, err)
+	}
+
+	page, err = cli.get(ctx, plain.URL)
+	if err != nil || page.Scheme != "http" {
+		t.Fatalf("http-only site: scheme %q err %v; want fallback to http", page.Scheme, err)
+	}
+}
+
+func TestScrapeMetaTags(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`<html><head><title>Plain title</title>
+			<meta property="og:title" content="OG Title">
+			<meta property="og:image" content="/img/card.png">
+			<meta property="og:image" content="/img/second.png">
+			<meta name="og:site_name" content="Example">
+			<meta name="twitter:card" content="summary">
+			<meta name="twitter:description" content="Twitter desc">
+			<meta name="description" content="Plain desc">
+			</head></html>`))
+	}))
+	defer srv.Close()
+
+	got, err := NewClient(DefaultConfig()).ScrapeMetaTags(context.Background(), srv.URL+"/post")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := MetaTags{
+		URL:         srv.URL + "/post",
+		Title:       "OG Title",
+		Description: "Twitter desc",
+		Image:       srv.URL + "/img/card.png",
+		OpenGraph:   map[string]string{"title": "OG Title", "image": "/img/card.png", "site_name": "Example"},
+		Twitter:     map[string]string{"card": "summary", "description": "Twitter desc"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("ScrapeMetaTags =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestFetchResolvesAgainstFinalURL(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/old", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/blog/2026/post", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/blog/2026/post", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`<a href="next">Next</a><a href="../archive">Archive</a>`))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	got, _, err := NewClient(DefaultConfig()).fetch(context.Background(), srv.URL+"/old", "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ScrapeResult{
+		{Title: "Next", Link: srv.URL + "/blog/2026/next"},
+		{Title: "Archive", Link: srv.URL + "/blog/archive"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("fetch() = %+v, want %+v", got, want)
+	}
+}
+
+// largeFixture is a page with n matching links, a few of them empty.
+func largeFixture(n int) string {
+	var b strings.Builder
+	for i := range n {
+		if i%97 == 0 {
+			b.WriteString(`<a class="item" href="/empty"></a>`)
+			continue
+		}
+		fmt.Fprintf(&b, `<a class="item" href="/item/%d" data-id="%d"><b>Item</b> %d</a>`, i, i, i)
+	}
+	return b.String()
+}
+
+func TestExtractAllParallelKeepsOrder(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(largeFixture(5000)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	base, _ := url.Parse("https://example.com/")
+	opts := Options{Attrs: []string{"data-id"}}
+
+	serial, _ := NewClient(DefaultConfig()).WithOptions(opts).extractAll(doc.Find("a.item"), base)
+	cfg := DefaultConfig()
+	cfg.ParseWorkers = 8
+	parallel, _ := NewClient(cfg).WithOptions(opts).extractAll(doc.Find("a.item"), base)
+
+	if len(serial) == 0 || !reflect.DeepEqual(serial, parallel) {
+		t.Fatalf("parallel extraction differs from serial: %d vs %d results", len(parallel), len(serial))
+	}
+}
+
+func BenchmarkExtractAll(b *testing.B) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(largeFixture(10000)))
+	if err != nil {
+		b.Fatal(err)
+	}
+	base, _ := url.Parse("https://example.com/")
+	opts := Options{Attrs: []string{"data-id"}, Fields: []FieldSpec{{Name: "label", Selector: "b"}}}
+
+	for _, workers := range []int{1, 4, 8} {
+		cfg := DefaultConfig()
+		cfg.ParseWorkers = workers
+		cli := NewClient(cfg).WithOptions(opts)
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			b.ReportAllocs()
+			for range b.N {
+				cli.extractAll(doc.Find("a.item"), base)
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	var gets atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	})
+	mux.HandleFunc("/nohead", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		gets.Add(1)
+		w.Header().Set("Content-Type", "application/pdf")
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	cli := NewClient(DefaultConfig())
+	ctx := context.Background()
+
+	if got := cli.Check(ctx, srv.URL+"/page"); !got.Reachable || got.Status != 200 || !got.HTML {
+		t.Fatalf("Check(/page) = %+v, want reachable HTML", got)
+	}
+	if got := cli.Check(ctx, srv.URL+"/nohead"); !got.Reachable || got.HTML || got.ContentType != "application/pdf" || gets.Load() != 1 {
+		t.Fatalf("Check(/nohead) = %+v after %d GETs, want a GET fallback reporting application/pdf", got, gets.Load())
+	}
+	if got := cli.Check(ctx, srv.URL+"/missing"); got.Reachable || got.Status != 404 {
+		t.Fatalf("Check(/missing) = %+v, want unreachable 404", got)
+	}
+}
+
+func TestParseSelectorGroups(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []SelectorGroup
+	}{
+		{"headline:h2 a, byline:.author", []SelectorGroup{{"headline", "h2 a"}, {"byline", ".author"}}},
+		{"links:a:not([href^='#']), img:img[alt='a, b']", []SelectorGroup{{"links", "a:not([href^='#'])"}, {"img", "img[alt='a, b']"}}},
+		{"h2 a", nil},
+		{"a:hover, h2", nil},
+		{"li:nth-child(2)", nil},
+		{"p::first-line", nil},
+		{"headline:h2, .author", nil}, // every part needs a label
+		{"name:", nil},
+	}
+	for _, tt := range tests {
+		got, ok := ParseSelectorGroups(tt.in)
+		if ok != (tt.want != nil) || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseSelectorGroups(%q) = %v, %v; want %v", tt.in, got, ok, tt.want)
+		}
+	}
+}
+
+func TestScrapeAllGroups(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintf(w, `<h2><a href="/1">%[1]s one</a></h2><span class="author">Ann</span>
+			<h2><a href="/2">%[1]s two</a></h2><span class="author">Bob</span>`, r.URL.Path)
+	}))
+	defer srv.Close()
+
+	cfg := DefaultConfig()
+	cfg.CrawlDelay = 0
+	cfg.RateLimit = 1e6
+	urls := []string{srv.URL + "/a", srv.URL + "/b"}
+	selector := "headline:h2 a, byline:.author"
+	run := NewClient(cfg).ScrapeAll(urls, selector)
+	if len(run.Errs) != 0 || len(run.Results) != 8 {
+		t.Fatalf("ScrapeAll = %+v, want 8 results", run)
+	}
+	for i, r := range run.Results {
+		if want := map[bool]string{true: "headline", false: "byline"}[i < 4]; r.Group != want {
+			t.Fatalf("result %d = %+v, want group %q (results ordered by group)", i, r, want)
+		}
+	}
+
+	out := NewScrapeOutput(urls, selector, 1, run.Results, nil)
+	if len(out.Groups["headline"]) != 4 || len(out.Groups["byline"]) != 4 || out.Groups["byline"][0].Title != "Ann" {
+		t.Fatalf("Groups = %+v, want 4 headlines and 4 bylines", out.Groups)
+	}
+}
+
+func TestBytesDownloaded(t *testing.T) {
+	body := `<a href="/x">Item</a>` + strings.Repeat(" ", 1000)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write([]byte(body))
+	}))
+	defer srv.Close()
+
+	cfg := DefaultConfig()
+	cfg.CrawlDelay = 0
+	cfg.RateLimit = 1e6
+	cli := NewClient(cfg)
+	urls := []string{srv.URL + "/a", srv.URL + "/b"}
+	if run := cli.ScrapeAll(urls, "a"); run.BytesDownloaded != int64(2*len(body)) || run.Timings[0].Bytes != int64(len(body)) {
+		t.Fatalf("BytesDownloaded = %d (page %d), want %d", run.BytesDownloaded, run.Timings[0].Bytes, 2*len(body))
+	}
+	if run := cli.ScrapeAll(urls, "a"); !run.Cached || run.BytesDownloaded != 0 {
+		t.Fatalf("revalidated run downloaded %d bytes (cached %v), want 0", run.BytesDownloaded, run.Cached)
+	}
+}
+
+func TestGroupByHost(t *testing.T) {
+	results := []ScrapeResult{
+		{Title: "a", Link: "https://www.Example.com/1"},
+		{Title: "b", Link: "https://other.org/x"},
+		{Title: "c", Link: ""},
+		{Title: "d", Link: "https://example.com/2"},
+		{Title: "e", Link: "/relative"},
+	}
+	GroupByHost(results)
+	var got []string
+	for _, r := range results {
+		got = append(got, r.Title+"@"+r.Group)
+	}
+	want := []string{"a@example.com", "d@example.com", "b@other.org", "c@internal", "e@internal"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("GroupByHost = %q, want %q", got, want)
+	}
+
+	out := NewScrapeOutput(nil, "h2 a", 1, results, nil)
+	if len(out.Groups) != 3 || len(out.Groups["example.com"]) != 2 || len(out.Groups[InternalGroup]) != 2 {
+		t.Fatalf("Groups = %+v, want example.com, other.org and internal", out.Groups)
+	}
+}
+
+func TestDedupModes(t *testing.T) {
+	results := []ScrapeResult{
+		{Title: "Hello world", Link: "https://x.test/a"},
+		{Title: "Hello  world", Link: "https://x.test/a"},
+		{Title: "hello world", Link: "https://x.test/a"},
+		{Title: "Hello world", Link: "https://x.test/a"},
+		{Title: "Hello world", Link: "https://x.test/b"},
+	}
+	for _, tt := range []struct {
+		opts Options
+		want int
+	}{
+		{Options{}, 5},
+		{Options{Dedup: true}, 4},
+		{Options{Dedup: true, DedupMode: DedupExact}, 4},
+		{Options{Dedup: true, DedupMode: DedupTrim}, 3},
+		{Options{Dedup: true, DedupMode: DedupLower}, 2},
+	} {
+		got := tt.opts.dedupResults(append([]ScrapeResult(nil), results...))
+		if len(got) != tt.want || got[0].Title != results[0].Title {
+			t.Errorf("dedup %+v kept %d results, want %d (first kept)", tt.opts, len(got), tt.want)
+		}
+	}
+
+	opts, err := ParseOptions(url.Values{"dedupMode": {"LOWER"}})
+	if err != nil || !opts.Dedup || opts.DedupMode != DedupLower {
+		t.Fatalf("ParseOptions(dedupMode=LOWER) = %+v, %v; want dedup on in lower mode", opts, err)
+	}
+	if _, err := ParseOptions(url.Values{"dedupMode": {"fuzzy"}}); err == nil {
+		t.Fatal("ParseOptions accepted dedupMode=fuzzy")
+	}
+}
+
+func TestCountOnly(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`<a href="/1">Go tips</a><a href="/2">Rust news</a><a href="/3">More Go</a><a href="/4"> </a>`))
+	}))
+	defer srv.Close()
+	cfg := DefaultConfig()
+	cfg.CrawlDelay = 0
+	cfg.RateLimit = 1e6
+	cli := NewClient(cfg)
+	urls := []string{srv.URL + "/a", srv.URL + "/b"}
+
+	for _, tt := range []struct {
+		opts Options
+		want int
+	}{
+		{Options{CountOnly: true}, 6},
+		{Options{CountOnly: true, Filter: "go"}, 4},
+		{Options{}, 6},
+	} {
+		run := cli.WithOptions(tt.opts).ScrapeAll(urls, "a")
+		if run.Count != tt.want {
+			t.Errorf("%+v: Count = %d, want %d", tt.opts, run.Count, tt.want)
+		}
+		if tt.opts.CountOnly && run.Results != nil {
+			t.Errorf("%+v: built %d results, want none", tt.opts, len(run.Results))
+		}
+	}
+
+	if _, err := ParseOptions(url.Values{"countOnly": {"true"}, "dedup": {"true"}}); err == nil {
+		t.Fatal("ParseOptions accepted countOnly with dedup")
+	}
+}
+
+func TestExplainError(t *testing.T) {
+	for _, tt := range []struct {
+		err  error
+		want string
+	}{
+		{fmt.Errorf("https://x.test: %w", &StatusError{StatusCode: 404, Status: "404 Not Found"}), "Page not found"},
+		{&retryableError{attempts: 3, err: &StatusError{StatusCode: 503, Status: "503 Service Unavailable"}}, "Site error"},
+		{fmt.Errorf("https://x.test: %w", ErrBlocked), "Blocked by anti-bot protection"},
+		{context.DeadlineExceeded, "Timed out"},
+		{&net.DNSError{Err: "no such host", Name: "nope.invalid"}, "Host not found"},
+		{errors.New("something else"), "Scrape failed"},
+	} {
+		if got := ExplainError(tt.err).Kind; got != tt.want {
+			t.Errorf("ExplainError(%v).Kind = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestVerifyRequest(t *testing.T) {
+	secret := []byte("s3cret")
+	body := `{"urls":["https://example.com"],"selector":"a"}`
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	newReq := func(method, target, ts, sig string) *http.Request {
+		r := httptest.NewRequest(method, target, strings.NewReader(body))
+		if sig != "" {
+			r.Header.Set(SignatureHeader, sig)
+		}
+		if ts != "" {
+			r.Header.Set(TimestampHeader, ts)
+		}
+		return r
+	}
+	good := Sign(secret, http.MethodPost, "/api/bulk-scrape", "x=1", now, []byte(body))
+
+	r := newReq("POST", "/api/bulk-scrape?x=1", now, "sha256="+good)
+	if err := VerifyRequest(secret, r); err != nil {
+		t.Fatalf("valid signature rejected: %v", err)
+	}
+	if got, _ := io.ReadAll(r.Body); string(got) != body {
+		t.Fatalf("body after verify = %q, want it restored", got)
+	}
+
+	old := strconv.FormatInt(time.Now().Add(-MaxSignatureAge-time.Minute).Unix(), 10)
+	tests := []struct {
+		name string
+		r    *http.Request
+		want error
+	}{
+		{"unsigned", newReq("POST", "/api/bulk-scrape?x=1", now, ""), ErrUnsigned},
+		{"no timestamp", newReq("POST", "/api/bulk-scrape?x=1", "", good), ErrUnsigned},
+		{"malformed", newReq("POST", "/api/bulk-scrape?x=1", now, "zz"), ErrBadSignature},
+		{"other path", newReq("POST", "/api/batch?x=1", now, good), ErrBadSignature},
+		{"other method", newReq("DELETE", "/api/bulk-scrape?x=1", now, good), ErrBadSignature},
+		{"other timestamp", newReq("POST", "/api/bulk-scrape?x=1", strconv.FormatInt(time.Now().Unix()-1, 10), good), ErrBadSignature},
+		{"expired", newReq("POST", "/api/bulk-scrape?x=1", old, Sign(secret, "POST", "/api/bulk-scrape", "x=1", old, []byte(body))), ErrStaleSignature},
+		{"bad timestamp", newReq("POST", "/api/bulk-scrape?x=1", "soon", good), ErrStaleSignature},
+	}
+	for _, tt := range tests {
+		if err := VerifyRequest(secret, tt.r); !errors.Is(err, tt.want) {
+			t.Errorf("%s: %v, want %v", tt.name, err, tt.want)
+		}
+	}
+	if err := VerifyRequest([]byte("other"), newReq("POST", "/api/bulk-scrape?x=1", now, good)); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("wrong secret = %v, want ErrBadSignature", err)
+	}
+
+	// SignRequest produces what VerifyRequest accepts.
+	r = httptest.NewRequest("DELETE", "/jobs/abc", nil)
+	if err := SignRequest(secret, r); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyRequest(secret, r); err != nil {
+		t.Errorf("SignRequest signature rejected: %v", err)
+	}
+}
+
+func TestSitemap(t *testing.T) {
+	var srvURL string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/sitemap.xml":
+			fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?>
+<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <sitemap><loc>%[1]s/posts.xml.gz</loc></sitemap>
+  <sitemap><loc>%[1]s/missing.xml</loc></sitemap>
+  <sitemap><loc>%[1]s/pages.xml</loc></sitemap>
+</sitemapindex>`, srvURL)
+		case "/posts.xml.gz":
+			zw := gzip.NewWriter(w)
+			fmt.Fprintf(zw, `<urlset><url><loc>%[1]s/a</loc></url><url><loc> %[1]s/b </loc></url></urlset>`, srvURL)
+			zw.Close()
+		case "/pages.xml":
+			fmt.Fprintf(w, `<urlset><url><loc>%[1]s/b</loc></url><url><loc>%[1]s/c</loc></url></urlset>`, srvURL)
+		case "/page.html":
+			_, _ = w.Write([]byte(`<html><body>not a sitemap</body></html>`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	srvURL = srv.URL
+	cfg := DefaultConfig()
+	cfg.MaxRetries = 1
+	cli := NewClient(cfg)
+
+	got, err := cli.Sitemap(context.Background(), srv.URL+"/sitemap.xml")
+	want := []string{srv.URL + "/a", srv.URL + "/b", srv.URL + "/c"}
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Fatalf("Sitemap = %v, %v; want %v", got, err, want)
+	}
+	if _, err := cli.Sitemap(context.Background(), srv.URL+"/page.html"); err == nil {
+		t.Fatal("an HTML page was accepted as a sitemap")
+	}
+}
+
+func TestSuggestSelectors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`<html><body>
+			<div class="nav"><a class="nav-link" href="/n1">Home</a><a class="nav-link" href="/n2">New</a><a class="nav-link" href="/n3">Ask</a></div>
+			<h2><a href="/h">Headline</a></h2>
+			<ul class="stories">
+				<li><span class="titleline"><a class="story" href="/1">One</a></span></li>
+				<li><span class="titleline"><a class="story" href="/2">Two</a></span></li>
+				<li><span class="titleline"><a class="story" href="/3">Three</a></span></li>
+				<li><span class="titleline"><a class="story" href="/4">Four</a></span></li>
+			</ul>
+			<div class="tags"><a class="tag" href="/t1">go</a><a class="tag" href="/t2">web</a></div>
+			<div class="promo"><a class="1ad sponsored" href="/ad">Buy now</a><a href="/empty"> </a></div>
+			</body></html>`))
+	}))
+	defer srv.Close()
+
+	// Class patterns matching fewer than minClassMatches elements (.tags,
+	// .promo) are dropped, common ones are kept at any count; ties are
+	// broken by selector.
+	all := []SelectorSuggestion{
+		{".titleline a", 4, "One"},
+		{"a.story", 4, "One"},
+		{"li a", 4, "One"},
+		{".nav a", 3, "Home"},
+		{"a.nav-link", 3, "Home"},
+		{"h2 a", 1, "Headline"},
+	}
+	cli := NewClient(DefaultConfig())
+	for _, limit := range []int{0, 2, 10} {
+		got, err := cli.SuggestSelectors(context.Background(), srv.URL, limit)
+		want := all
+		if limit > 0 && limit < len(all) {
+			want = all[:limit]
+		}
+		if err != nil || !reflect.DeepEqual(got, want) {
+			t.Errorf("SuggestSelectors(limit %d) = %+v, %v; want %+v", limit, got, err, want)
+		}
+	}
+}
+
+func TestClassifyLinks(t *testing.T) {
+	var heads atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			heads.Add(1)
+		}
+		switch r.URL.Path {
+		case "/":
+			_, _ = w.Write([]byte(`<a href="/doc.pdf">Paper</a><a href="/doc.pdf">Paper again</a>
+				<a href="/photo">Photo</a><a href="/post">Post</a><a href="/gone">Gone</a><a href="#top">Top</a>`))
+		case "/doc.pdf":
+			w.Header().Set("Content-Type", "application/pdf")
+		case "/photo":
+			w.Header().Set("Content-Type", "image/jpeg")
+		case "/post":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	cli := NewClient(DefaultConfig()).WithOptions(Options{ClassifyLinks: true})
+
+	run := cli.ScrapeAll([]string{srv.URL + "/"}, "a")
+	var got []string
+	for _, r := range run.Results {
+		got = append(got, r.LinkType)
+	}
+	want := []string{LinkPDF, LinkPDF, LinkImage, LinkPage, LinkUnknown, LinkPage}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("link types = %v, want %v", got, want)
+	}
+	first := heads.Load()
+	cli.ScrapeAll([]string{srv.URL + "/"}, "a")
+	if again := heads.Load() - first; again != 1 {
+		t.Fatalf("second scrape sent %d HEADs, want 1 (only the failed link; the rest are cached)", again)
+	}
+}
+
+func TestScrapeScriptJSON(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`<html><head>
+			<script type="application/ld+json">{"@type": "Article", "headline": "First", "id": 9007199254740993}</script>
+			<script type="application/ld+json">not json</script>
+			<script>var ignored = {"headline": "Nope"};</script>
+			</head><body>
+			<script id="__NEXT_DATA__" type="application/json">{"props": {"pageProps": {"posts": [{"title": "A"}, {"title": "B"}]}}}</script>
+			</body></html>`))
+	}))
+	defer srv.Close()
+	cli := NewClient(DefaultConfig())
+
+	got, err := cli.ScrapeScriptJSON(context.Background(), srv.URL, "", "props.pageProps.posts[*].title")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Scripts != 3 || got.Invalid != 1 || !reflect.DeepEqual(got.Values, []any{"A", "B"}) {
+		t.Fatalf("ScrapeScriptJSON = %+v, want 3 scripts, 1 invalid, values [A B]", got)
+	}
+
+	got, err = cli.ScrapeScriptJSON(context.Background(), srv.URL, `script[type="application/ld+json"]`, "id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []any{json.Number("9007199254740993")}; !reflect.DeepEqual(got.Values, want) {
+		t.Fatalf("values = %#v, want %#v", got.Values, want)
+	}
+
+	for _, bad := range []string{"a..b", "a[]", ".a"} {
+		if _, err := ParseJSONPath(bad); err == nil {
+			t.Errorf("ParseJSONPath(%q) accepted an empty key", bad)
+		}
+	}
+}
+
+func TestRunBatchWithLimits(t *testing.T) {
+	var inFlight, peak atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for p := peak.Load(); n > p && !peak.CompareAndSwap(p, n); p = peak.Load() {
+		}
+		if r.URL.Path == "/slow" {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(2 * time.Second):
+			}
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+		_, _ = w.Write([]byte(`<a href="/x">X</a>`))
+	}))
+	defer srv.Close()
+
+	items := []BatchItem{{URL: srv.URL + "/slow", Selector: "a"}}
+	for i := range 4 {
+		items = append(items, BatchItem{URL: fmt.Sprintf("%s/%d", srv.URL, i), Selector: "a"})
+	}
+	cfg := DefaultConfig()
+	cfg.CrawlDelay, cfg.RateLimit = 0, 100
+	start := time.Now()
+	run := NewClient(cfg).WithLimits(2, 200*time.Millisecond).RunBatch(items)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("batch took %s, want the slow site cut off at its timeout", elapsed)
+	}
+	if peak.Load() > 2 {
+		t.Fatalf("%d requests in flight, want at most 2", peak.Load())
+	}
+	if slow := run.Results[0]; !slow.TimedOut || slow.Timing != nil {
+		t.Fatalf("slow row = %+v, want timed out without timing", slow)
+	}
+	for _, row := range run.Results[1:] {
+		if row.Error != "" || row.Timing == nil || row.Timing.TotalMs <= 0 {
+			t.Fatalf("row %s = %+v, want success with timing", row.URL, row)
+		}
+	}
+}
+
+func TestScrapeAllPages(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/og" {
+			_, _ = w.Write([]byte(`<head><title>OG only</title><meta property="og:description" content="From OG"><link rel="canonical" href="/og-page"></head><a href="/">x</a>`))
+			return
+		}
+		_, _ = w.Write([]byte(`<head><title>
+			Front   page </title><meta name="description" content=" The news "></head><a href="/">x</a>`))
+	}))
+	defer srv.Close()
+
+	run := NewClient(DefaultConfig()).ScrapeAll([]string{srv.URL + "/"}, "a")
+	want := []PageInfo{{URL: srv.URL + "/", Title: "Front page", Description: "The news"}}
+	if !reflect.DeepEqual(run.Pages, want) {
+		t.Fatalf("Pages = %+v, want %+v", run.Pages, want)
+	}
+	run = NewClient(DefaultConfig()).ScrapeAll([]string{srv.URL + "/og"}, "a")
+	if len(run.Pages) != 1 || run.Pages[0].Description != "From OG" || run.Pages[0].Canonical != srv.URL+"/og-page" {
+		t.Fatalf("Pages = %+v, want the og:description fallback and the resolved canonical URL", run.Pages)
+	}
+}
+
+func TestHostHeaders(t *testing.T) {
+	got := make(chan http.Header, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got <- r.Header
+		_, _ = w.Write([]byte(`<a href="/">x</a>`))
+	}))
+	defer srv.Close()
+
+	hh, err := ParseHostHeaders([]byte(`{
+		"0.1": {"Referer": "https://parent/", "X-Site": "parent"},
+		"127.0.0.1": {"X-Site": "exact", "Accept-Language": "de"},
+		"example.com": {"X-Site": "other"}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := DefaultConfig()
+	cfg.HostHeaders = hh
+	cfg.CacheSize = 0
+	cli := NewClient(cfg)
+
+	opts, err := ParseOptions(url.Values{"header": {"Referer: https://request/", "X-Extra:  1 "}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run := cli.WithOptions(opts).ScrapeAll([]string{srv.URL}, "a"); len(run.Errs) != 0 {
+		t.Fatal(run.Errs)
+	}
+	h := <-got
+	for name, want := range map[string]string{"Referer": "https://request/", "X-Site": "exact", "Accept-Language": "de", "X-Extra": "1"} {
+		if h.Get(name) != want {
+			t.Errorf("%s = %q, want %q", name, h.Get(name), want)
+		}
+	}
+
+	for _, bad := range [][]string{{"NoColon"}, {"Host: evil"}, {"Bad Name: x"}} {
+		if _, err := ParseHeaders(bad); err == nil {
+			t.Errorf("ParseHeaders(%q) accepted", bad)
+		}
+	}
+	if _, err := ParseHostHeaders([]byte(`{"a.com": {"Content-Length": "1"}}`)); err == nil {
+		t.Error("ParseHostHeaders accepted Content-Length")
+	}
+}
+
+func TestRedirectLoop(t *testing.T) {
+	var hits atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/start", func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		http.Redirect(w, r, "/a", http.StatusFound)
+	})
+	mux.HandleFunc("/a", func(w http.ResponseWriter, r *http.Request) { http.Redirect(w, r, "/b", http.StatusFound) })
+	mux.HandleFunc("/b", func(w http.ResponseWriter, r *http.Request) { http.Redirect(w, r, "/a", http.StatusFound) })
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	run := NewClient(DefaultConfig()).ScrapeAll([]string{srv.URL + "/start"}, "a")
+	if len(run.Errs) != 1 {
+		t.Fatalf("errs = %v, want one", run.Errs)
+	}
+	var loop *RedirectLoopError
+	if !errors.As(run.Errs[0], &loop) || !errors.Is(run.Errs[0], ErrRedirectLoop) {
+		t.Fatalf("err = %v, want a RedirectLoopError", run.Errs[0])
+	}
+	if want := []string{srv.URL + "/a", srv.URL + "/b", srv.URL + "/a"}; !reflect.DeepEqual(loop.Cycle, want) {
+		t.Fatalf("cycle = %v, want %v", loop.Cycle, want)
+	}
+	if hits.Load() != 1 {
+		t.Fatalf("start requested %d times, want 1: a loop is not retried", hits.Load())
+	}
+	if got := ExplainError(run.Errs[0]).Kind; got != "Redirect loop" {
+		t.Fatalf("ExplainError kind = %q", got)
+	}
+}
+
+func TestScrapeOutputTruncate(t *testing.T) {
+	var results []ScrapeResult
+	for i := range 50 {
+		results = append(results, ScrapeResult{Title: fmt.Sprintf("Result %02d", i), Link: "https://example.com/", Group: "g"})
+	}
+	full := NewScrapeOutput([]string{"https://example.com"}, "h2 a", 1, results, nil)
+	if full.Truncate(0) || full.Truncate(1<<20) || len(full.Results) != 50 {
+		t.Fatal("output was truncated without a binding cap")
+	}
+
+	out := NewScrapeOutput([]string{"https://example.com"}, "h2 a", 1, results, nil)
+	const limit = 2000
+	if !out.Truncate(limit) {
+		t.Fatal("Truncate reported nothing dropped")
+	}
+	b, _ := json.Marshal(out)
+	if len(b)+1 > limit {
+		t.Fatalf("encoded %d bytes, want at most %d", len(b)+1, limit)
+	}
+	n := len(out.Results)
+	if n == 0 || n == 50 || !out.Truncated || out.Meta.TotalItems != 50 || len(out.Groups["g"]) != n {
+		t.Fatalf("kept %d results (groups %d), truncated=%v, total %d", n, len(out.Groups["g"]), out.Truncated, out.Meta.TotalItems)
+	}
+	more := NewScrapeOutput([]string{"https://example.com"}, "h2 a", 1, results[:n+1], nil)
+	more.Truncated = true
+	if b, _ := json.Marshal(more); len(b)+1 <= limit {
+		t.Fatalf("%d results would still fit in %d bytes", n+1, limit)
+	}
+}
+
+func TestLogin(t *testing.T) {
+	var logins atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		logins.Add(1)
+		if r.Method != http.MethodPost || r.PostFormValue("email") != "ada" || r.PostFormValue("pw") != "s3cret" {
+			http.Error(w, "bad credentials", http.StatusUnauthorized)
+			return
+		}
+		http.SetCookie(w, &http.Cookie{Name: "sid", Value: "42", Path: "/"})
+		http.Redirect(w, r, "/home", http.StatusSeeOther)
+	})
+	mux.HandleFunc("/home", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("/private/", func(w http.ResponseWriter, r *http.Request) {
+		if c, err := r.Cookie("sid"); err != nil || c.Value != "42" {
+			_, _ = w.Write([]byte(`<a href="/login">Sign in</a>`))
+			return
+		}
+		_, _ = w.Write([]byte(`<a href="/secret">Secret</a>`))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	q := url.Values{"loginUrl": {srv.URL + "/login"}, "loginUserField": {"email"}, "loginPassField": {"pw"}, "loginUser": {"ada"}, "loginPass": {"s3cret"}}
+	opts, err := ParseOptions(q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := fmt.Sprintf("%v %+v %#v", opts.Login, opts, opts.Login); strings.Contains(s, "s3cret") {
+		t.Fatalf("formatted options leak the password: %s", s)
+	}
+	run := NewClient(DefaultConfig()).WithOptions(opts).ScrapeAll([]string{srv.URL + "/private/1", srv.URL + "/private/2"}, "a")
+	if len(run.Errs) != 0 || len(run.Results) != 2 || run.Results[0].Title != "Secret" {
+		t.Fatalf("results = %+v, errs = %v, want the logged-in page twice", run.Results, run.Errs)
+	}
+	if logins.Load() != 1 {
+		t.Fatalf("logged in %d times, want once per scrape", logins.Load())
+	}
+
+	q.Set("loginPass", "wrong")
+	opts, _ = ParseOptions(q)
+	run = NewClient(DefaultConfig()).WithOptions(opts).ScrapeAll([]string{srv.URL + "/private/1"}, "a")
+	if len(run.Errs) != 1 || !errors.Is(run.Errs[0], ErrLoginFailed) || strings.Contains(run.Errs[0].Error(), "wrong") {
+		t.Fatalf("errs = %v, want ErrLoginFailed without the password", run.Errs)
+	}
+
+	if _, err := ParseOptions(url.Values{"loginUrl": {srv.URL + "/login"}, "loginUser": {"ada"}}); err == nil {
+		t.Fatal("loginUrl without loginPass was accepted")
+	}
+}
+
+func TestParseRobots(t *testing.T) {
+	body := []byte(`# comment
+User-agent: *
+Disallow: /private
+Allow: /private/open
+Disallow: /*.pdf$
+
+User-agent: GoScraper
+User-agent: other
+Disallow: /only-bots
+Crawl-delay: 5
+`)
+	star := parseRobots(body, "")
+	for path, want := range map[string]bool{
+		"/": true, "/private": false, "/private/x": false, "/private/open/y": true,
+		"/doc.pdf": false, "/doc.pdf?x=1": true, "/only-bots": true,
+	} {
+		if got := star.allowed(path); got != want {
+			t.Errorf("* allowed(%q) = %v, want %v", path, got, want)
+		}
+	}
+	bot := parseRobots(body, "goscraper")
+	if bot.allowed("/only-bots") || !bot.allowed("/private") {
+		t.Error("the GoScraper group should replace the * group")
+	}
+}
+
+func TestPolite(t *testing.T) {
+	var agents sync.Map
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		agents.Store(r.UserAgent(), true)
+		if r.URL.Path == "/robots.txt" {
+			_, _ = w.Write([]byte("User-agent: *\nDisallow: /admin\n"))
+			return
+		}
+		_, _ = w.Write([]byte(`<a href="/">x</a>`))
+	}))
+	defer srv.Close()
+
+	opts, err := ParseOptions(url.Values{"polite": {"true"}, "crawlDelay": {"10ms"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !opts.Robots || opts.Workers != PoliteWorkers || opts.CrawlDelay != 10*time.Millisecond {
+		t.Fatalf("polite options = %+v", opts)
+	}
+	cfg := DefaultConfig()
+	cfg.CrawlDelay = 0
+	cli := NewClient(cfg).WithOptions(opts)
+	if cli.Workers() != PoliteWorkers {
+		t.Fatalf("workers = %d, want %d", cli.Workers(), PoliteWorkers)
+	}
+	run := cli.ScrapeAll([]string{srv.URL + "/", srv.URL + "/admin/users"}, "a")
+	if len(run.Errs) != 1 || !errors.Is(run.Errs[0], ErrDisallowedByRobots) || len(run.Results) != 1 {
+		t.Fatalf("errs = %v, results = %d, want /admin refused by robots.txt", run.Errs, len(run.Results))
+	}
+	if _, ok := agents.Load(PoliteUserAgent); !ok {
+		t.Fatal("requests did not carry PoliteUserAgent")
+	}
+
+	if opts, _ := ParseOptions(url.Values{"polite": {"true"}, "robots": {"false"}}); opts.Robots {
+		t.Fatal("robots=false did not override polite")
+	}
+	if _, err := ParseOptions(url.Values{"crawlDelay": {"2h"}}); err == nil {
+		t.Fatal("crawlDelay over the maximum was accepted")
+	}
+}
+
+func TestScrapeMicrodata(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`<html><body>
+			<div itemscope itemtype="https://schema.org/Product" itemid="sku-1">
+				<h1 itemprop="name">  Blue
+					Kettle </h1>
+				<img itemprop="image" src="/kettle.jpg">
+				<div itemprop="offers" itemscope itemtype="https://schema.org/Offer">
+					<meta itemprop="priceCurrency" content="EUR">
+					<span itemprop="price">19.99</span>
+				</div>
+				<section><span itemprop="keywords tags">kitchen</span></section>
+			</div>
+			<p itemscope><time itemprop="published" datetime="2024-05-01">May 1</time></p>
+			</body></html>`))
+	}))
+	defer srv.Close()
+
+	got, err := NewClient(DefaultConfig()).ScrapeMicrodata(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []MicroItem{
+		{
+			Type: []string{"https://schema.org/Product"},
+			ID:   "sku-1",
+			Properties: map[string][]any{
+				"name":  {"Blue Kettle"},
+				"image": {srv.URL + "/kettle.jpg"},
+				"offers": {MicroItem{
+					Type:       []string{"https://schema.org/Offer"},
+					Properties: map[string][]any{"priceCurrency": {"EUR"}, "price": {"19.99"}},
+				}},
+				"keywords": {"kitchen"},
+				"tags":     {"kitchen"},
+			},
+		},
+		{Properties: map[string][]any{"published": {"2024-05-01"}}},
+	}
+	if !reflect.DeepEqual(got.Items, want) {
+		t.Fatalf("items = %+v\nwant %+v", got.Items, want)
+	}
+}
+
+func TestStats(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(`<a href="/x">x</a>`))
+	}))
+	defer srv.Close()
+	cfg := DefaultConfig()
+	cfg.CrawlDelay, cfg.RateLimit, cfg.MaxRetries = 0, 100, 1
+	cli := NewClient(cfg)
+
+	cli.ScrapeAll([]string{srv.URL, srv.URL + "/missing"}, "a")
+	cli.WithOptions(Options{}).ScrapeAll([]string{srv.URL}, "a")
+
+	stats := cli.Stats()
+	if len(stats) != 2 {
+		t.Fatalf("Stats() = %+v, want 2 URLs", stats)
+	}
+	if s := stats[0]; s.URL != srv.URL || s.Count != 2 || s.Errors != 0 || s.MinMs > s.AvgMs || s.AvgMs > s.MaxMs {
+		t.Errorf("stats[0] = %+v, want 2 clean scrapes of %s", s, srv.URL)
+	}
+	if s := stats[1]; s.Count != 1 || s.Errors != 1 || !strings.Contains(s.LastError, "404") {
+		t.Errorf("stats[1] = %+v, want 1 failed scrape with a 404", s)
+	}
+}
+
+func TestProxyRotation(t *testing.T) {
+	var hits [2]atomic.Int32
+	proxy := func(i int) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			hits[i].Add(1)
+			_, _ = w.Write([]byte(`<a href="/a">via proxy</a>`))
+		}))
+	}
+	p0, p1 := proxy(0), proxy(1)
+	defer p0.Close()
+	defer p1.Close()
+	dead := httptest.NewServer(http.NotFoundHandler())
+	dead.Close()
+
+	proxies, err := ParseProxies(p0.URL + ", " + strings.TrimPrefix(p1.URL, "http://") + "\n" + dead.URL)
+	if err != nil || len(proxies) != 3 {
+		t.Fatalf("ParseProxies = %v, %v", proxies, err)
+	}
+	cfg := DefaultConfig()
+	cfg.CrawlDelay, cfg.RateLimit = 0, 100
+	cfg.Proxies = proxies
+	cli := NewClient(cfg)
+
+	seen := map[string]int{}
+	for range 6 {
+		for r := range cli.ScrapeStreamed([]string{"http://target.invalid/"}, "a") {
+			if r.Err != nil || len(r.Items) != 1 {
+				t.Fatalf("scrape through proxies: %v, %d items", r.Err, len(r.Items))
+			}
+			seen[r.Proxy]++
+		}
+	}
+	// The dead proxy's turns fall through to the first one.
+	if hits[0].Load() != 4 || hits[1].Load() != 2 || seen[p0.URL] != 4 || seen[p1.URL] != 2 {
+		t.Errorf("hits = %d/%d, reported %v; want 4 via %s and 2 via %s", hits[0].Load(), hits[1].Load(), seen, p0.URL, p1.URL)
+	}
+
+	if _, err := ParseProxies("ftp://proxy:21"); err == nil {
+		t.Error("ParseProxies accepted an ftp proxy")
+	}
+}
+
+func TestProxyBlockPrivate(t *testing.T) {
+	var hits atomic.Int32
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		hits.Add(1)
+		_, _ = w.Write([]byte(`<a href="/a">via proxy</a>`))
+	}))
+	defer proxy.Close()
+	proxies, err := ParseProxies(proxy.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := DefaultConfig()
+	cfg.CrawlDelay, cfg.RateLimit = 0, 100
+	cfg.Proxies = proxies
+	cfg.HostPolicy.BlockPrivate = true
+	cli := NewClient(cfg)
+
+	// The proxy itself is on loopback and trusted; the targets are not.
+	// fetch skips the up-front CheckURL, as a rebound name would.
+	for _, target := range []string{"http://127.0.0.1:9/", "http://localhost:9/", "http://target.invalid/"} {
+		if _, _, err := cli.fetch(context.Background(), target, "a"); !errors.Is(err, ErrHostNotAllowed) {
+			t.Errorf("fetch(%s) through a proxy = %v, want ErrHostNotAllowed", target, err)
+		}
+	}
+	if n := hits.Load(); n != 0 {
+		t.Fatalf("proxy got %d requests for refused targets", n)
+	}
+	if _, _, err := cli.fetch(context.Background(), "http://203.0.113.7/", "a"); err != nil || hits.Load() != 1 {
+		t.Errorf("fetch of a public address through the proxy = %v, %d proxy hits; want it served", err, hits.Load())
+	}
+}
+
+func TestScrapeForms(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`<html><body>
+			<form id="login" action="/session" method="post">
+				<input type="hidden" name="csrf" value="t0k3n">
+				<input name="user" required>
+				<input type="password" name="pass">
+				<input type="submit" value="Sign in">
+			</form>
+			<input form="login" type="checkbox" name="remember" value="1">
+			<form role="search">
+				<select name="in"><option value="all">All</option><option selected>Docs</option></select>
+				<textarea name="q">go</textarea>
+			</form>
+			</body></html>`))
+	}))
+	defer srv.Close()
+
+	got, err := NewClient(DefaultConfig()).ScrapeForms(context.Background(), srv.URL+"/page")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Form{
+		{ID: "login", Action: srv.URL + "/session", Method: "POST", Fields: []FormField{
+			{Name: "csrf", Type: "hidden", Value: "t0k3n"},
+			{Name: "user", Type: "text", Required: true},
+			{Name: "pass", Type: "password"},
+			{Name: "remember", Type: "checkbox", Value: "1"},
+		}},
+		{Action: srv.URL + "/page", Method: "GET", Fields: []FormField{
+			{Name: "in", Type: "select", Value: "Docs", Options: []string{"all", "Docs"}},
+			{Name: "q", Type: "textarea", Value: "go"},
+		}},
+	}
+	if !reflect.DeepEqual(got.Forms, want) {
+		t.Fatalf("forms = %+v\nwant %+v", got.Forms, want)
+	}
+}
+
+func TestAllowedAttrs(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(`<a href="/x" title="T" data-id="7" data-secret="s"><b>X</b></a>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	base, _ := url.Parse("https://example.com/")
+	opts := Options{
+		Attrs:  []string{"title", "data-secret", "data-id"},
+		Fields: []FieldSpec{{Name: "label", Selector: "b"}, {Name: "secret", Attr: "data-secret"}},
+	}
+	cfg := DefaultConfig()
+	cfg.AllowedAttrs = []string{"title", "data-id"}
+
+	got, _ := NewClient(cfg).WithOptions(opts).extractAll(doc.Find("a"), base)
+	if len(got) != 1 {
+		t.Fatalf("got %d results, want 1", len(got))
+	}
+	if want := map[string]string{"title": "T", "data-id": "7"}; !reflect.DeepEqual(got[0].Attrs, want) {
+		t.Errorf("Attrs = %v, want %v", got[0].Attrs, want)
+	}
+	if want := map[string]string{"label": "X"}; !reflect.DeepEqual(got[0].Fields, want) {
+		t.Errorf("Fields = %v, want %v", got[0].Fields, want)
+	}
+	if len(opts.Attrs) != 3 || len(opts.Fields) != 2 {
+		t.Errorf("WithOptions modified the caller's options: %+v", opts)
+	}
+}
+
+func TestRequestFormat(t *testing.T) {
+	for _, tc := range []struct{ target, accept, fallback, want string }{
+		{"/?url=x", "", "", ""},
+		{"/?url=x", "application/json", "", "json"},
+		{"/?url=x", "text/csv;charset=utf-8", "", "csv"},
+		{"/?url=x", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", "", ""},
+		{"/?url=x", "text/html;q=0.5, application/json", "", "json"},
+		{"/?url=x", "application/json;q=0.2, text/csv;q=0.8", "", "csv"},
+		{"/?url=x", "image/png", "", ""},
+		{"/?url=x&format=md", "application/json", "", "md"},
+		{"/?url=x", "", "json", "json"},
+		{"/?url=x", "*/*", "csv", "csv"},
+		{"/?url=x", "text/html,*/*;q=0.8", "json", ""},
+		{"/?url=x", "text/markdown", "json", "md"},
+		{"/?url=x&format=csv", "", "json", "csv"},
+	} {
+		r := httptest.NewRequest(http.MethodGet, tc.target, nil)
+		if tc.accept != "" {
+			r.Header.Set("Accept", tc.accept)
+		}
+		if got := RequestFormat(r, tc.fallback); got != tc.want {
+			t.Errorf("RequestFormat(%s, Accept: %q, %q) = %q, want %q", tc.target, tc.accept, tc.fallback, got, tc.want)
+		}
+	}
+}
+
+func TestTitleAndLinkRegex(t *testing.T) {
+	q := url.Values{"url": {"https://example.com"}, "selector": {"a"}, "titleRegex": {`\$([0-9.]+)`}, "linkRegex": {`/item/(\d+)`}}
+	opts, err := ParseOptions(q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(`<a href="/item/42">Now only $19.99!</a><a href="/about">About us</a>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	base, _ := url.Parse("https://example.com/")
+	got, _ := NewClient(DefaultConfig()).WithOptions(opts).extractAll(doc.Find("a"), base)
+	want := []ScrapeResult{
+		{Title: "19.99", Link: "42"},
+		{Title: "About us", Link: "https://example.com/about"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	q.Set("titleRegex", "(unclosed")
+	if _, err := ParseOptions(q); err == nil || !strings.Contains(err.Error(), "titleRegex") {
+		t.Errorf("invalid titleRegex: err = %v, want one naming the parameter", err)
+	}
+}
+
+func TestParseWarning(t *testing.T) {
+	for _, tc := range []struct {
+		name, body, want string
+	}{
+		{"empty", "  \n", "empty page"},
+		{"plain text", "Access denied", "parse produced no elements from 13 bytes"},
+		{"few elements in a large body", "<p>" + strings.Repeat("x", 2000) + "</p>", "only 1 elements parsed from 2007 bytes"},
+		{"small page", "<p>hi</p>", ""},
+		{"normal page", "<html><head><title>T</title></head><body><ul><li>a</li><li>b</li><li>c</li></ul></body></html>", ""},
+	} {
+		doc, err := goquery.NewDocumentFromReader(strings.NewReader(tc.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := parseWarning(doc, []byte(tc.body))
+		if tc.want == "" && got != "" || !strings.Contains(got, tc.want) {
+			t.Errorf("%s: warning = %q, want it to contain %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestConcurrency(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.WorkerCount, cfg.MaxWorkers = 4, 10
+	for _, tc := range []struct {
+		query url.Values
+		want  int
+	}{
+		{url.Values{}, 4},
+		{url.Values{"concurrency": {"2"}}, 2},
+		{url.Values{"concurrency": {"8"}}, 8},
+		{url.Values{"concurrency": {"50"}}, 10},
+		{url.Values{"workers": {"7"}}, 7},
+		{url.Values{"polite": {"true"}, "concurrency": {"8"}}, 4},
+	} {
+		opts, err := ParseOptions(tc.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := NewClient(cfg).WithOptions(opts).Workers(); got != tc.want {
+			t.Errorf("%v: workers = %d, want %d", tc.query, got, tc.want)
+		}
+	}
+	if _, err := ParseOptions(url.Values{"concurrency": {"0"}}); err == nil {
+		t.Error("concurrency=0 was accepted")
+	}
+}
+
+func TestCursor(t *testing.T) {
+	all := []ScrapeResult{{Title: "a"}, {Title: "b"}, {Title: "c"}, {Title: "d"}, {Title: "e"}}
+	var got []string
+	q := url.Values{"count": {"2"}}
+	for range len(all) {
+		opts, err := ParseOptions(q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out := ScrapeOutput{Results: opts.window(all)}
+		out.SetNextCursor(opts.Offset, len(all))
+		for _, r := range out.Results {
+			got = append(got, r.Title)
+		}
+		if out.NextCursor == "" {
+			break
+		}
+		q.Set("cursor", out.NextCursor)
+	}
+	if want := []string{"a", "b", "c", "d", "e"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("pages returned %v, want %v", got, want)
+	}
+
+	for _, bad := range []url.Values{
+		{"cursor": {"not-a-cursor"}},
+		{"cursor": {EncodeCursor(2)}, "offset": {"1"}},
+	} {
+		if _, err := ParseOptions(bad); err == nil {
+			t.Errorf("%v was accepted", bad)
+		}
+	}
+}
+
+func TestImageLinks(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(`
+		<img alt="Wide" src="/s.jpg" srcset="/m.jpg 800w, /l.jpg 1600w, /xs.jpg 320w">
+		<img alt="Dense" srcset="/a.jpg, /b.jpg 3x,/c.jpg 2x">
+		<img src="plain.png">
+		<img alt="Data" srcset="data:image/png;base64,AAA= 1x, /d2.png 2x">
+		<img alt="None">`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	base, _ := url.Parse("https://example.com/gallery/")
+	cli := NewClient(DefaultConfig())
+	got, _ := cli.extractAll(doc.Find("img"), base)
+	want := []ScrapeResult{
+		{Title: "Wide", Link: "https://example.com/l.jpg"},
+		{Title: "Dense", Link: "https://example.com/b.jpg"},
+		{Title: "", Link: "https://example.com/gallery/plain.png"},
+		{Title: "Data", Link: "https://example.com/d2.png"},
+		{Title: "None", Link: ""},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+	if n := cli.countMatches(doc.Find("img")); n != len(want) {
+		t.Errorf("countMatches = %d, want %d", n, len(want))
+	}
+}
+
+func TestScrapeAPI(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/html" {
+			_, _ = w.Write([]byte(`<p>not json</p>`))
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{
+			"method": r.Method,
+			"type":   r.Header.Get("Content-Type"),
+			"body":   string(body),
+		}})
+	}))
+	defer srv.Close()
+
+	q := url.Values{"mode": {"api"}, "contentType": {"json"}, "body": {`{"query":"{ posts { title } }"}`}, "path": {"data.*"}}
+	opts, err := ParseOptions(q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := NewClient(DefaultConfig()).WithOptions(opts).ScrapeAPI(context.Background(), srv.URL+"/graphql", opts.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []any{`{"query":"{ posts { title } }"}`, "POST", ContentTypeJSON}
+	if got.Method != http.MethodPost || !reflect.DeepEqual(got.Values, want) {
+		t.Errorf("ScrapeAPI = %+v, want a POST answering %v", got, want)
+	}
+
+	got, err = NewClient(DefaultConfig()).WithOptions(Options{Mode: ModeAPI}).ScrapeAPI(context.Background(), srv.URL+"/rest", "data.method")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got.Values, []any{"GET"}) {
+		t.Errorf("without a body: values = %v, want [GET]", got.Values)
+	}
+
+	if _, err := NewClient(DefaultConfig()).ScrapeAPI(context.Background(), srv.URL+"/html", ""); !errors.Is(err, ErrNotJSON) {
+		t.Errorf("HTML answer: err = %v, want ErrNotJSON", err)
+	}
+
+	for _, bad := range []url.Values{
+		{"body": {"q=1"}},
+		{"mode": {"api"}, "contentType": {"xml"}},
+		{"mode": {"api"}, "contentType": {"json"}, "body": {"{not json"}},
+	} {
+		if _, err := ParseOptions(bad); err == nil {
+			t.Errorf("%v was accepted", bad)
+		}
+	}
+}
+
+func TestAutoFixSelector(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`<div class="list"><a class="story" href="/1">One</a><a class="story" href="/2">Two</a></div>`))
+	}))
+	defer srv.Close()
+
+	for _, tc := range []struct{ selector, want string }{
+		{".list.story", ".list .story"},
+		{".list .story", ""},
+		{"story", ".story"},
+		{"section", ""},
+	} {
+		cli := NewClient(DefaultConfig()).WithOptions(Options{AutoFix: true})
+		r := <-cli.ScrapeStreamed([]string{srv.URL}, tc.selector)
+		if r.Err != nil {
+			t.Fatal(r.Err)
+		}
+		if r.Page.FixedSelector != tc.want {
+			t.Errorf("%q: fixed to %q, want %q", tc.selector, r.Page.FixedSelector, tc.want)
+		}
+		if tc.selector != "section" && len(r.Items) != 2 {
+			t.Errorf("%q: %d results, want 2", tc.selector, len(r.Items))
+		}
+	}
+
+	r := <-NewClient(DefaultConfig()).ScrapeStreamed([]string{srv.URL}, ".list.story")
+	if r.Page.FixedSelector != "" || len(r.Items) != 0 {
+		t.Errorf("without AutoFix: fixed %q, %d results", r.Page.FixedSelector, len(r.Items))
+	}
+}
+
+func TestDOMPath(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(`<div id="main" class="list  wide"><p><a class="story" href="/1">One</a></p></div>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	base, _ := url.Parse("https://example.com/")
+	got, _ := NewClient(DefaultConfig()).WithOptions(Options{Debug: true}).extractAll(doc.Find("a"), base)
+	if want := "html > body > div#main.list.wide > p > a.story"; len(got) != 1 || got[0].DOMPath != want {
+		t.Fatalf("got %+v, want DOMPath %q", got, want)
+	}
+	if got, _ := NewClient(DefaultConfig()).extractAll(doc.Find("a"), base); got[0].DOMPath != "" {
+		t.Errorf("DOMPath set without Debug: %q", got[0].DOMPath)
+	}
+}
+
+func TestTableObjects(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`<table>
+			<tr><th>Team</th><th colspan="2">Score</th><th></th></tr>
+			<tr><td rowspan="2">Reds</td><td>1</td><td>2</td><td>x</td></tr>
+			<tr><td>3</td><td>4</td></tr>
+		</table>`))
+	}))
+	defer srv.Close()
+
+	opts, err := ParseOptions(url.Values{"tableMode": {"objects"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tables, err := NewClient(DefaultConfig()).WithOptions(opts).ScrapeTables(context.Background(), srv.URL, "table")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tables) != 1 {
+		t.Fatalf("got %d tables, want 1", len(tables))
+	}
+	if want := []string{"Team", "Score", "Score 2", "column 4"}; !reflect.DeepEqual(tables[0].Header, want) {
+		t.Errorf("header = %q, want %q", tables[0].Header, want)
+	}
+	want := []map[string]string{
+		{"Team": "Reds", "Score": "1", "Score 2": "2", "column 4": "x"},
+		{"Team": "Reds", "Score": "3", "Score 2": "4", "column 4": ""},
+	}
+	if !reflect.DeepEqual(tables[0].Objects, want) {
+		t.Errorf("objects = %v, want %v", tables[0].Objects, want)
+	}
+}
+
+func TestQuoteContains(t *testing.T) {
+	for _, tc := range []struct{ in, want string }{
+		{`a:contains(Download now)`, `a:contains("Download now")`},
+		{`a:contains( Download )`, `a:contains("Download")`},
+		{`a:contains("Download now")`, `a:contains("Download now")`},
+		{`a:contains('Get it')`, `a:contains('Get it')`},
+		{`p:containsOwn(say "hi")`, `p:containsOwn("say \"hi\"")`},
+		{`a[title=':contains(x y)']`, `a[title=':contains(x y)']`},
+		{`links:a:contains(Get it), h2`, `links:a:contains("Get it"), h2`},
+		{`a:contains()`, `a:contains()`},
+		{`a:contains(open`, `a:contains(open`},
+		{`a:hover`, `a:hover`},
+	} {
+		if got := quoteContains(tc.in); got != tc.want {
+			t.Errorf("quoteContains(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = io.WriteString(w, `<a href="/a">Download now</a><a href="/b">download NOW (mirror)</a><a href="/c">Docs</a>`)
+	}))
+	defer srv.Close()
+	cfg := DefaultConfig()
+	cfg.CrawlDelay = 0
+	run := NewClient(cfg).ScrapeAll([]string{srv.URL}, `a:contains(download now)`)
+	if len(run.Errs) != 0 || len(run.Results) != 2 || run.Results[1].Link != srv.URL+"/b" {
+		t.Fatalf("ScrapeAll = %+v, want the two download links", run)
+	}
+}
+
+func TestScrapeStreamedContextCancel(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fast" {
+			_, _ = io.WriteString(w, `<a href="/x">x</a>`)
+			return
+		}
+		<-r.Context().Done() // hang until the scrape gives up
+	}))
+	defer srv.Close()
+
+	cfg := DefaultConfig()
+	cfg.CrawlDelay = 0
+	cfg.WorkerCount = 2
+	cfg.MaxRetries = 1
+	cfg.PageTimeout = 0
+	urls := []string{srv.URL + "/fast", srv.URL + "/slow1", srv.URL + "/slow2", srv.URL + "/slow3", srv.URL + "/slow4"}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	start := time.Now()
+	var got []JobResult
+	for r := range NewClient(cfg).ScrapeStreamedContext(ctx, urls, "a") {
+		got = append(got, r)
+		cancel() // the client went away after the first page
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("stream took %v after cancel", elapsed)
+	}
+	if len(got) == 0 || len(got) == len(urls) {
+		t.Fatalf("got %d pages, want some but not all of %d", len(got), len(urls))
+	}
+	for _, r := range got[1:] {
+		if r.Err == nil {
+			t.Errorf("%s: err = nil after cancel", r.URL)
+		}
+	}
+}
+
+func TestScrapeCeilings(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintf(w, `<a href="%[1]s/1">1</a><a href="%[1]s/2">2</a><a href="%[1]s/3">3</a>`, r.URL.Path)
+	}))
+	defer srv.Close()
+	var urls []string
+	for i := range 5 {
+		urls = append(urls, fmt.Sprintf("%s/p%d", srv.URL, i))
+	}
+	cfg := DefaultConfig()
+	cfg.CrawlDelay = 0
+	cfg.RateLimit = 1e6
+	cfg.WorkerCount = 1
+	cfg.MaxTotalResults = 10
+	cli := NewClient(cfg)
+
+	run := cli.ScrapeAll(urls, "a")
+	if len(run.Results) != 10 || !strings.Contains(run.StoppedReason, "maxTotalResults") {
+		t.Errorf("default ceiling: %d results, reason %q; want 10 and maxTotalResults", len(run.Results), run.StoppedReason)
+	}
+
+	for _, tc := range []struct {
+		query   string
+		results int
+		reason  string
+	}{
+		{"maxPages=2", 6, "after 2 of 5 pages (maxPages)"},
+		{"maxTotalResults=4", 4, "stopped at 4 results after 2 of 5 pages"},
+		{"maxTotalResults=6", 6, "stopped at 6 results after 2 of 5 pages"},
+		{"maxTotalResults=50", 10, "maxTotalResults"},                        // capped by the config
+		{"maxPages=3&maxTotalResults=9", 9, "after 3 of 5 pages (maxPages)"}, // 9 reached on the last page
+		{"maxPages=2&countOnly=true", 0, "maxPages"},
+	} {
+		q, _ := url.ParseQuery(tc.query)
+		opts, err := ParseOptions(q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		run := cli.WithOptions(opts).ScrapeAll(urls, "a")
+		if len(run.Results) != tc.results || !strings.Contains(run.StoppedReason, tc.reason) {
+			t.Errorf("%s: %d results, reason %q; want %d and %q", tc.query, len(run.Results), run.StoppedReason, tc.results, tc.reason)
+		}
+	}
+
+	if _, err := ParseOptions(url.Values{"maxPages": {"-1"}}); err == nil {
+		t.Error("maxPages=-1 accepted")
+	}
+
+	// Results are counted after dedup, a single page is never cut, and
+	// maxPages leaves the per-request URL cap alone.
+	same := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `<a href="/1">1</a><a href="/2">2</a><a href="/3">3</a>`)
+	}))
+	defer same.Close()
+	dup := []string{same.URL + "/a", same.URL + "/b", same.URL + "/c"}
+	run = cli.WithOptions(Options{Dedup: true, MaxTotalResults: 4}).ScrapeAll(dup, "a")
+	if len(run.Results) != 3 || run.StoppedReason != "" || run.Attempted != 3 {
+		t.Errorf("dedup: %d results, reason %q, %d attempted; want 3 unique from every page", len(run.Results), run.StoppedReason, run.Attempted)
+	}
+	run = cli.WithOptions(Options{MaxTotalResults: 2}).ScrapeAll(dup[:1], "a")
+	if len(run.Results) != 3 || run.StoppedReason != "" {
+		t.Errorf("single page: %d results, reason %q; want all 3", len(run.Results), run.StoppedReason)
+	}
+	if DefaultConfig().MaxTotalResults != 0 {
+		t.Error("MaxTotalResults is on by default")
+	}
+	if got := cli.WithOptions(Options{MaxPages: 2}).MaxURLs(); got != cfg.MaxURLsPerRequest {
+		t.Errorf("MaxURLs with maxPages=2 = %d, want %d", got, cfg.MaxURLsPerRequest)
+	}
+
+	// Attempted counts only the pages maxPages let through, so a caller can
+	// tell that all of them failed.
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer failing.Close()
+	run = cli.WithOptions(Options{MaxPages: 2}).ScrapeAll([]string{failing.URL + "/1", failing.URL + "/2", failing.URL + "/3"}, "a")
+	if run.Attempted != 2 || len(run.Errs) != 2 {
+		t.Errorf("failing pages: %d attempted, %d errors; want 2 and 2", run.Attempted, len(run.Errs))
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		header string
+		want   time.Duration
+		ok     bool
+	}{
+		{"120", 2 * time.Minute, true},
+		{" 0 ", 0, true},
+		{now.Add(30 * time.Second).Format(http.TimeFormat), 30 * time.Second, true},
+		{now.Add(-time.Hour).Format(http.TimeFormat), 0, true},
+		{"-5", 0, false},
+		{"1.5", 0, false},
+		{"soon", 0, false},
+		{"", 0, false},
+	} {
+		if got, ok := parseRetryAfter(tc.header, now); got != tc.want || ok != tc.ok {
+			t.Errorf("parseRetryAfter(%q) = %v, %v; want %v, %v", tc.header, got, ok, tc.want, tc.ok)
+		}
+	}
+
+	var calls, offCalls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/long":
+			w.Header().Set("Retry-After", "3600")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case r.URL.Path == "/off":
+			offCalls.Add(1)
+			w.Header().Set("Retry-After", "30")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case calls.Add(1) == 1:
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			_, _ = io.WriteString(w, `<a href="/x">x</a>`)
+		}
+	}))
+	defer srv.Close()
+	cfg := DefaultConfig()
+	cfg.CrawlDelay = 0
+	cfg.BaseRetryDelay = 10 * time.Second // far longer than the Retry-After
+	cfg.RetryStatuses = []int{http.StatusTooManyRequests}
+	cli := NewClient(cfg)
+
+	start := time.Now()
+	if _, err := cli.FetchRaw(context.Background(), srv.URL+"/short"); err != nil {
+		t.Fatalf("FetchRaw after Retry-After: %v", err)
+	}
+	if d := time.Since(start); d < time.Second || d > 5*time.Second {
+		t.Errorf("retried after %v, want the 1s Retry-After", d)
+	}
+
+	start = time.Now()
+	_, err := cli.FetchRaw(context.Background(), srv.URL+"/long")
+	var status *StatusError
+	if !errors.As(err, &status) || status.RetryAfter != time.Hour || !strings.Contains(err.Error(), "rate limited, retry after 1h0m0s (HTTP 429 Too Many Requests)") {
+		t.Fatalf("err = %v, want a rate-limited StatusError asking for 1h", err)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("gave up after %v, want at once", d)
+	}
+
+	// With retries off the wait is reported, not waited for.
+	cfg.MaxRetries = 1
+	_, err = NewClient(cfg).FetchRaw(context.Background(), srv.URL+"/off")
+	if !errors.As(err, &status) || status.RetryAfter != 30*time.Second || !strings.Contains(err.Error(), "rate limited, retry after 30s (HTTP 429 Too Many Requests)") {
+		t.Fatalf("err = %v, want a rate-limited StatusError asking for 30s", err)
+	}
+	if n := offCalls.Load(); n != 1 {
+		t.Errorf("%d requests with MaxRetries 1, want 1", n)
+	}
+}
+
+func TestLinkSchemes(t *testing.T) {
+	const page = `<a href="/a">Relative</a><a href="https://x.example/b">Absolute</a>
+		<a href="javascript:void(0)">Script</a><a href="mailto:me@x.example">Mail</a>
+		<a href="TEL:+100">Phone</a><a href="">Empty</a><a href="#">Hash</a><a>Plain</a>`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = io.WriteString(w, page)
+	}))
+	defer srv.Close()
+
+	for _, tc := range []struct{ schemes, want string }{
+		{"", "Relative Absolute Plain"},
+		{"https", "Relative Absolute Plain"},
+		{"mailto:, tel", "Mail Phone Plain"},
+		{"any", "Relative Absolute Script Mail Phone Empty Hash Plain"},
+	} {
+		opts, err := ParseOptions(url.Values{"linkSchemes": {tc.schemes}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		cli := NewClient(DefaultConfig()).WithOptions(opts)
+		results, _, err := cli.fetch(context.Background(), srv.URL, "a")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var titles []string
+		for _, r := range results {
+			titles = append(titles, r.Title)
+		}
+		if got := strings.Join(titles, " "); got != tc.want {
+			t.Errorf("linkSchemes=%q kept %q, want %q", tc.schemes, got, tc.want)
+		}
+		opts.CountOnly = true
+		if _, meta, _ := NewClient(DefaultConfig()).WithOptions(opts).fetch(context.Background(), srv.URL, "a"); meta.count != len(titles) {
+			t.Errorf("linkSchemes=%q counted %d, want %d", tc.schemes, meta.count, len(titles))
+		}
+	}
+
+	if _, err := ParseOptions(url.Values{"linkSchemes": {"ht tp"}}); err == nil {
+		t.Error(`linkSchemes="ht tp" accepted`)
+	}
+}
+
+func TestGroupRecords(t *testing.T) {
+	const page = `<p class="author">Masthead</p><ul>
+		<li><h2><a href="/1">One</a></h2><span class="author">Ann</span><span class="author">Al</span></li>
+		<li><span class="author">Bob</span><h2><a href="/2">Two</a></h2></li>
+		<li><h2><a href="/3">Three</a></h2></li>
+		<li><h2><a href="/4">Four</a></h2><span class="author">Dee</span><time>May 4</time></li>
+	</ul>`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = io.WriteString(w, page)
+	}))
+	defer srv.Close()
+
+	run := NewClient(DefaultConfig()).ScrapeAll([]string{srv.URL}, "headline:h2 a, byline:.author, date:time")
+	if len(run.Errs) != 0 {
+		t.Fatal(run.Errs)
+	}
+	title := func(r *ScrapeResult) string {
+		if r == nil {
+			return "null"
+		}
+		return r.Title
+	}
+	var got []string
+	for _, rec := range run.Records {
+		got = append(got, title(rec["headline"])+"/"+title(rec["byline"])+"/"+title(rec["date"]))
+	}
+	want := "One/Ann/null Two/Bob/null Three/null/null Four/Dee/May 4"
+	if strings.Join(got, " ") != want {
+		t.Errorf("records = %q, want %q", strings.Join(got, " "), want)
+	}
+	b, _ := json.Marshal(run.Records[2])
+	if string(b) != `{"byline":null,"date":null,"headline":{"title":"Three","link":"`+srv.URL+`/3"}}` {
+		t.Errorf("record JSON = %s", b)
+	}
+
+	if run := NewClient(DefaultConfig()).ScrapeAll([]string{srv.URL}, "h2 a"); run.Records != nil {
+		t.Errorf("ungrouped selector made records: %+v", run.Records)
+	}
+	opts := Options{Filter: "two"}
+	if run := NewClient(DefaultConfig()).WithOptions(opts).ScrapeAll([]string{srv.URL}, "headline:h2 a, byline:.author"); len(run.Records) != 1 || title(run.Records[0]["byline"]) != "Bob" {
+		t.Errorf("filtered records = %+v, want Two's only", run.Records)
+	}
+}
+
+func TestMaxResultBytes(t *testing.T) {
+	big := strings.Repeat("x", 600)
+	var page strings.Builder
+	for i := 0; i < 600; i++ {
+		fmt.Fprintf(&page, `<p><a href="/%d">%s</a></p>`, i, big)
+	}
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(page.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	base, _ := url.Parse("https://example.com/")
+
+	for _, workers := range []int{1, 2} {
+		cfg := DefaultConfig()
+		cfg.ParseWorkers = workers
+		cfg.MaxResultBytes = 10 << 10
+		_, err := NewClient(cfg).extractAll(doc.Find("a"), base)
+		if !errors.Is(err, ErrResultsTooLarge) || !strings.Contains(err.Error(), "10240-byte budget") {
+			t.Errorf("workers=%d: err = %v, want ErrResultsTooLarge naming the budget", workers, err)
+		}
+
+		cfg.MaxResultBytes = 0
+		got, err := NewClient(cfg).extractAll(doc.Find("a"), base)
+		if err != nil || len(got) != 600 {
+			t.Errorf("workers=%d, no budget: %d results, err %v", workers, len(got), err)
+		}
+	}
+}
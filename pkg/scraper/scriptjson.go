@@ -0,0 +1,107 @@
+package scraper
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// DefaultScriptSelector picks the script tags read by mode=scriptjson when no
+// selector is given: JSON-LD structured data and Next.js page props.
+const DefaultScriptSelector = `script[type="application/ld+json"], script#__NEXT_DATA__`
+
+// ScriptJSON is the data embedded in a page's JSON script tags, returned for
+// mode=scriptjson.
+type ScriptJSON struct {
+	URL     string `json:"url"`
+	Path    string `json:"path,omitempty"`
+	Scripts int    `json:"scripts"`           // script tags matched
+	Invalid int    `json:"invalid,omitempty"` // matched tags whose body is not JSON, skipped
+	Values  []any  `json:"values"`            // what Path selects in each script, in page order
+}
+
+// ParseJSONPath splits a path expression such as "props.pageProps.items[0].title"
+// into its keys. "[n]" and ".n" index arrays, "*" (or "[*]") takes every
+// element of an array or object. "" selects the whole document.
+func ParseJSONPath(expr string) ([]string, error) {
+	expr = strings.TrimSpace(expr)
+	if expr == "" {
+		return nil, nil
+	}
+	var keys []string
+	for _, part := range strings.Split(strings.NewReplacer("[", ".", "]", "").Replace(expr), ".") {
+		if part == "" {
+			return nil, fmt.Errorf("path %q has an empty key", expr)
+		}
+		keys = append(keys, part)
+	}
+	return keys, nil
+}
+
+// ScrapeScriptJSON fetches pageURL, parses the JSON body of every script tag
+// matching selector (DefaultScriptSelector when empty) and collects what path
+// selects in each; see ParseJSONPath. Scripts where the path finds nothing
+// add no values.
+func (c *Client) ScrapeScriptJSON(ctx context.Context, pageURL, selector, path string) (ScriptJSON, error) {
+	keys, err := ParseJSONPath(path)
+	if err != nil {
+		return ScriptJSON{}, err
+	}
+	if selector == "" {
+		selector = DefaultScriptSelector
+	}
+	doc, _, err := c.document(ctx, pageURL)
+	if err != nil {
+		return ScriptJSON{}, err
+	}
+
+	out := ScriptJSON{URL: pageURL, Path: strings.TrimSpace(path), Values: []any{}}
+	doc.Find(selector).Each(func(_ int, s *goquery.Selection) {
+		out.Scripts++
+		dec := json.NewDecoder(bytes.NewReader(bytes.TrimSpace([]byte(s.Text()))))
+		dec.UseNumber() // keep ids and prices exactly as written
+		var v any
+		if err := dec.Decode(&v); err != nil {
+			out.Invalid++
+			return
+		}
+		out.Values = append(out.Values, selectJSON(v, keys)...)
+	})
+	return out, nil
+}
+
+// selectJSON walks v along keys, fanning out at each "*" (object members
+// in key order, since maps are unordered).
+func selectJSON(v any, keys []string) []any {
+	if len(keys) == 0 {
+		return []any{v}
+	}
+	key, rest := keys[0], keys[1:]
+	var found []any
+	switch node := v.(type) {
+	case map[string]any:
+		if key == "*" {
+			for _, k := range slices.Sorted(maps.Keys(node)) {
+				found = append(found, selectJSON(node[k], rest)...)
+			}
+		} else if child, ok := node[key]; ok {
+			found = selectJSON(child, rest)
+		}
+	case []any:
+		if key == "*" {
+			for _, child := range node {
+				found = append(found, selectJSON(child, rest)...)
+			}
+		} else if i, err := strconv.Atoi(key); err == nil && i >= 0 && i < len(node) {
+			found = selectJSON(node[i], rest)
+		}
+	}
+	return found
+}
@@ -0,0 +1,113 @@
+package scraper
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SignatureHeader carries the HMAC of an API request when the server has a
+// shared secret (SCRAPER_API_SECRET).
+const SignatureHeader = "X-Signature"
+
+// TimestampHeader carries the Unix time, in seconds, at which a request was
+// signed. It is part of the signature, so a captured request stops working
+// once it is older than MaxSignatureAge.
+const TimestampHeader = "X-Signature-Timestamp"
+
+// MaxSignatureAge is how far a request's TimestampHeader may be from the
+// server's clock, either way, for VerifyRequest to accept it.
+const MaxSignatureAge = 5 * time.Minute
+
+// MaxSignedBody caps the request body VerifyRequest reads to check it.
+const MaxSignedBody = 10 << 20
+
+var (
+	// ErrUnsigned is returned by VerifyRequest when SignatureHeader or
+	// TimestampHeader is missing.
+	ErrUnsigned = errors.New("missing " + SignatureHeader + " or " + TimestampHeader + " header")
+	// ErrBadSignature is returned by VerifyRequest when the signature does not match.
+	ErrBadSignature = errors.New("invalid " + SignatureHeader + " signature")
+	// ErrStaleSignature is returned by VerifyRequest when TimestampHeader is
+	// malformed or more than MaxSignatureAge away from now.
+	ErrStaleSignature = errors.New(TimestampHeader + " is malformed or outside the allowed window")
+)
+
+// Sign returns the hex-encoded HMAC-SHA256 under secret of
+//
+//	METHOD "\n" PATH "\n" RAW_QUERY "\n" TIMESTAMP "\n" BODY
+//
+// which is the SignatureHeader value for a request. path is the URL path as
+// sent, e.g. "/api/bulk-scrape"; rawQuery is the query string exactly as
+// sent, without the "?"; timestamp is the TimestampHeader value; body is
+// empty for a GET.
+func Sign(secret []byte, method, path, rawQuery, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	fmt.Fprintf(mac, "%s\n%s\n%s\n%s\n", strings.ToUpper(method), path, rawQuery, timestamp)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// SignRequest sets r's TimestampHeader to now and its SignatureHeader to
+// the matching Sign. It reads the body and puts it back.
+func SignRequest(secret []byte, r *http.Request) error {
+	body, err := peekBody(r)
+	if err != nil {
+		return err
+	}
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	r.Header.Set(TimestampHeader, ts)
+	r.Header.Set(SignatureHeader, Sign(secret, r.Method, r.URL.EscapedPath(), r.URL.RawQuery, ts, body))
+	return nil
+}
+
+// VerifyRequest checks r's SignatureHeader (hex, optionally prefixed
+// "sha256=") against Sign, in constant time, and that its TimestampHeader
+// is within MaxSignatureAge of now. It reads the body, at most
+// MaxSignedBody bytes, and puts it back so the handler can still decode it.
+func VerifyRequest(secret []byte, r *http.Request) error {
+	sig := strings.TrimPrefix(r.Header.Get(SignatureHeader), "sha256=")
+	ts := r.Header.Get(TimestampHeader)
+	if sig == "" || ts == "" {
+		return ErrUnsigned
+	}
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return ErrStaleSignature
+	}
+	if age := time.Since(time.Unix(unix, 0)); age > MaxSignatureAge || age < -MaxSignatureAge {
+		return ErrStaleSignature
+	}
+	body, err := peekBody(r)
+	if err != nil {
+		return err
+	}
+	got, err := hex.DecodeString(sig)
+	want, _ := hex.DecodeString(Sign(secret, r.Method, r.URL.EscapedPath(), r.URL.RawQuery, ts, body))
+	if err != nil || !hmac.Equal(got, want) {
+		return ErrBadSignature
+	}
+	return nil
+}
+
+// peekBody reads r's body, at most MaxSignedBody bytes, and puts it back.
+func peekBody(r *http.Request) ([]byte, error) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil, nil
+	}
+	body, err := readBody(r.Body, MaxSignedBody)
+	if err != nil {
+		return nil, err
+	}
+	r.Body.Close()
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
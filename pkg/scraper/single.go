@@ -0,0 +1,42 @@
+package scraper
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Scraper scrapes one page at a time for programs embedding this package.
+// It exposes the few knobs most callers need and runs on the same fetch,
+// retry and parsing code as Client; use Client directly for worker pools,
+// rate limits and host policies. The zero value is ready to use.
+//
+//	s := &scraper.Scraper{Timeout: 5 * time.Second, UserAgent: "my-bot/1.0"}
+//	results, err := s.Scrape(ctx, "https://news.ycombinator.com", ".titleline > a")
+type Scraper struct {
+	HTTPClient *http.Client  // used for every request when set; Timeout is then ignored
+	Timeout    time.Duration // per-request timeout; 0 uses DefaultConfig's
+	UserAgent  string        // User-Agent header; empty keeps Go's default
+
+	once sync.Once
+	cli  *Client
+}
+
+// Scrape fetches pageURL and returns the text and resolved href of every
+// element matching selector. ctx bounds the whole call, retries included.
+func (s *Scraper) Scrape(ctx context.Context, pageURL, selector string) ([]ScrapeResult, error) {
+	s.once.Do(func() {
+		cfg := DefaultConfig()
+		cfg.UserAgent = s.UserAgent
+		if s.Timeout > 0 {
+			cfg.HTTPTimeout = s.Timeout
+		}
+		s.cli = NewClient(cfg)
+		if s.HTTPClient != nil {
+			s.cli.httpClient = s.HTTPClient
+		}
+	})
+	items, _, err := s.cli.fetch(ctx, pageURL, selector)
+	return items, err
+}
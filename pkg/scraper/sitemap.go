@@ -0,0 +1,100 @@
+package scraper
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	maxSitemapURLs  = 50000 // the sitemaps.org limit for one file, applied to the whole walk
+	maxSubSitemaps  = 50    // sitemaps fetched from one sitemap index
+	maxSitemapDepth = 2     // an index of indexes is as deep as we follow
+)
+
+// sitemapFile matches both a <urlset> and a <sitemapindex>: the first lists
+// pages in <url><loc>, the second more sitemaps in <sitemap><loc>.
+type sitemapFile struct {
+	XMLName  xml.Name
+	URLs     []sitemapLoc `xml:"url"`
+	Sitemaps []sitemapLoc `xml:"sitemap"`
+}
+
+type sitemapLoc struct {
+	Loc string `xml:"loc"`
+}
+
+// SitemapOutput is the JSON body for sitemap=true without a selector.
+type SitemapOutput struct {
+	URL   string   `json:"url"`
+	URLs  []string `json:"urls"`
+	Total int      `json:"total"`
+}
+
+// Sitemap fetches the sitemap at sitemapURL and returns the page URLs of its
+// <loc> entries, in order and without duplicates. A sitemap index is
+// followed into its sub-sitemaps (up to 50, two levels deep); a sub-sitemap
+// that fails is skipped so one broken file doesn't lose the rest. Gzipped
+// sitemaps (.xml.gz) are decompressed.
+func (c *Client) Sitemap(ctx context.Context, sitemapURL string) ([]string, error) {
+	var urls []string
+	seen := map[string]bool{}
+	if err := c.walkSitemap(ctx, sitemapURL, 0, seen, &urls); err != nil {
+		return nil, err
+	}
+	return urls, nil
+}
+
+func (c *Client) walkSitemap(ctx context.Context, sitemapURL string, depth int, seen map[string]bool, urls *[]string) error {
+	page, err := c.get(ctx, sitemapURL)
+	if err != nil {
+		return err
+	}
+	body := page.Body
+	if bytes.HasPrefix(body, []byte{0x1f, 0x8b}) {
+		zr, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return fmt.Errorf("%s: %w", sitemapURL, err)
+		}
+		if body, err = readBody(zr, c.cfg.MaxBodyBytes); err != nil {
+			return fmt.Errorf("%s: %w", sitemapURL, err)
+		}
+	}
+
+	var file sitemapFile
+	dec := xml.NewDecoder(bytes.NewReader(body))
+	dec.CharsetReader = func(_ string, r io.Reader) (io.Reader, error) { return r, nil } // sitemaps must be UTF-8
+	if err := dec.Decode(&file); err != nil {
+		return fmt.Errorf("%s: not a sitemap: %w", sitemapURL, err)
+	}
+	if name := file.XMLName.Local; name != "urlset" && name != "sitemapindex" {
+		return fmt.Errorf("%s: not a sitemap: root element is <%s>", sitemapURL, name)
+	}
+
+	for _, u := range file.URLs {
+		loc := strings.TrimSpace(u.Loc)
+		if loc == "" || seen[loc] || len(*urls) >= maxSitemapURLs {
+			continue
+		}
+		seen[loc] = true
+		*urls = append(*urls, loc)
+	}
+	if depth >= maxSitemapDepth {
+		return nil
+	}
+	for i, s := range file.Sitemaps {
+		if i >= maxSubSitemaps || len(*urls) >= maxSitemapURLs || ctx.Err() != nil {
+			break
+		}
+		loc := strings.TrimSpace(s.Loc)
+		if loc == "" || c.CheckURL(ctx, loc) != nil {
+			continue // the index may point anywhere; keep to the HostPolicy
+		}
+		_ = c.walkSitemap(ctx, loc, depth+1, seen, urls)
+	}
+	return nil
+}
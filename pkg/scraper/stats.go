@@ -0,0 +1,97 @@
+package scraper
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// statsMaxURLs bounds the URLs a Client keeps statistics for; the one
+// scraped least recently is dropped to make room.
+const statsMaxURLs = 1000
+
+// URLStats summarizes every pool scrape of one URL by a Client and its
+// WithOptions copies: ScrapeAll, ScrapeStreamed and everything built on them.
+// Durations include retries.
+type URLStats struct {
+	URL         string    `json:"url"`
+	Count       int       `json:"count"`  // scrapes, failed ones included
+	Errors      int       `json:"errors"` // scrapes that failed
+	AvgMs       int64     `json:"avg_ms"`
+	MinMs       int64     `json:"min_ms"`
+	MaxMs       int64     `json:"max_ms"`
+	LastScraped time.Time `json:"last_scraped"`
+	LastError   string    `json:"last_error,omitempty"` // error of the latest failed scrape
+}
+
+// statsTable collects URLStats, safe for concurrent workers.
+type statsTable struct {
+	mu   sync.Mutex
+	urls map[string]*urlStats
+}
+
+// urlStats is URLStats while it is being accumulated.
+type urlStats struct {
+	URLStats
+	totalMs int64
+}
+
+func newStatsTable() *statsTable {
+	return &statsTable{urls: make(map[string]*urlStats)}
+}
+
+// record adds one scrape of pageURL that took d and ended with err.
+func (st *statsTable) record(pageURL string, d time.Duration, err error) {
+	ms := d.Milliseconds()
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	s, ok := st.urls[pageURL]
+	if !ok {
+		if len(st.urls) >= statsMaxURLs {
+			st.evictOldest()
+		}
+		s = &urlStats{URLStats: URLStats{URL: pageURL, MinMs: ms, MaxMs: ms}}
+		st.urls[pageURL] = s
+	}
+	s.Count++
+	s.totalMs += ms
+	s.AvgMs = s.totalMs / int64(s.Count)
+	s.MinMs = min(s.MinMs, ms)
+	s.MaxMs = max(s.MaxMs, ms)
+	s.LastScraped = time.Now().UTC()
+	if err != nil {
+		s.Errors++
+		s.LastError = err.Error()
+	}
+}
+
+// evictOldest drops the URL scraped least recently. st.mu must be held.
+func (st *statsTable) evictOldest() {
+	var oldest *urlStats
+	for _, s := range st.urls {
+		if oldest == nil || s.LastScraped.Before(oldest.LastScraped) {
+			oldest = s
+		}
+	}
+	if oldest != nil {
+		delete(st.urls, oldest.URL)
+	}
+}
+
+// Stats returns the statistics of every URL this Client has scraped, most
+// scraped first (ties by URL).
+func (c *Client) Stats() []URLStats {
+	c.stats.mu.Lock()
+	out := make([]URLStats, 0, len(c.stats.urls))
+	for _, s := range c.stats.urls {
+		out = append(out, s.URLStats)
+	}
+	c.stats.mu.Unlock()
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		return out[i].URL < out[j].URL
+	})
+	return out
+}
@@ -0,0 +1,107 @@
+package scraper
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// SelectorSuggestion is one candidate selector proposed for a page.
+type SelectorSuggestion struct {
+	Selector   string `json:"selector"`
+	MatchCount int    `json:"match_count"` // elements with non-empty text
+	Sample     string `json:"sample"`      // text of the first match
+}
+
+// SuggestOutput is the JSON body for GET /api/suggest.
+type SuggestOutput struct {
+	URL         string               `json:"url"`
+	Suggestions []SelectorSuggestion `json:"suggestions"`
+}
+
+// commonSelectors are patterns that pick out headline lists on many sites.
+var commonSelectors = []string{
+	"article a", "article h2 a", "h1 a", "h2 a", "h3 a",
+	".title a", ".title", ".headline a", "main a", "li a", "table a",
+}
+
+// minClassMatches is how often a class-based pattern must match before it is
+// worth suggesting; rarer ones are usually one-off layout elements.
+const minClassMatches = 3
+
+// cssIdent matches class names usable in a selector without escaping.
+var cssIdent = regexp.MustCompile(`^-?[A-Za-z_][A-Za-z0-9_-]*$`)
+
+// SuggestSelectors fetches pageURL and returns up to limit candidate
+// selectors ranked by match count. Candidates are the commonSelectors plus
+// class patterns derived from links: the link's own class ("a.story") and
+// the class of its nearest classed ancestor (".titleline a").
+func (c *Client) SuggestSelectors(ctx context.Context, pageURL string, limit int) ([]SelectorSuggestion, error) {
+	doc, _, err := c.document(ctx, pageURL)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := make(map[string]bool)
+	for _, sel := range commonSelectors {
+		candidates[sel] = false
+	}
+	doc.Find("a[href]").Each(func(_ int, a *goquery.Selection) {
+		if strings.TrimSpace(a.Text()) == "" {
+			return
+		}
+		if cls := firstClass(a); cls != "" {
+			candidates["a."+cls] = true
+		}
+		for p, depth := a.Parent(), 0; p.Length() > 0 && depth < 3; p, depth = p.Parent(), depth+1 {
+			if cls := firstClass(p); cls != "" {
+				candidates["."+cls+" a"] = true
+				break
+			}
+		}
+	})
+
+	suggestions := []SelectorSuggestion{}
+	for sel, fromClass := range candidates {
+		s := SelectorSuggestion{Selector: sel}
+		doc.Find(sel).Each(func(_ int, m *goquery.Selection) {
+			text := strings.Join(strings.Fields(m.Text()), " ")
+			if text == "" {
+				return
+			}
+			if s.MatchCount == 0 {
+				s.Sample, _ = truncateTitle(text, 80)
+			}
+			s.MatchCount++
+		})
+		if s.MatchCount == 0 || (fromClass && s.MatchCount < minClassMatches) {
+			continue
+		}
+		suggestions = append(suggestions, s)
+	}
+
+	sort.Slice(suggestions, func(i, j int) bool {
+		if suggestions[i].MatchCount != suggestions[j].MatchCount {
+			return suggestions[i].MatchCount > suggestions[j].MatchCount
+		}
+		return suggestions[i].Selector < suggestions[j].Selector
+	})
+	if limit > 0 && len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+	return suggestions, nil
+}
+
+// firstClass returns the first selector-safe class name on s, or "".
+func firstClass(s *goquery.Selection) string {
+	class, _ := s.Attr("class")
+	for _, c := range strings.Fields(class) {
+		if cssIdent.MatchString(c) {
+			return c
+		}
+	}
+	return ""
+}
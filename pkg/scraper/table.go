@@ -0,0 +1,208 @@
+package scraper
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Table is one scraped HTML table with its header row kept apart from the body.
+type Table struct {
+	Header []string   `json:"header,omitempty"` // <thead> row, or a leading row of only <th> cells
+	Rows   [][]string `json:"rows,omitempty"`
+
+	// Objects, with TableObjects, is each row keyed by its column's header
+	// text; Header then holds the keys in column order. A table without a
+	// header row takes its first row as the header.
+	Objects []map[string]string `json:"objects,omitempty"`
+}
+
+// TableOutput is the JSON body returned for a tableMode scrape.
+type TableOutput struct {
+	URL      string  `json:"url"`
+	Selector string  `json:"selector"`
+	Tables   []Table `json:"tables"`
+}
+
+// maxCellSpan caps colspan and rowspan, so a bogus colspan="100000" can't
+// blow up a row.
+const maxCellSpan = 100
+
+// ScrapeTables fetches pageURL and extracts every table matched by selector.
+// The selector may point at a <table>, or a <thead>/<tbody>/<tr> inside one.
+// With Options.TableMode TableObjects, cells spanning several columns or
+// rows are repeated in each, so every row lines up with the header, and
+// Table.Objects is set.
+func (c *Client) ScrapeTables(ctx context.Context, pageURL, selector string) ([]Table, error) {
+	doc, _, err := c.document(ctx, pageURL)
+	if err != nil {
+		return nil, err
+	}
+
+	objects := c.opts.TableMode == TableObjects
+	tables := []Table{}
+	doc.Find(selector).Each(func(_ int, s *goquery.Selection) {
+		t, ok := parseTable(s, objects)
+		if !ok {
+			return
+		}
+		if objects {
+			t.keyRows()
+		}
+		tables = append(tables, t)
+	})
+	return tables, nil
+}
+
+// parseTable turns the rows under s into a Table. Rows of tables nested
+// inside s are skipped so their cells don't leak into the outer table.
+// With spans, colspan and rowspan are expanded, see ScrapeTables.
+func parseTable(s *goquery.Selection, spans bool) (Table, bool) {
+	owner := s
+	if goquery.NodeName(s) != "table" {
+		owner = s.Closest("table")
+	}
+
+	rows := s.Find("tr")
+	if goquery.NodeName(s) == "tr" {
+		rows = s
+	}
+
+	t := Table{Rows: [][]string{}}
+	var carried []spanCell // cells of earlier rows reaching into this one, by column
+	rows.Each(func(i int, tr *goquery.Selection) {
+		if !tr.Closest("table").IsSelection(owner) {
+			return
+		}
+		cells := tr.Children().Filter("td, th")
+		if cells.Length() == 0 {
+			return
+		}
+		var row []string
+		if spans {
+			row, carried = spannedRow(cells, carried)
+		} else {
+			row = make([]string, 0, cells.Length())
+			cells.Each(func(_ int, cell *goquery.Selection) {
+				row = append(row, cellText(cell))
+			})
+		}
+
+		inHead := tr.ParentsFiltered("thead").Length() > 0
+		allTH := cells.Filter("th").Length() == cells.Length()
+		if t.Header == nil && (inHead || (allTH && len(t.Rows) == 0)) {
+			t.Header = row
+			return
+		}
+		t.Rows = append(t.Rows, row)
+	})
+
+	if t.Header == nil && len(t.Rows) == 0 {
+		return Table{}, false
+	}
+	return t, true
+}
+
+// cellText is the text of a table cell with whitespace collapsed.
+func cellText(cell *goquery.Selection) string {
+	return strings.Join(strings.Fields(cell.Text()), " ")
+}
+
+// spanCell is a cell with rowspan, still to fill rows more rows of its column.
+type spanCell struct {
+	text string
+	rows int
+}
+
+// spannedRow lays out the cells of one <tr> with colspan repeated, after
+// placing the cells carried down from rows above by rowspan. It returns the
+// row and what it carries on to the next one.
+func spannedRow(cells *goquery.Selection, carried []spanCell) ([]string, []spanCell) {
+	var row []string
+	next := make([]spanCell, 0, len(carried))
+	col := 0
+	place := func(text string, rows int) {
+		row = append(row, text)
+		for len(next) <= col {
+			next = append(next, spanCell{})
+		}
+		if rows > 1 {
+			next[col] = spanCell{text: text, rows: rows - 1}
+		}
+		col++
+	}
+	fillCarried := func() {
+		for col < len(carried) && carried[col].rows > 0 {
+			place(carried[col].text, carried[col].rows)
+		}
+	}
+	cells.Each(func(_ int, cell *goquery.Selection) {
+		fillCarried()
+		text := cellText(cell)
+		rows := spanAttr(cell, "rowspan")
+		for range spanAttr(cell, "colspan") {
+			place(text, rows)
+		}
+	})
+	for col < len(carried) {
+		if carried[col].rows > 0 {
+			fillCarried()
+		} else {
+			place("", 0)
+		}
+	}
+	return row, next
+}
+
+// spanAttr reads a colspan or rowspan, between 1 and maxCellSpan.
+func spanAttr(cell *goquery.Selection, name string) int {
+	n, err := strconv.Atoi(strings.TrimSpace(cell.AttrOr(name, "")))
+	if err != nil || n < 1 {
+		return 1
+	}
+	return min(n, maxCellSpan)
+}
+
+// keyRows sets t.Objects from t.Rows, keyed by header text. Empty header
+// cells become "column N" and repeated ones get a " 2", " 3", ... suffix,
+// so no column is lost.
+func (t *Table) keyRows() {
+	if t.Header == nil && len(t.Rows) > 0 {
+		t.Header, t.Rows = t.Rows[0], t.Rows[1:]
+	}
+	width := len(t.Header)
+	for _, row := range t.Rows {
+		width = max(width, len(row))
+	}
+	keys := make([]string, width)
+	seen := make(map[string]int, width)
+	for i := range keys {
+		key := ""
+		if i < len(t.Header) {
+			key = t.Header[i]
+		}
+		if key == "" {
+			key = fmt.Sprintf("column %d", i+1)
+		}
+		if seen[key]++; seen[key] > 1 {
+			key = fmt.Sprintf("%s %d", key, seen[key])
+		}
+		keys[i] = key
+	}
+	t.Header = keys
+	t.Objects = make([]map[string]string, 0, len(t.Rows))
+	for _, row := range t.Rows {
+		obj := make(map[string]string, len(keys))
+		for i, key := range keys {
+			if i < len(row) {
+				obj[key] = row[i]
+			} else {
+				obj[key] = ""
+			}
+		}
+		t.Objects = append(t.Objects, obj)
+	}
+}
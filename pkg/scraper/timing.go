@@ -0,0 +1,86 @@
+package scraper
+
+import (
+	"crypto/tls"
+	"math"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// Timing breaks one page fetch into phases, in milliseconds. DNS, Connect and
+// TLS are zero when a kept-alive connection was reused. With retries, only the
+// last attempt is measured.
+type Timing struct {
+	DNSMs     float64 `json:"dns_ms"`
+	ConnectMs float64 `json:"connect_ms"`
+	TLSMs     float64 `json:"tls_ms"`
+	TTFBMs    float64 `json:"ttfb_ms"`  // request sent to first response byte
+	TotalMs   float64 `json:"total_ms"` // request sent to body fully read
+	ParseMs   float64 `json:"parse_ms"` // HTML parsing and selector matching
+}
+
+// PageTiming is the Timing of one URL in a scrape.
+type PageTiming struct {
+	URL    string `json:"url"`
+	Bytes  int64  `json:"bytes"`           // body bytes downloaded
+	Scheme string `json:"scheme"`          // scheme the page was fetched over, see Options.UpgradeInsecure
+	Proxy  string `json:"proxy,omitempty"` // proxy that answered, see Config.Proxies
+	Timing
+}
+
+// phaseTrace collects Timing through an httptrace.ClientTrace. The hooks can
+// fire from several goroutines (e.g. parallel dials), hence the mutex.
+type phaseTrace struct {
+	mu                         sync.Mutex
+	start, dns, connect, tlsHS time.Time
+	timing                     Timing
+}
+
+// begin resets the trace for a new attempt.
+func (pt *phaseTrace) begin() {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+	pt.start = time.Now()
+	pt.timing = Timing{}
+}
+
+// finish records the total and returns the phases of the last attempt.
+func (pt *phaseTrace) finish() Timing {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+	pt.timing.TotalMs = millis(time.Since(pt.start))
+	return pt.timing
+}
+
+func (pt *phaseTrace) record(field *float64, since *time.Time) {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+	*field = millis(time.Since(*since))
+}
+
+func (pt *phaseTrace) mark(t *time.Time) {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+	*t = time.Now()
+}
+
+func (pt *phaseTrace) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart:          func(httptrace.DNSStartInfo) { pt.mark(&pt.dns) },
+		DNSDone:           func(httptrace.DNSDoneInfo) { pt.record(&pt.timing.DNSMs, &pt.dns) },
+		ConnectStart:      func(string, string) { pt.mark(&pt.connect) },
+		ConnectDone:       func(string, string, error) { pt.record(&pt.timing.ConnectMs, &pt.connect) },
+		TLSHandshakeStart: func() { pt.mark(&pt.tlsHS) },
+		TLSHandshakeDone:  func(tls.ConnectionState, error) { pt.record(&pt.timing.TLSMs, &pt.tlsHS) },
+		GotFirstResponseByte: func() {
+			pt.record(&pt.timing.TTFBMs, &pt.start)
+		},
+	}
+}
+
+// millis converts d to milliseconds with two decimals, so sub-millisecond
+// phases on fast networks don't all read as zero.
+func millis(d time.Duration) float64 {
+	return math.Round(float64(d)/float64(time.Millisecond)*100) / 100
+}
@@ -0,0 +1,101 @@
+// Package web holds what the two web front ends, the standalone server in
+// internal/server and the Vercel function in api/, share beyond the scraper
+// itself. It is not internal because Vercel's Go builder compiles api/ in a
+// way that can't import internal packages.
+package web
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/GhanshyamJha05/WEB_SCRAPPER_Using-GO/pkg/scraper"
+)
+
+// Debouncer shares a scrape's result with repeats of it that arrive while it
+// runs or within a window after it finished, so double submits and rapid
+// reloads don't hit the target sites again.
+type Debouncer struct {
+	window time.Duration
+	mu     sync.Mutex
+	runs   map[string]*debouncedRun
+}
+
+// debouncedRun is one scrape, running until done is closed.
+type debouncedRun struct {
+	done     chan struct{}
+	run      scraper.ScrapeRun // set before done is closed
+	ok       bool              // scrape returned; false when it panicked
+	finished time.Time         // zero while running; guarded by Debouncer.mu
+}
+
+// NewDebouncer returns a Debouncer sharing results for window after each
+// scrape finishes.
+func NewDebouncer(window time.Duration) *Debouncer {
+	return &Debouncer{window: window, runs: make(map[string]*debouncedRun)}
+}
+
+// DebounceKey identifies a scrape by the client's address and its query,
+// without the parameters that only change how the result is written. A
+// client behind a shared proxy shares its key with the proxy's other users.
+func DebounceKey(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	q := r.URL.Query()
+	q.Del("format")
+	q.Del("copy")
+	return host + "\x00" + q.Encode()
+}
+
+// Do returns the result of the scrape running or recently finished under
+// key and true, or runs scrape and returns its result and false. A repeat
+// waiting for a running scrape gives up with ctx's error once ctx is done.
+// If scrape panics, the panic goes on up and its waiters run the scrape
+// again themselves, one of them for all.
+func (d *Debouncer) Do(ctx context.Context, key string, scrape func() scraper.ScrapeRun) (scraper.ScrapeRun, bool, error) {
+	for {
+		d.mu.Lock()
+		if e, ok := d.runs[key]; ok && (e.finished.IsZero() || time.Since(e.finished) < d.window) {
+			d.mu.Unlock()
+			select {
+			case <-e.done:
+			case <-ctx.Done():
+				return scraper.ScrapeRun{}, false, ctx.Err()
+			}
+			if e.ok {
+				return e.run, true, nil
+			}
+			continue
+		}
+		for k, e := range d.runs {
+			if !e.finished.IsZero() && time.Since(e.finished) >= d.window {
+				delete(d.runs, k)
+			}
+		}
+		e := &debouncedRun{done: make(chan struct{})}
+		d.runs[key] = e
+		d.mu.Unlock()
+		return d.run(key, e, scrape), false, nil
+	}
+}
+
+// run runs scrape for e, which is marked finished and released to its
+// waiters however scrape ends.
+func (d *Debouncer) run(key string, e *debouncedRun, scrape func() scraper.ScrapeRun) scraper.ScrapeRun {
+	defer func() {
+		d.mu.Lock()
+		e.finished = time.Now()
+		if !e.ok && d.runs[key] == e {
+			delete(d.runs, key)
+		}
+		d.mu.Unlock()
+		close(e.done)
+	}()
+	e.run = scrape()
+	e.ok = true
+	return e.run
+}
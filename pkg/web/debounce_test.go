@@ -0,0 +1,124 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/GhanshyamJha05/WEB_SCRAPPER_Using-GO/pkg/scraper"
+)
+
+func TestDebouncer(t *testing.T) {
+	d := NewDebouncer(50 * time.Millisecond)
+	var calls atomic.Int32
+	release := make(chan struct{})
+	scrape := func() scraper.ScrapeRun {
+		calls.Add(1)
+		<-release
+		return scraper.ScrapeRun{Count: 7}
+	}
+
+	// Concurrent repeats wait for the one scrape and share its result.
+	var wg sync.WaitGroup
+	var reused atomic.Int32
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			run, ok, err := d.Do(context.Background(), "k", scrape)
+			if err != nil || run.Count != 7 {
+				t.Errorf("Do = %+v, %v, want Count 7", run, err)
+			}
+			if ok {
+				reused.Add(1)
+			}
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if calls.Load() != 1 || reused.Load() != 4 {
+		t.Errorf("%d scrapes, %d reused; want 1 and 4", calls.Load(), reused.Load())
+	}
+
+	// Within the window the result is still shared; after it, scraped again.
+	if _, ok, _ := d.Do(context.Background(), "k", scrape); !ok {
+		t.Error("repeat within the window was not reused")
+	}
+	time.Sleep(60 * time.Millisecond)
+	if _, ok, _ := d.Do(context.Background(), "k", scrape); ok || calls.Load() != 2 {
+		t.Errorf("repeat after the window: reused %v, %d scrapes; want a new scrape", ok, calls.Load())
+	}
+	// Other keys never share.
+	if _, ok, _ := d.Do(context.Background(), "other", scrape); ok {
+		t.Error("a different key was reused")
+	}
+}
+
+func TestDebouncerWaiterLeaves(t *testing.T) {
+	d := NewDebouncer(time.Minute)
+	release := make(chan struct{})
+	defer close(release)
+	go d.Do(context.Background(), "k", func() scraper.ScrapeRun {
+		<-release
+		return scraper.ScrapeRun{}
+	})
+	time.Sleep(10 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, _, err := d.Do(ctx, "k", nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("waiter err = %v, want its context's deadline", err)
+	}
+}
+
+func TestDebouncerPanic(t *testing.T) {
+	d := NewDebouncer(time.Minute)
+	started, release := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer func() { recover() }()
+		d.Do(context.Background(), "k", func() scraper.ScrapeRun {
+			close(started)
+			<-release
+			panic("boom")
+		})
+	}()
+	<-started
+
+	done := make(chan scraper.ScrapeRun)
+	go func() {
+		run, _, _ := d.Do(context.Background(), "k", func() scraper.ScrapeRun { return scraper.ScrapeRun{Count: 1} })
+		done <- run
+	}()
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	select {
+	case run := <-done:
+		if run.Count != 1 {
+			t.Errorf("waiter got %+v, want its own scrape's result", run)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("waiter still blocked after the scrape panicked")
+	}
+
+	// The panicked run is not kept for later repeats either.
+	if _, ok, _ := d.Do(context.Background(), "k", func() scraper.ScrapeRun { return scraper.ScrapeRun{} }); !ok {
+		t.Error("the waiter's run was not shared")
+	}
+}
+
+func TestDebounceKey(t *testing.T) {
+	a := httptest.NewRequest("GET", "/?url=x&format=json", nil)
+	b := httptest.NewRequest("GET", "/?url=x&copy=json", nil)
+	c := httptest.NewRequest("GET", "/?url=y", nil)
+	if DebounceKey(a) != DebounceKey(b) {
+		t.Error("format and copy should not change the key")
+	}
+	if DebounceKey(a) == DebounceKey(c) {
+		t.Error("different queries share a key")
+	}
+}
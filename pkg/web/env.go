@@ -0,0 +1,92 @@
+package web
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Environment variables both web servers read. They configure the servers,
+// not scraper.Config; the standalone server's own are in internal/server.
+const (
+	EnvTemplateDir  = "SCRAPER_TEMPLATE_DIR"      // directory with an index.html replacing the embedded one
+	EnvHistorySize  = "SCRAPER_HISTORY_SIZE"      // entries kept in the "Recently Scraped" list
+	EnvHistoryCanon = "SCRAPER_HISTORY_CANONICAL" // "true" lists pages under their canonical URL in "Recently Scraped"
+	EnvRenderLimit  = "SCRAPER_RENDER_LIMIT"      // results rendered into the index page; "0" renders all
+	EnvRenderWarn   = "SCRAPER_RENDER_WARN"       // page renders slower than this Go duration are logged
+	EnvDebounce     = "SCRAPER_DEBOUNCE"          // a repeat of a client's scrape within this Go duration reuses its result
+	EnvDefaultFmt   = "SCRAPER_DEFAULT_FORMAT"    // format for requests naming none: json, jsonl, csv or md; unset serves the HTML page
+	EnvAPISecret    = "SCRAPER_API_SECRET"        // shared secret for scraper.SignatureHeader; unset leaves the API open
+)
+
+// DefaultHistorySize is how many visited URLs the web UI remembers.
+const DefaultHistorySize = 10
+
+// HistorySizeFromEnv returns SCRAPER_HISTORY_SIZE, or DefaultHistorySize
+// when it is unset, malformed or not positive.
+func HistorySizeFromEnv() int {
+	if n, err := strconv.Atoi(os.Getenv(EnvHistorySize)); err == nil && n > 0 {
+		return n
+	}
+	return DefaultHistorySize
+}
+
+// HistoryCanonicalFromEnv reports whether SCRAPER_HISTORY_CANONICAL is set
+// to a true value as understood by strconv.ParseBool.
+func HistoryCanonicalFromEnv() bool {
+	b, _ := strconv.ParseBool(os.Getenv(EnvHistoryCanon))
+	return b
+}
+
+// DefaultRenderLimit is how many results the web UI renders into the page;
+// exports such as format=json still carry all of them.
+const DefaultRenderLimit = 500
+
+// RenderLimitFromEnv returns SCRAPER_RENDER_LIMIT, 0 meaning no limit, or
+// DefaultRenderLimit when it is unset, malformed or negative.
+func RenderLimitFromEnv() int {
+	if n, err := strconv.Atoi(os.Getenv(EnvRenderLimit)); err == nil && n >= 0 {
+		return n
+	}
+	return DefaultRenderLimit
+}
+
+// DefaultRenderWarn is how long rendering a page may take before the web
+// servers log it as slow.
+const DefaultRenderWarn = 2 * time.Second
+
+// RenderWarnFromEnv returns SCRAPER_RENDER_WARN, a Go duration such as
+// "500ms", or DefaultRenderWarn when it is unset, malformed or not positive.
+func RenderWarnFromEnv() time.Duration {
+	if d, err := time.ParseDuration(os.Getenv(EnvRenderWarn)); err == nil && d > 0 {
+		return d
+	}
+	return DefaultRenderWarn
+}
+
+// DebounceFromEnv returns SCRAPER_DEBOUNCE, a Go duration such as "2s", or
+// 0 (no debouncing) when it is unset, malformed or not positive.
+func DebounceFromEnv() time.Duration {
+	if d, err := time.ParseDuration(os.Getenv(EnvDebounce)); err == nil && d > 0 {
+		return d
+	}
+	return 0
+}
+
+// DefaultFormatFromEnv returns SCRAPER_DEFAULT_FORMAT, the format answered
+// to a request with neither a format parameter nor an Accept header
+// preferring one, see scraper.RequestFormat. It is "" (the HTML page) when
+// unset; a value other than json, jsonl, csv or md is logged and ignored.
+func DefaultFormatFromEnv() string {
+	v := strings.ToLower(strings.TrimSpace(os.Getenv(EnvDefaultFmt)))
+	switch v {
+	case "", "json", "jsonl", "csv", "md":
+		return v
+	case "html":
+		return ""
+	}
+	log.Printf("ignoring %s=%q: use json, jsonl, csv or md", EnvDefaultFmt, v)
+	return ""
+}
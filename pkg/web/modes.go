@@ -0,0 +1,68 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/GhanshyamJha05/WEB_SCRAPPER_Using-GO/pkg/scraper"
+)
+
+// JSONOnly returns the message refusing format for feature, such as
+// "mode=meta" or "tableMode", whose output is only served as JSON or on
+// the HTML page. It returns "" when format is one of those.
+func JSONOnly(feature, format string) string {
+	switch format {
+	case "jsonl", "md", "csv":
+		return feature + " does not support format=" + format + "."
+	}
+	return ""
+}
+
+// ValueScrape scrapes one page in a mode whose results are a list of JSON
+// values. out is the whole output, served as is for format=json; values are
+// what the HTML page shows, one block each. cli already carries opts.
+type ValueScrape func(ctx context.Context, cli *scraper.Client, opts scraper.Options, pageURL, selector string) (out any, values []any, err error)
+
+// ValueModes maps the scraper.Options.Mode values served as a list of JSON
+// values to their ValueScrape. mode=meta has a page layout of its own and
+// is not among them.
+var ValueModes = map[string]ValueScrape{
+	scraper.ModeScriptJSON: func(ctx context.Context, cli *scraper.Client, opts scraper.Options, pageURL, selector string) (any, []any, error) {
+		found, err := cli.ScrapeScriptJSON(ctx, pageURL, selector, opts.Path)
+		return found, found.Values, err
+	},
+	scraper.ModeAPI: func(ctx context.Context, cli *scraper.Client, opts scraper.Options, pageURL, _ string) (any, []any, error) {
+		found, err := cli.ScrapeAPI(ctx, pageURL, opts.Path)
+		return found, found.Values, err
+	},
+	scraper.ModeMicrodata: func(ctx context.Context, cli *scraper.Client, _ scraper.Options, pageURL, _ string) (any, []any, error) {
+		found, err := cli.ScrapeMicrodata(ctx, pageURL)
+		return found, anySlice(found.Items), err
+	},
+	scraper.ModeForms: func(ctx context.Context, cli *scraper.Client, _ scraper.Options, pageURL, _ string) (any, []any, error) {
+		found, err := cli.ScrapeForms(ctx, pageURL)
+		return found, anySlice(found.Forms), err
+	},
+}
+
+func anySlice[T any](s []T) []any {
+	out := make([]any, len(s))
+	for i, v := range s {
+		out[i] = v
+	}
+	return out
+}
+
+// IndentValues returns each of values as indented JSON, leaving out those
+// that can't be encoded.
+func IndentValues(values []any) []string {
+	var out []string
+	for _, v := range values {
+		b, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			continue
+		}
+		out = append(out, string(b))
+	}
+	return out
+}
@@ -0,0 +1,16 @@
+package web
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// Capitalize returns msg with its first letter in upper case, for showing an
+// error, whose text starts in lower case by Go convention, on its own.
+func Capitalize(msg string) string {
+	r, n := utf8.DecodeRuneInString(msg)
+	if n == 0 || unicode.IsUpper(r) {
+		return msg
+	}
+	return string(unicode.ToUpper(r)) + msg[n:]
+}
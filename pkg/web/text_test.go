@@ -0,0 +1,17 @@
+package web
+
+import "testing"
+
+func TestCapitalize(t *testing.T) {
+	for _, tt := range []struct{ in, want string }{
+		{"refusing to scrape x: denied", "Refusing to scrape x: denied"},
+		{"Already", "Already"},
+		{"éclair", "Éclair"},
+		{"", ""},
+		{"404 page", "404 page"},
+	} {
+		if got := Capitalize(tt.in); got != tt.want {
+			t.Errorf("Capitalize(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}